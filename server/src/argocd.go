@@ -0,0 +1,251 @@
+package main
+
+import (
+	"argocd/pkg/argocdInstances"
+	"argocd/pkg/argocdSession"
+	"argocd/pkg/auth"
+	"argocd/pkg/metrics"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// argocdURL is the default ArgoCD instance, which apps are read from unless
+// argocdInstancesFile routes their account and region elsewhere
+var argocdURL = "https://argocd.pismo.services"
+
+// argocdInstancesFile declares further ArgoCD instances, such as the control
+// planes of regulated tenants; see projects/argocd-instances.example.json
+var argocdInstancesFile = "projects/argocd-instances.json"
+
+// argocdInstanceRegistry routes each app to its ArgoCD instance
+var argocdInstanceRegistry *argocdInstances.Registry
+
+const argocdSessionCookie = "lighthouse_argocd_session"
+
+// argocdResolver picks the ArgoCD credentials for each caller. Until a user
+// stores their own token or signs in with OIDC, the shared token.txt is used
+// in read-only service mode.
+var argocdResolver = &argocdSession.Resolver{
+	Sessions:         argocdSession.NewStore(12 * time.Hour),
+	ServiceTokenFile: "token.txt",
+}
+
+func setupArgoCDDelegation(mode, tokenURL, clientID, audience string) error {
+	argocdURL = strings.TrimRight(argocdURL, "/")
+
+	switch mode {
+	case "off":
+		return nil
+	case "passthrough":
+		argocdResolver.Exchanger = argocdSession.NewExchanger("", "", "", "")
+		return nil
+	case "rfc8693":
+		if tokenURL == "" {
+			return fmt.Errorf("rfc8693 exchange needs -argocd-exchange-url")
+		}
+		secret := ""
+		if secretBytes, err := os.ReadFile("argocd-exchange-secret.txt"); err == nil {
+			secret = strings.TrimSpace(string(secretBytes))
+		}
+		argocdResolver.Exchanger = argocdSession.NewExchanger(tokenURL, clientID, secret, audience)
+		return nil
+	}
+	return fmt.Errorf("unknown ArgoCD exchange mode %q, use off, passthrough or rfc8693", mode)
+}
+
+// setupArgoCDInstances builds the ArgoCD instance registry. Without
+// argocdInstancesFile it only holds argocdURL.
+func setupArgoCDInstances() error {
+	config := &argocdInstances.Config{}
+	if _, err := os.Stat(argocdInstancesFile); err == nil {
+		if config, err = argocdInstances.LoadConfig(argocdInstancesFile); err != nil {
+			return err
+		}
+	}
+
+	// Instances with their own TLS settings get the upstream metrics too
+	registry, err := argocdInstances.NewRegistry(*config, argocdURL, instrumentTransport)
+	if err != nil {
+		return err
+	}
+	argocdInstanceRegistry = registry
+	argocdURL = registry.Default().URL
+	if len(config.Instances) > 0 {
+		slog.Info("Loaded ArgoCD instances", "file", argocdInstancesFile, "instances", len(registry.Instances()), "routes", len(config.Routes))
+	}
+	return nil
+}
+
+// instrumentTransport wraps a transport built for a client of its own, such as
+// one with its own TLS settings, in the upstream metrics of http.DefaultTransport
+func instrumentTransport(transport http.RoundTripper) http.RoundTripper {
+	if instrumented, ok := http.DefaultTransport.(*metrics.Transport); ok {
+		return instrumented.Wrap(transport)
+	}
+	return transport
+}
+
+// argocdInstancesHandler serves /argocd/instances: the health of every
+// ArgoCD instance, probed in parallel
+func argocdInstancesHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 10*time.Second)
+	defer cancel()
+
+	instances := argocdInstanceRegistry.Instances()
+	health := make([]argocdInstances.Health, len(instances))
+	var wg sync.WaitGroup
+	for i, instance := range instances {
+		wg.Add(1)
+		go func(i int, instance *argocdInstances.Instance) {
+			defer wg.Done()
+			health[i] = instance.CheckHealth(ctx)
+		}(i, instance)
+	}
+	wg.Wait()
+
+	writeJSON(w, health)
+}
+
+// argocdCall calls an ArgoCD instance on behalf of a caller. Every call to
+// ArgoCD goes through it, so read-only credentials can never run an action.
+func argocdCall(ctx context.Context, instance *argocdInstances.Instance, caller argocdSession.Credentials, method, url string) ([]byte, error) {
+	creds := instance.Credentials(caller)
+	if err := creds.CheckAction(method); err != nil {
+		return nil, err
+	}
+	body, err := requestURLContent(ctx, instance.Client, method, url, creds.Token)
+	instance.Observe(err)
+	return body, err
+}
+
+// argocdCredentials resolves the ArgoCD credentials for the request's caller
+func argocdCredentials(r *http.Request) (argocdSession.Credentials, error) {
+	sessionID := ""
+	if cookie, err := r.Cookie(argocdSessionCookie); err == nil {
+		sessionID = cookie.Value
+	}
+
+	subject, oidcToken := "", ""
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		subject = principal.Subject
+		if principal.Method == "oidc" {
+			oidcToken = principal.Token
+		}
+	}
+
+	return argocdResolver.Resolve(r.Context(), sessionID, subject, oidcToken)
+}
+
+type argocdUserInfo struct {
+	LoggedIn bool     `json:"loggedIn"`
+	Username string   `json:"username"`
+	Groups   []string `json:"groups"`
+}
+
+// argocdUserInfoFor checks a token against ArgoCD and returns who it belongs to
+func argocdUserInfoFor(ctx context.Context, token string) (*argocdUserInfo, error) {
+	instance := argocdInstanceRegistry.Default()
+	body, err := getURLContent(ctx, instance.Client, instance.URL+"/api/v1/session/userinfo", token)
+	if err != nil {
+		return nil, err
+	}
+
+	var info argocdUserInfo
+	if err := json.Unmarshal(body, &info); err != nil {
+		return nil, fmt.Errorf("error parsing ArgoCD user info: %v", err)
+	}
+	if !info.LoggedIn {
+		return nil, fmt.Errorf("ArgoCD did not accept the token")
+	}
+	return &info, nil
+}
+
+// argocdSessionHandler manages the caller's own ArgoCD token. POST stores a
+// token ({"token": "..."}) after checking it with ArgoCD, GET reports which
+// credentials the caller's ArgoCD calls run under, and DELETE forgets the token.
+func argocdSessionHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	subject := ""
+	if principal := auth.FromContext(r.Context()); principal != nil {
+		subject = principal.Subject
+	}
+
+	switch r.Method {
+	case http.MethodPost:
+		var request struct {
+			Token string `json:"token"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil || strings.TrimSpace(request.Token) == "" {
+			http.Error(w, "Expected a JSON body with a token", http.StatusBadRequest)
+			return
+		}
+		token := strings.TrimSpace(request.Token)
+
+		info, err := argocdUserInfoFor(r.Context(), token)
+		if err != nil {
+			http.Error(w, "Invalid ArgoCD token: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		id, err := argocdResolver.Sessions.Put(argocdSession.Session{
+			Subject:  subject,
+			Username: info.Username,
+			Groups:   info.Groups,
+			Token:    token,
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		session, _ := argocdResolver.Sessions.Get(id)
+
+		http.SetCookie(w, &http.Cookie{
+			Name:     argocdSessionCookie,
+			Value:    id,
+			Path:     "/",
+			Expires:  session.Expires,
+			HttpOnly: true,
+			Secure:   r.TLS != nil,
+			SameSite: http.SameSiteLaxMode,
+		})
+		writeJSON(w, session)
+
+	case http.MethodDelete:
+		if cookie, err := r.Cookie(argocdSessionCookie); err == nil {
+			argocdResolver.Sessions.Delete(cookie.Value)
+		}
+		http.SetCookie(w, &http.Cookie{Name: argocdSessionCookie, Value: "", Path: "/", MaxAge: -1})
+		w.WriteHeader(http.StatusNoContent)
+
+	case http.MethodGet:
+		creds, err := argocdCredentials(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		writeJSON(w, creds)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
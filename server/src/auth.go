@@ -0,0 +1,128 @@
+package main
+
+import (
+	"argocd/pkg/auth"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// authFile configures authentication; see projects/auth.example.json
+var authFile = "projects/auth.json"
+
+// authenticator is nil when authentication is off
+var authenticator auth.Authenticator
+
+func setupAuth() error {
+	if _, err := os.Stat(authFile); os.IsNotExist(err) {
+		slog.Warn("Auth config not found, the API is running without authentication", "file", authFile)
+		return nil
+	}
+
+	config, err := auth.LoadConfig(authFile)
+	if err != nil {
+		return err
+	}
+	authenticator, err = auth.NewAuthenticator(config)
+	if err != nil {
+		return err
+	}
+	if authenticator == nil {
+		slog.Warn("Auth mode is none, the API is running without authentication")
+	}
+	return nil
+}
+
+// requireRole wraps a handler so the caller must hold the role for the squad
+// owning the requested repo or squad. Refreshes triggered with ?force=true
+// need squad-operator, or admin when they cover the whole fleet.
+func requireRole(role auth.Role, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if authenticator == nil || r.Method == http.MethodOptions {
+			handler(w, r)
+			return
+		}
+
+		setCORSHeaders(w, r)
+		principal, err := authenticator.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="lighthouse"`)
+			http.Error(w, "Unauthorized: "+err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		squad, err := requestSquad(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		required := role
+		if r.URL.Query().Get("force") == "true" && !required.Includes(auth.SquadOperator) {
+			required = auth.SquadOperator
+			if squad == "" {
+				required = auth.Admin
+			}
+		}
+
+		if !principal.Can(required, squad) {
+			scope := "the fleet"
+			if squad != "" {
+				scope = "squad " + squad
+			}
+			http.Error(w, "Forbidden: "+string(required)+" role required on "+scope, http.StatusForbidden)
+			return
+		}
+
+		handler(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// requestSquad works out which squad a request targets, from /squads/<name>,
+// or from the owner of the repo in /repos/<name> or ?repo=. An empty result
+// means a fleet-wide request.
+func requestSquad(r *http.Request) (string, error) {
+	if strings.HasPrefix(r.URL.Path, "/squads/") {
+		return strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/squads/"), "/"), "/")[0], nil
+	}
+
+	repo := r.URL.Query().Get("repo")
+	// repoResourceHandler serves the repo of the path, so that is the one
+	// the caller must be allowed to read
+	if strings.HasPrefix(r.URL.Path, "/repos/") {
+		pathRepo := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/repos/"), "/"), "/")[0]
+		if repo != "" && repo != pathRepo {
+			return "", fmt.Errorf("?repo=%s does not match the repo %s in the path", repo, pathRepo)
+		}
+		repo = pathRepo
+	}
+	if repo == "" {
+		return "", nil
+	}
+	if owner := repoOwner(repo); owner != "" {
+		return owner, nil
+	}
+	// Unowned repos are only visible to unscoped roles
+	return "-", nil
+}
+
+// whoamiHandler returns the authenticated caller and their role bindings
+func whoamiHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	principal := auth.FromContext(r.Context())
+	if principal == nil {
+		principal = &auth.Principal{
+			Subject:  "anonymous",
+			Method:   "none",
+			Bindings: []auth.Binding{{Role: auth.Admin}},
+		}
+	}
+	writeJSON(w, principal)
+}
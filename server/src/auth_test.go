@@ -0,0 +1,69 @@
+package main
+
+import (
+	"argocd/pkg/auth"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// withOwners stands in for the squads and catalog repoOwner reads
+func withOwners(t *testing.T, owners map[string]string) {
+	t.Helper()
+	ownerCache.Lock()
+	ownerCache.owners = owners
+	ownerCache.loadedAt = time.Now()
+	ownerCache.Unlock()
+	t.Cleanup(func() {
+		ownerCache.Lock()
+		ownerCache.owners = make(map[string]string)
+		ownerCache.loadedAt = time.Time{}
+		ownerCache.Unlock()
+	})
+}
+
+func TestRequireRoleScopesReposToTheirPath(t *testing.T) {
+	withOwners(t, map[string]string{
+		"backoffice-core-bff": "psm-console",
+		"crm-core":            "crm",
+	})
+	authenticator = auth.NewStaticAuthenticator([]auth.StaticToken{{
+		Token:    "console-token",
+		Subject:  "console",
+		Bindings: []auth.Binding{{Role: auth.Viewer, Squads: []string{"psm-console"}}},
+	}})
+	t.Cleanup(func() { authenticator = nil })
+
+	var served string
+	handler := requireRole(auth.Viewer, func(w http.ResponseWriter, r *http.Request) {
+		served = r.URL.Path
+	})
+
+	cases := []struct {
+		target string
+		status int
+	}{
+		{"/repos/backoffice-core-bff/logs", http.StatusOK},
+		{"/repos/backoffice-core-bff/logs?repo=backoffice-core-bff", http.StatusOK},
+		{"/repos/crm-core/logs", http.StatusForbidden},
+		// The path names the repo served, so ?repo= cannot stand in for it
+		{"/repos/crm-core/logs?repo=backoffice-core-bff", http.StatusBadRequest},
+		{"/repos/backoffice-core-bff/logs?repo=crm-core", http.StatusBadRequest},
+		{"/?repo=backoffice-core-bff", http.StatusOK},
+		{"/?repo=crm-core", http.StatusForbidden},
+	}
+	for _, c := range cases {
+		served = ""
+		request := httptest.NewRequest(http.MethodGet, c.target, nil)
+		request.Header.Set("Authorization", "Bearer console-token")
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		if recorder.Code != c.status {
+			t.Errorf("%s: status = %d, want %d", c.target, recorder.Code, c.status)
+		}
+		if served != "" && c.status != http.StatusOK {
+			t.Errorf("%s: served despite status %d", c.target, recorder.Code)
+		}
+	}
+}
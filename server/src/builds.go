@@ -0,0 +1,97 @@
+package main
+
+import (
+	"argocd/pkg/ci"
+	"argocd/pkg/summary"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const recentBuildsLimit = 50
+
+// ciProvider is nil when no CI credentials are configured
+var ciProvider ci.Provider
+
+// codefreshURL is the Codefresh instance builds and pipelines link to
+var codefreshURL = "https://g.codefresh.io"
+
+// setupCI enables the Codefresh provider when codefresh-token.txt exists
+func setupCI(baseURL, repoOwner string) error {
+	codefreshURL = strings.TrimRight(baseURL, "/")
+
+	tokenBytes, err := ioutil.ReadFile("codefresh-token.txt")
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("error reading codefresh token file: %v", err)
+	}
+
+	ciProvider = ci.NewCodefreshClient(baseURL, strings.TrimSpace(string(tokenBytes)), repoOwner)
+	return nil
+}
+
+func codefreshSearchURL(baseRepoName string) string {
+	return codefreshURL + "/pipelines/all/?filter=pageSize:10;field:name~Name;order:asc~Asc;search:" + baseRepoName
+}
+
+// linkBuilds attaches the repo's recent builds to the summary and points each
+// app's codefresh section at the build that produced its deployed image
+func linkBuilds(ctx context.Context, baseRepoName string, repoData *summary.Repo) {
+	if ciProvider == nil {
+		return
+	}
+
+	builds, err := ciProvider.RecentBuilds(ctx, baseRepoName, recentBuildsLimit)
+	if err != nil {
+		slog.WarnContext(ctx, "Error fetching builds", "provider", ciProvider.Name(), "repo", baseRepoName, "error", err)
+		return
+	}
+	repoData.Builds = builds
+
+	for i := range repoData.Apps {
+		app := &repoData.Apps[i]
+		for _, image := range app.Images {
+			build := ci.FindBuildForTag(builds, ci.ImageTag(image))
+			if build == nil {
+				continue
+			}
+			app.Codefresh = &summary.Codefresh{URL: build.URL, Build: build}
+			break
+		}
+	}
+}
+
+// repoBuildsHandler serves /repos/<name>/builds?limit=<n>
+func repoBuildsHandler(w http.ResponseWriter, r *http.Request, repoName string) {
+	if ciProvider == nil {
+		http.Error(w, "No CI provider configured", http.StatusNotImplemented)
+		return
+	}
+
+	limit := 20
+	if value := r.URL.Query().Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+		limit = parsed
+	}
+
+	builds, err := ciProvider.RecentBuilds(r.Context(), repoName, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching builds: %v", err), http.StatusBadGateway)
+		return
+	}
+	if builds == nil {
+		builds = []ci.Build{}
+	}
+	writeJSON(w, builds)
+}
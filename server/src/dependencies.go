@@ -0,0 +1,138 @@
+package main
+
+import (
+	"argocd/pkg/depgraph"
+	"argocd/pkg/gitProcessor"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+)
+
+// dependencyCache keeps the graph fleetDependencies scanned until the next
+// refresh, as the scan parses every Go file of every checkout
+var dependencyCache = struct {
+	sync.Mutex
+	graph   depgraph.Graph
+	loaded  bool
+	refresh uint64
+}{}
+
+// refreshes counts the completed refreshes, each of which may have changed
+// a checkout
+var refreshes atomic.Uint64
+
+// invalidateDependencies makes the next /dependencies scan the checkouts again
+func invalidateDependencies() {
+	refreshes.Add(1)
+}
+
+// cachedDependencies returns the graph scanned since the last refresh,
+// scanning the checkouts if there is none
+func cachedDependencies() (depgraph.Graph, error) {
+	// A refresh completing during the scan leaves the graph stale
+	refresh := refreshes.Load()
+	dependencyCache.Lock()
+	defer dependencyCache.Unlock()
+	if dependencyCache.loaded && dependencyCache.refresh == refresh {
+		return dependencyCache.graph, nil
+	}
+
+	graph, err := fleetDependencies()
+	if err != nil {
+		return depgraph.Graph{}, err
+	}
+	dependencyCache.graph = graph
+	dependencyCache.loaded = true
+	dependencyCache.refresh = refresh
+	return graph, nil
+}
+
+// fleetDependencies scans the checkout of every catalog repo for its
+// outbound dependencies
+func fleetDependencies() (depgraph.Graph, error) {
+	catalog, err := loadPismoData()
+	if err != nil {
+		return depgraph.Graph{}, err
+	}
+	repoModule, err := gitProcessor.NewRepositoryModule(gitProcessor.Options{})
+	if err != nil {
+		return depgraph.Graph{}, fmt.Errorf("error initializing repository module: %v", err)
+	}
+
+	// Repos without a checkout are known before any scan starts
+	fleet := make([]string, 0, len(catalog.Repositories))
+	checkouts := make(map[string]string)
+	var unscanned []string
+	for _, repo := range catalog.Repositories {
+		fleet = append(fleet, repo.RepositoryName)
+		repoDir := filepath.Join("projects/projects", repo.RepositoryName, "github")
+		if _, err := os.Stat(repoDir); err != nil {
+			unscanned = append(unscanned, repo.RepositoryName)
+			continue
+		}
+		checkouts[repo.RepositoryName] = repoDir
+	}
+
+	scanned := make(map[string][]gitProcessor.Outbound)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, 10)
+	for repo, repoDir := range checkouts {
+		wg.Add(1)
+		go func(repo, repoDir string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			outbounds, err := repoModule.OutboundDependencies(repoDir)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				slog.Warn("Error scanning outbound dependencies", "repo", repo, "error", err)
+				unscanned = append(unscanned, repo)
+				return
+			}
+			scanned[repo] = outbounds
+		}(repo, repoDir)
+	}
+	wg.Wait()
+
+	return depgraph.Build(scanned, fleet, unscanned), nil
+}
+
+// dependenciesHandler serves /dependencies: the HTTP hosts, GraphQL
+// upstreams, SNS topics and SQS queues each repo's code and ConfigMaps point
+// to, as JSON, or as a graph in DOT (?format=dot) or Mermaid
+// (?format=mermaid). ?repo= keeps the dependencies of and on one service.
+func dependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	format, err := depgraph.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	graph, err := cachedDependencies()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if repo := r.URL.Query().Get("repo"); repo != "" {
+		graph = graph.Around(repo)
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	if err := depgraph.Write(w, format, graph); err != nil {
+		slog.Warn("Error writing dependency graph", "format", format, "error", err)
+	}
+}
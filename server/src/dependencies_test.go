@@ -0,0 +1,100 @@
+package main
+
+import (
+	"argocd/pkg/depgraph"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// inWorkdir runs the test in a temporary working directory holding files,
+// as the handlers read the catalog and checkouts relative to it
+func inWorkdir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for path, content := range files {
+		writeTestFile(t, filepath.Join(dir, path), content)
+	}
+	previous, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(previous) })
+	return dir
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDependenciesHandlerScansOncePerRefresh(t *testing.T) {
+	dir := inWorkdir(t, map[string]string{
+		"projects/projects/pismo.json": `{"repositories":[
+			{"repository_name":"backoffice-core-bff"},
+			{"repository_name":"console-audit-api"},
+			{"repository_name":"psm-accounts"}
+		]}`,
+		"projects/projects/backoffice-core-bff/github/client.go": `package main
+
+var audit = "https://console-audit-api.pismolabs.io/v1"
+`,
+	})
+	invalidateDependencies()
+
+	// A deadlocked handler fails the test rather than hanging it
+	get := func() []depgraph.Edge {
+		t.Helper()
+		done := make(chan *httptest.ResponseRecorder, 1)
+		go func() {
+			recorder := httptest.NewRecorder()
+			dependenciesHandler(recorder, httptest.NewRequest(http.MethodGet, "/dependencies", nil))
+			done <- recorder
+		}()
+		var recorder *httptest.ResponseRecorder
+		select {
+		case recorder = <-done:
+		case <-time.After(10 * time.Second):
+			t.Fatal("/dependencies did not respond")
+		}
+		if recorder.Code != http.StatusOK {
+			t.Fatalf("status = %d: %s", recorder.Code, recorder.Body)
+		}
+		var graph depgraph.Graph
+		if err := json.Unmarshal(recorder.Body.Bytes(), &graph); err != nil {
+			t.Fatalf("bad graph: %v", err)
+		}
+		return graph.Edges
+	}
+
+	first := get()
+	if len(first) != 1 || first[0].To != "service:console-audit-api" {
+		t.Fatalf("first edges = %+v", first)
+	}
+
+	// A new dependency in a checkout is not seen until the next refresh
+	writeTestFile(t, filepath.Join(dir, "projects/projects/backoffice-core-bff/github/accounts.go"), `package main
+
+var accounts = "https://psm-accounts.pismolabs.io/v1"
+`)
+	if second := get(); len(second) != 1 {
+		t.Errorf("edges changed without a refresh: %+v", second)
+	}
+
+	invalidateDependencies()
+	if third := get(); len(third) != 2 || third[1].To != "service:psm-accounts" {
+		t.Errorf("edges after a refresh = %+v", third)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"argocd/pkg/argocdSession"
+	"argocd/pkg/deployments"
+	"argocd/pkg/gitProcessor"
+	"argocd/pkg/regions"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// repoDeploymentsHandler serves /repos/<name>/deployments: the ArgoCD sync
+// history of each of the repo's apps joined with its commits and tags, by env
+func repoDeploymentsHandler(w http.ResponseWriter, r *http.Request, repoName string) {
+	repoBitUrl, _, locations := getRepoFileDetails(repoName)
+	if repoBitUrl == "" {
+		http.Error(w, "Unknown baseRepoName", http.StatusBadRequest)
+		return
+	}
+
+	creds, err := argocdCredentials(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// Without the git details the log still has the revisions, only no
+	// versions or commit messages
+	repoModule, err := gitProcessor.NewRepositoryModule(gitProcessor.Options{CommitHistoryMonths: 24})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error initializing repository module: %v", err), http.StatusInternalServerError)
+		return
+	}
+	gitCtx, cancelGit := context.WithTimeout(r.Context(), gitTimeout)
+	commits, tags, err := repoModule.Revisions(gitCtx, filepath.Join("projects/projects", repoName, "github"))
+	cancelGit()
+	if err != nil {
+		slog.WarnContext(r.Context(), "Error reading git revisions", "repo", repoName, "error", err)
+	}
+
+	var histories []deployments.AppHistory
+	var unreachable []string
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, 10)
+	for _, location := range locations {
+		// Clusters ArgoCD does not manage have no sync history
+		if kubernetesClusters.For(location) != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(location regions.Location) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			appName := location.AppName(repoName)
+			syncs, err := appHistory(r.Context(), location, appName, creds)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				slog.WarnContext(r.Context(), "Error reading ArgoCD history", "app", appName, "error", err)
+				unreachable = append(unreachable, appName)
+				return
+			}
+			histories = append(histories, deployments.AppHistory{Location: location, App: appName, Syncs: syncs})
+		}(location)
+	}
+	wg.Wait()
+	sort.Strings(unreachable)
+
+	writeJSON(w, deployments.Build(repoName, histories, commits, tags, unreachable))
+}
+
+// appHistory reads the sync history of an app from its ArgoCD instance
+func appHistory(ctx context.Context, location regions.Location, appName string, creds argocdSession.Credentials) ([]deployments.Sync, error) {
+	ctx, cancel := context.WithTimeout(ctx, appTimeout)
+	defer cancel()
+
+	instance := argocdInstanceRegistry.For(location)
+	body, err := argocdCall(ctx, instance, creds, http.MethodGet, fmt.Sprintf("%s/api/v1/applications/%s?appNamespace=argocd", instance.URL, url.PathEscape(appName)))
+	if err != nil {
+		return nil, err
+	}
+	return deployments.ParseHistory(body)
+}
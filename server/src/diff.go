@@ -0,0 +1,59 @@
+package main
+
+import (
+	"argocd/pkg/drift"
+	"argocd/pkg/regions"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// repoDiffHandler serves /repos/<name>/diff?app=<app>: what drifted between
+// the live and desired state of each of the app's resources, from ArgoCD's
+// managed resources. ?all=true lists the resources in sync too.
+func repoDiffHandler(w http.ResponseWriter, r *http.Request, repoName string) {
+	// Only an app at one of the repo's declared locations is the repo's:
+	// crm-core-bff-prod-sa-east-1 also starts with crm-core-
+	appName := r.URL.Query().Get("app")
+	_, _, declared := getRepoFileDetails(repoName)
+	var location regions.Location
+	found := false
+	for _, candidate := range declared {
+		if candidate.AppName(repoName) == appName {
+			location, found = candidate, true
+			break
+		}
+	}
+	if !found {
+		http.Error(w, fmt.Sprintf("app must be one of %s's ArgoCD applications at its declared locations, <repo>-<account>-<region>", repoName), http.StatusBadRequest)
+		return
+	}
+	if kubernetesClusters.For(location) != nil {
+		http.Error(w, fmt.Sprintf("%s is read from Kubernetes directly and has no ArgoCD desired state", appName), http.StatusBadRequest)
+		return
+	}
+
+	creds, err := argocdCredentials(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	instance := argocdInstanceRegistry.For(location)
+	managedURL := fmt.Sprintf("%s/api/v1/applications/%s/managed-resources?appNamespace=argocd", instance.URL, url.PathEscape(appName))
+	ctx, cancel := context.WithTimeout(r.Context(), appTimeout)
+	defer cancel()
+	body, err := argocdCall(ctx, instance, creds, http.MethodGet, managedURL)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching managed resources of %s: %v", appName, err), http.StatusBadGateway)
+		return
+	}
+
+	resources, err := drift.ParseManagedResources(body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, drift.Build(appName, instance.Name, resources, r.URL.Query().Get("all") == "true"))
+}
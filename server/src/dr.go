@@ -0,0 +1,94 @@
+package main
+
+import (
+	"argocd/pkg/dr"
+	"argocd/pkg/regions"
+	"argocd/pkg/summary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// drFile declares DR pairs for repos whose clismo.yaml and terraform do not;
+// see projects/dr.example.json
+var drFile = "projects/dr.json"
+
+// drConfig is nil when drFile does not exist
+var drConfig *dr.Config
+
+// setupDR loads the Lighthouse DR pairs, if any are declared
+func setupDR() error {
+	if _, err := os.Stat(drFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	config, err := dr.LoadConfig(drFile)
+	if err != nil {
+		return err
+	}
+	drConfig = config
+	slog.Info("Loaded DR pairs", "file", drFile, "repos", len(config.Repos), "defaults", len(config.Defaults))
+	return nil
+}
+
+// repoDRPairs returns the DR pairs declared for a repo
+func repoDRPairs(baseRepoName string, regionDetails []regions.RegionDetails) []dr.Pair {
+	repoDir := filepath.Join("projects/projects", baseRepoName, "github")
+	return dr.Resolve(drConfig, baseRepoName, repoDir, regionDetails)
+}
+
+// applyDRPairs marks the failover region of each pair as a failover,
+// adding it when the repo's terraform does not deploy there so that its
+// absence shows up in the summary
+func applyDRPairs(locations []regions.Location, pairs []dr.Pair) []regions.Location {
+	for _, pair := range pairs {
+		failover := pair.FailoverLocation()
+		found := false
+		for i := range locations {
+			if locations[i].Key() == failover.Key() {
+				locations[i].Primary = false
+				found = true
+			}
+		}
+		if !found {
+			locations = append(locations, failover)
+		}
+	}
+	return locations
+}
+
+// repoDRHandler serves /repos/<name>/dr with the readiness of each of the
+// repo's DR pairs, judged from its last summary
+func repoDRHandler(w http.ResponseWriter, r *http.Request, repoName string) {
+	regionDetails, err := regions.ParseRegions(repoName)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error parsing regions: %v", err), http.StatusNotFound)
+		return
+	}
+	pairs := repoDRPairs(repoName, regionDetails)
+
+	deploymentPath := filepath.Join("projects/projects-summary", repoName+".json")
+	deploymentInfo, _, err := summary.Read(deploymentPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		deploymentInfo = &summary.Repo{RepoName: repoName}
+	} else if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	readiness := dr.Check(deploymentInfo, pairs)
+	ready := true
+	for _, result := range readiness {
+		ready = ready && result.Ready
+	}
+
+	writeJSON(w, map[string]interface{}{
+		"repo":  repoName,
+		"ready": ready,
+		"pairs": readiness,
+	})
+}
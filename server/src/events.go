@@ -0,0 +1,213 @@
+package main
+
+import (
+	"argocd/pkg/argocdSession"
+	"argocd/pkg/events"
+	"argocd/pkg/summary"
+	"argocd/pkg/tracing"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// eventBroker carries refresh progress and detected changes to /events subscribers
+var eventBroker = events.NewBroker(500)
+
+func publishEvent(event events.Event) {
+	eventBroker.Publish(event)
+}
+
+func appProgressEvent(baseRepoName string, app summary.App, done, total int) events.Event {
+	appName := app.AppName
+	location := app.Location()
+	status := "ok"
+	if app.HasErrors() {
+		status = "error"
+	}
+
+	return events.Event{
+		Type:     events.TypeRefreshProgress,
+		Repo:     baseRepoName,
+		App:      appName,
+		Env:      location.Env,
+		Location: location.Key(),
+		Message:  fmt.Sprintf("fetched %s (%d/%d)", appName, done, total),
+		Data: map[string]interface{}{
+			"done":   done,
+			"total":  total,
+			"status": status,
+		},
+	}
+}
+
+func publishRefreshFailed(baseRepoName string, err error) {
+	publishEvent(events.Event{
+		Type:    events.TypeRefreshFailed,
+		Repo:    baseRepoName,
+		Message: err.Error(),
+	})
+}
+
+// publishRefreshCompleted announces the end of a refresh followed by every
+// change detected against the previous summary
+func publishRefreshCompleted(baseRepoName string, previous, current *summary.Repo) {
+	invalidateDependencies()
+	changes := events.DetectChanges(baseRepoName, previous, current)
+	publishEvent(events.Event{
+		Type:    events.TypeRefreshCompleted,
+		Repo:    baseRepoName,
+		Message: fmt.Sprintf("refresh completed with %d changes", len(changes)),
+		Data:    map[string]interface{}{"changes": len(changes)},
+	})
+	for _, change := range changes {
+		publishEvent(change)
+	}
+}
+
+// refreshRepo runs a forced refresh of the repo, as handleRepoRequest does with force=true
+func refreshRepo(ctx context.Context, baseRepoName string, creds argocdSession.Credentials) error {
+	if err := checkAndPullRepo(ctx, baseRepoName); err != nil {
+		return err
+	}
+
+	repoBitUrl, namespace, locations := getRepoFileDetails(baseRepoName)
+	if repoBitUrl == "" {
+		return fmt.Errorf("unknown baseRepoName %s", baseRepoName)
+	}
+
+	_, err := processRepoData(ctx, baseRepoName, repoBitUrl, namespace, locations, true, creds)
+	return err
+}
+
+// refreshHandler starts a refresh in the background and returns straight
+// away; progress is streamed on /events
+func refreshHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	baseRepoName := r.URL.Query().Get("repo")
+	if baseRepoName == "" {
+		http.Error(w, "Missing repo parameter", http.StatusBadRequest)
+		return
+	}
+
+	creds, err := argocdCredentials(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	// The refresh outlives the request but keeps its request ID and trace
+	ctx := context.WithoutCancel(r.Context())
+	go func() {
+		if err := refreshRepo(ctx, baseRepoName, creds); err != nil {
+			slog.ErrorContext(ctx, "Refresh failed", "repo", baseRepoName, "error", err)
+			publishRefreshFailed(baseRepoName, err)
+		}
+	}()
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{
+		"repo":   baseRepoName,
+		"events": "/events?repo=" + baseRepoName,
+	})
+}
+
+// eventsHandler streams events as Server-Sent Events. ?repo= limits the
+// stream to one repo, and a Last-Event-ID header replays missed events.
+func eventsHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("lastEventId")
+	}
+	stream, unsubscribe := eventBroker.Subscribe(r.URL.Query().Get("repo"), lastEventID)
+	defer unsubscribe()
+
+	// Tell the browser how long to wait before reconnecting
+	fmt.Fprint(w, "retry: 5000\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(15 * time.Second)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case event, ok := <-stream:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+// runBackgroundRefresher refreshes every repo that has a summary file, one
+// repo at a time, so change events are published without anyone polling
+func runBackgroundRefresher(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		creds, err := argocdResolver.Service()
+		if err != nil {
+			slog.Warn("Skipping background refresh", "error", err)
+			continue
+		}
+
+		files, err := filepath.Glob(filepath.Join("projects/projects-summary", "*.json"))
+		if err != nil {
+			slog.Error("Error listing summaries", "error", err)
+			continue
+		}
+
+		for _, file := range files {
+			baseRepoName := strings.TrimSuffix(filepath.Base(file), ".json")
+			if _, err := os.Stat(filepath.Join("projects/projects", baseRepoName)); err != nil {
+				continue
+			}
+			ctx := tracing.WithRequestID(context.Background(), "background-"+tracing.NewRequestID())
+			if err := refreshRepo(ctx, baseRepoName, creds); err != nil {
+				slog.ErrorContext(ctx, "Background refresh failed", "repo", baseRepoName, "error", err)
+				publishRefreshFailed(baseRepoName, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"argocd/pkg/export"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// exportTables maps the names accepted by the -export flag to their builders
+var exportTables = map[string]func([]map[string]interface{}) export.Table{
+	"overview": export.Overview,
+	"versions": export.VersionMatrix,
+	"drift":    export.DriftMatrix,
+}
+
+// decodeReposList unpacks the /list-repos payload into its repository entries
+func decodeReposList(jsonData []byte) ([]map[string]interface{}, error) {
+	var response struct {
+		Repositories []map[string]interface{} `json:"repositories"`
+	}
+	if err := json.Unmarshal(jsonData, &response); err != nil {
+		return nil, fmt.Errorf("error parsing repos list: %v", err)
+	}
+	return response.Repositories, nil
+}
+
+// writeExport renders one table built from the repos list and sends it as a download
+func writeExport(w http.ResponseWriter, format export.Format, filename string, reposJSON []byte, build func([]map[string]interface{}) export.Table) {
+	repos, err := decodeReposList(reposJSON)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", format.ContentType())
+	if format != export.FormatJSON {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+"."+format.Extension()))
+	}
+	if err := export.Write(w, format, build(repos)); err != nil {
+		slog.Error("Error writing export", "format", format, "error", err)
+	}
+}
+
+func matrixHandler(filename string, build func([]map[string]interface{}) export.Table) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		setCORSHeaders(w, r)
+
+		// Handle preflight requests
+		if r.Method == http.MethodOptions {
+			return
+		}
+
+		format, err := export.ParseFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		reposJSON, _, err := getReposList(r.URL.Query().Get("force") == "true")
+		if err != nil {
+			slog.ErrorContext(r.Context(), "Error building repos list", "error", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		writeExport(w, format, filename, reposJSON, build)
+	}
+}
+
+// runExport writes the comma separated list of tables (overview, versions,
+// drift) to outputPath, or stdout when outputPath is empty
+func runExport(tableNames, formatName, outputPath string) error {
+	format, err := export.ParseFormat(formatName)
+	if err != nil {
+		return err
+	}
+
+	reposJSON, _, err := getReposList(true)
+	if err != nil {
+		return err
+	}
+	repos, err := decodeReposList(reposJSON)
+	if err != nil {
+		return err
+	}
+
+	var tables []export.Table
+	for _, name := range strings.Split(tableNames, ",") {
+		build, ok := exportTables[strings.TrimSpace(name)]
+		if !ok {
+			return fmt.Errorf("unknown export table %q, use overview, versions or drift", name)
+		}
+		tables = append(tables, build(repos))
+	}
+
+	var out io.Writer = os.Stdout
+	if outputPath != "" {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			return fmt.Errorf("error creating %s: %v", outputPath, err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	return export.Write(out, format, tables...)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"argocd/pkg/argocdSession"
+	"argocd/pkg/cluster"
+	"argocd/pkg/regions"
+	"context"
+	"log/slog"
+	"net/http"
+	"os"
+)
+
+// kubernetesFile routes the apps of clusters ArgoCD does not manage to
+// kubeconfig contexts; see projects/kubernetes.example.json
+var kubernetesFile = "projects/kubernetes.json"
+
+// kubernetesClusters is nil when kubernetesFile does not exist, and every app
+// is read from ArgoCD
+var kubernetesClusters *cluster.Clusters
+
+// setupKubernetes builds a clientset per routed kubeconfig context
+func setupKubernetes() error {
+	if _, err := os.Stat(kubernetesFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	config, err := cluster.LoadConfig(kubernetesFile)
+	if err != nil {
+		return err
+	}
+	clusters, err := cluster.NewClusters(*config, instrumentTransport)
+	if err != nil {
+		return err
+	}
+	kubernetesClusters = clusters
+	slog.Info("Loaded Kubernetes clusters", "file", kubernetesFile, "kubeconfig", config.Kubeconfig, "routes", len(config.Routes))
+	return nil
+}
+
+// workloadProvider returns where the apps of a location are read from: their
+// kubeconfig context when one is routed, their ArgoCD instance otherwise
+func workloadProvider(location regions.Location, creds argocdSession.Credentials) cluster.Provider {
+	if kubernetes := kubernetesClusters.For(location); kubernetes != nil {
+		return kubernetes
+	}
+
+	instance := argocdInstanceRegistry.For(location)
+	return cluster.NewArgoCD(instance.Name, instance.URL, func(ctx context.Context, url string) ([]byte, error) {
+		return argocdCall(ctx, instance, creds, http.MethodGet, url)
+	})
+}
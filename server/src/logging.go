@@ -0,0 +1,128 @@
+package main
+
+import (
+	"argocd/pkg/tracing"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// setupLogging routes slog, and the log package through it, to stderr as
+// text or JSON, adding the request ID and trace IDs of each record's context
+func setupLogging(format, level string) error {
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+		return fmt.Errorf("invalid log level %q: %v", level, err)
+	}
+	options := &slog.HandlerOptions{Level: logLevel}
+
+	var handler slog.Handler
+	switch format {
+	case "text":
+		handler = slog.NewTextHandler(os.Stderr, options)
+	case "json":
+		handler = slog.NewJSONHandler(os.Stderr, options)
+	default:
+		return fmt.Errorf("unknown log format %q, use text or json", format)
+	}
+
+	slog.SetDefault(slog.New(tracing.NewLogHandler(handler)))
+	return nil
+}
+
+// setupTracing exports spans to an OTLP/HTTP collector, a file, or nowhere.
+// Request IDs are handed out either way.
+func setupTracing(otlpEndpoint, traceFile string) error {
+	var exporter tracing.Exporter
+	switch {
+	case otlpEndpoint != "" && traceFile != "":
+		return fmt.Errorf("use either -otlp-endpoint or -trace-file, not both")
+	case otlpEndpoint != "":
+		headers := map[string]string{}
+		if tokenBytes, err := os.ReadFile("otlp-token.txt"); err == nil {
+			headers["Authorization"] = "Bearer " + strings.TrimSpace(string(tokenBytes))
+		}
+		exporter = tracing.NewOTLPExporter(otlpEndpoint, "lighthouse", headers)
+	case traceFile != "":
+		fileExporter, err := tracing.NewFileExporter(traceFile, "lighthouse")
+		if err != nil {
+			return err
+		}
+		exporter = fileExporter
+	default:
+		return nil
+	}
+
+	tracing.SetDefault(tracing.NewTracer("lighthouse", exporter))
+	return nil
+}
+
+// shutdownTracing flushes the spans still batched. Deferred calls do not run
+// when the process exits, so every way out of main calls it explicitly.
+func shutdownTracing() {
+	if err := tracing.Shutdown(); err != nil {
+		slog.Warn("Error shutting down tracing", "error", err)
+	}
+}
+
+// exitWithError logs err, flushes tracing and ends the process
+func exitWithError(message string, err error) {
+	slog.Error(message, "error", err)
+	shutdownTracing()
+	os.Exit(1)
+}
+
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush keeps /events streaming through the recorder
+func (r *statusRecorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// withRequestTracing gives every request an ID (X-Request-ID, or a new one)
+// and a root span continuing the caller's traceparent, and logs the outcome
+func withRequestTracing(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = tracing.NewRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ctx := tracing.WithRequestID(r.Context(), requestID)
+		ctx, span := tracing.StartRemote(ctx, r.Method+" "+r.URL.Path, r.Header.Get("traceparent"))
+		span.SetAttribute("http.method", r.Method)
+		// Not the query, which may carry ?access_token=
+		span.SetAttribute("http.target", r.URL.Path)
+		span.SetAttribute("request.id", requestID)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		start := time.Now()
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		span.SetAttribute("http.status_code", recorder.status)
+		if recorder.status >= 500 {
+			span.RecordError(fmt.Errorf("status %d", recorder.status))
+		}
+		span.Finish()
+
+		slog.InfoContext(ctx, "Request handled",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"duration_ms", time.Since(start).Milliseconds())
+	})
+}
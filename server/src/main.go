@@ -3,35 +3,48 @@ package main
 import (
 	"argocd/pkg/analyzer"
 	"argocd/pkg/analyzerArgoCd"
+	"argocd/pkg/argocdSession"
+	"argocd/pkg/auth"
+	"argocd/pkg/cluster"
+	"argocd/pkg/events"
+	"argocd/pkg/export"
 	"argocd/pkg/gitProcessor"
+	"argocd/pkg/grafana"
+	"argocd/pkg/inventory"
+	"argocd/pkg/tracing"
 
 	//"argocd/pkg/gitParser/pkg/gitProcessor"
 	"argocd/pkg/regions"
+	"argocd/pkg/summary"
 	"argocd/pkg/terraformConfig"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"github.com/tidwall/gjson"
 	"github.com/tidwall/pretty"
 	"io/ioutil"
 	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
-	"net/url"
 	"os"
 	"os/exec"
+	"os/signal"
 	"path/filepath"
-	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 )
 
 type RepoDetails struct {
-	RepoBitUrl      string
-	Namespace       string
-	AppNameSuffixes map[string]bool
+	RepoBitUrl string
+	Namespace  string
+	Locations  []regions.Location
 }
 
 var repoDetailsArray = []struct {
@@ -95,74 +108,84 @@ func handleTerraformConfigs(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte(jsonData))
 }
 
-func getRepoFileDetails(baseRepoName string) (string, string, map[string]bool) {
+func getRepoFileDetails(baseRepoName string) (string, string, []regions.Location) {
 	// Construct the path to the regions.json file
 	regionsFilePath := filepath.Join("projects/projects", baseRepoName, "regions.json")
 
 	// Get the region details directly using the parser
-	regions, err := regions.ParseRegions(baseRepoName)
+	regionDetails, err := regions.ParseRegions(baseRepoName)
 	if err != nil {
-		fmt.Printf("Error parsing regions: %v\n", err)
+		slog.Warn("Error parsing regions", "repo", baseRepoName, "error", err)
 		return "", "", nil
 	}
 
 	// Convert to JSON and write to file
-	jsonData, err := json.MarshalIndent(regions, "", "  ")
+	jsonData, err := json.MarshalIndent(regionDetails, "", "  ")
 	if err != nil {
-		fmt.Printf("Error marshaling regions to JSON: %v\n", err)
+		slog.Warn("Error marshaling regions to JSON", "repo", baseRepoName, "error", err)
 		return "", "", nil
 	}
 
 	// Write to file
 	err = ioutil.WriteFile(regionsFilePath, jsonData, 0644)
 	if err != nil {
-		fmt.Printf("Error writing regions.json file: %v\n", err)
+		slog.Warn("Error writing regions.json file", "repo", baseRepoName, "file", regionsFilePath, "error", err)
 		return "", "", nil
 	}
 
 	// Populate the data structure
 	repoBitUrl := fmt.Sprintf("https://github.com/pismo/%s", baseRepoName)
-	appNameSuffixes := make(map[string]bool)
+	var locations []regions.Location
+	seen := make(map[string]bool)
 	namespace := ""
 
 	// Use the first found namespace
-	for _, region := range regions {
-		suffix := fmt.Sprintf("-%s-%s", region.AccountDefault, region.RegionDefault)
+	for _, region := range regionDetails {
+		location := regions.NewLocation(region.AccountDefault, region.RegionDefault)
 		namespace = region.Namespace
-		appNameSuffixes[suffix] = true
+		if !seen[location.Key()] {
+			seen[location.Key()] = true
+			locations = append(locations, location)
+		}
 	}
+	locations = applyDRPairs(locations, repoDRPairs(baseRepoName, regionDetails))
+	sort.Slice(locations, func(i, j int) bool { return locations[i].Key() < locations[j].Key() })
 
-	return repoBitUrl, namespace, appNameSuffixes
+	return repoBitUrl, namespace, locations
 }
 
-func extractImageFromJSON(jsonData string) (string, error) {
-	// Define a regular expression to find the image field
-	cleanedJSON := strings.ReplaceAll(jsonData, "\\", "")
-	re := regexp.MustCompile(`"image":"([^"]+)"`)
-	matches := re.FindStringSubmatch(cleanedJSON)
-	if len(matches) < 2 {
-		return "", fmt.Errorf("no image found in JSON")
-	}
-	return matches[1], nil
+func getURLContent(ctx context.Context, client *http.Client, url, token string) ([]byte, error) {
+	return requestURLContent(ctx, client, http.MethodGet, url, token)
 }
 
-func getURLContent(url, token string) ([]byte, error) {
-	req, err := http.NewRequest("GET", url, nil)
+func requestURLContent(ctx context.Context, client *http.Client, method, url, token string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
 	if err != nil {
 		return nil, fmt.Errorf("error creating request: %w", err)
 	}
 
+	ctx, span := tracing.Start(ctx, method+" "+req.URL.Host)
+	defer span.Finish()
+	span.SetAttribute("http.url", req.URL.Path)
+
 	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("traceparent", span.Traceparent())
+	if requestID := tracing.RequestID(ctx); requestID != "" {
+		req.Header.Set("X-Request-ID", requestID)
+	}
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
+		span.RecordError(err)
 		return nil, fmt.Errorf("error making request: %w", err)
 	}
 	defer resp.Body.Close()
 
+	span.SetAttribute("http.status_code", resp.StatusCode)
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error: received status code %d", resp.StatusCode)
+		err := fmt.Errorf("error: received status code %d", resp.StatusCode)
+		span.RecordError(err)
+		return nil, err
 	}
 
 	body, err := ioutil.ReadAll(resp.Body)
@@ -177,119 +200,77 @@ func getURLContent(url, token string) ([]byte, error) {
 	//return []byte(cleanedBody), nil
 }
 
-func writeFormattedJSONToFile(filename string, jsonData []byte) error {
+func writeFormattedJSONToFile(ctx context.Context, filename string, jsonData []byte) error {
 	formattedData := pretty.Pretty(jsonData)
 
-	err := ioutil.WriteFile(filename, formattedData, 0644)
+	err := writeFileAtomic(filename, formattedData)
 	if err != nil {
 		return fmt.Errorf("error writing to file: %w", err)
 	}
 
-	fmt.Printf("Writing: %s\n", filename)
+	slog.DebugContext(ctx, "Wrote upstream response", "file", filename)
 
 	return nil
 }
 
-func fetchImages(baseRepoName, appNameSuffix, spr string, isPrimary bool) map[string]interface{} {
+func fetchImages(ctx context.Context, baseRepoName string, location regions.Location, spr string, creds argocdSession.Credentials) summary.App {
 
-	appName := baseRepoName + appNameSuffix
-	escapedAppName := url.QueryEscape(appName)
-	url1 := fmt.Sprintf("https://argocd.pismo.services/api/v1/applications/%s/resource-tree?appNamespace=argocd", escapedAppName)
-	url2 := fmt.Sprintf("https://argocd.pismo.services/api/v1/applications/%s/resource?name=%s&appNamespace=argocd&namespace=%s&resourceName=%s&version=v1alpha1&kind=Rollout&group=argoproj.io", escapedAppName, baseRepoName, spr, baseRepoName)
+	appName := location.AppName(baseRepoName)
+	ctx, span := tracing.Start(ctx, "fetchImages")
+	defer span.Finish()
+	span.SetAttribute("app", appName)
+	provider := workloadProvider(location, creds)
+	span.SetAttribute("provider", provider.Name())
 
-	tokenBytes, err := ioutil.ReadFile("token.txt")
-	if err != nil {
-		log.Fatalf("Error reading token file: %v", err)
-	}
-	token := strings.TrimSpace(string(tokenBytes))
+	slog.InfoContext(ctx, "Fetching app", "repo", baseRepoName, "app", appName, "namespace", spr, "primary", location.Primary, "provider", provider.Name())
 
-	fmt.Printf("baseRepoName: %s, appNameSuffix: %s, spr: %s, isPrimary: %t\n", baseRepoName, appNameSuffix, spr, isPrimary)
-
-	app := map[string]interface{}{"appName": appName, "type": "primary"}
-	if !isPrimary {
-		app["type"] = "failover"
-	}
+	app := summary.NewApp(appName, location)
+	app.Provider = provider.Name()
 	errors := []string{}
 	warnings := []string{}
 
-	body1, err := getURLContent(url1, token)
+	workload, err := provider.Workload(ctx, cluster.Ref{App: appName, Rollout: baseRepoName, Namespace: spr})
 	if err != nil {
-		errors = append(errors, err.Error())
-		app["error"] = errors
+		span.RecordError(err)
+		app.Error = append(errors, err.Error())
 		return app
 	}
 
-	err = writeFormattedJSONToFile("tmp/"+appName+"-url1.json", body1)
-	//if err != nil {
-	//	fmt.Println("Error writing to file:", err)
-	//	return map[string]interface{}{"appName": appName, "error": []string{err.Error()}}
-	//}
-
-	body2, err2 := getURLContent(url2, token)
-	if err2 != nil {
-		errors = append(errors, err2.Error())
-		app["error"] = errors
-		return app
+	for name, body := range workload.Raw {
+		writeFormattedJSONToFile(ctx, "tmp/"+appName+"-"+name+".json", body)
 	}
-
-	err = writeFormattedJSONToFile("tmp/"+appName+"-url2.json", body2)
-	//if err != nil {
-	//	fmt.Println("Error writing to file:", err)
-	//	return map[string]interface{}{"appName": appName, "error": []string{err.Error()}}
-	//}
-
-	// Process the first URL response
-	jsonString1 := string(body1)
-	phase := gjson.Get(jsonString1, "phase").String()
-	if phase == "Error" {
-		message := gjson.Get(jsonString1, "message").String()
-		errors = append(errors, message)
-	}
-
-	// Process the second URL response
-	jsonString2 := string(body2)
-	if strings.Contains(jsonString2, "Error") {
-		re := regexp.MustCompile(`"phase":"Error","message":"([^"]+)"`)
-		match := re.FindStringSubmatch(jsonString2)
-		if len(match) > 1 {
-			errors = append(errors, "Error found in application response: "+match[1])
-		} else {
-			errors = append(errors, "Error found in application response, but no specific message could be extracted.")
-		}
-	}
-
-	// Extract the image field from the JSON string
-	image, err := extractImageFromJSON(jsonString2)
-	if err != nil {
-		errors = append(errors, err.Error())
+	for _, warning := range workload.Warnings {
+		slog.WarnContext(ctx, "Error reading part of the workload", "app", appName, "provider", provider.Name(), "error", warning)
 	}
+	errors = append(errors, workload.Errors...)
+	app.HPAs = workload.HPAs
+	app.Events = workload.Events
 
-	var imageList []string
-	printedImages := make(map[string]bool)
+	// The analyzers and the inventory read the pods in the shape of ArgoCD's
+	// resource tree, whichever provider they came from
+	resourceTree := cluster.ResourceTree(workload.Pods)
+	rolloutManifest := string(workload.Rollout)
 
-	// Add the extracted image to the list if it matches the baseRepoName
-	if image != "" {
-		if strings.Contains(image, baseRepoName) && !printedImages[image] {
-			imageList = append(imageList, image)
-			printedImages[image] = true
-		}
-	}
+	// List every container, keeping the main images from the rollout as the
+	// app's images
+	app.Containers = inventory.FromArgoCD(baseRepoName, resourceTree, rolloutManifest)
+	imageList := inventory.Images(app.Containers, inventory.RoleMain, inventory.SourceRollout)
 
 	if len(imageList) == 0 {
 		errors = append(errors, "No images found")
 	}
-	app["images"] = imageList
+	app.Images = imageList
 
 	// Add warning if PR is found in any image name
 	for _, image := range imageList {
-		if strings.Contains(image, "PR-") && !strings.Contains(appNameSuffix, "integration") && !strings.Contains(appNameSuffix, "ext") {
+		if strings.Contains(image, "PR-") && location.Env != "integration" && location.Env != "ext" {
 			warnings = append(warnings, "PR found on non-integration/ext environment")
 			break
 		}
 	}
 
 	// Check if non-ext or non-integration images are the same
-	if !strings.Contains(appNameSuffix, "integration") && !strings.Contains(appNameSuffix, "ext") {
+	if location.Env != "integration" && location.Env != "ext" {
 		var referenceImage string
 		for _, image := range imageList {
 			if referenceImage == "" {
@@ -301,87 +282,110 @@ func fetchImages(baseRepoName, appNameSuffix, spr string, isPrimary bool) map[st
 		}
 	}
 
-	phase2 := gjson.Get(jsonString2, "operationState.phase").String()
-	if phase2 == "Error" {
-		message2 := gjson.Get(jsonString2, "operationState.message").String()
-		errors = append(errors, message2)
-	}
-
-	// Add errors and warnings to the app map if they exist
+	// Add errors and warnings to the app if they exist
 	if len(errors) > 0 {
-		app["error"] = errors
+		app.Error = errors
 	}
 	if len(warnings) > 0 {
-		app["warning"] = warnings
+		app.Warning = warnings
 	}
 
-	// Analyze deployment and add the result to the app map
-	result1, err := analyzer.AnalyzeDeployment(string(body1))
+	// Analyze deployment and add the result to the app
+	_, analyzerSpan := tracing.Start(ctx, "analyzer.AnalyzeDeployment")
+	result1, err := analyzer.AnalyzeDeployment(resourceTree)
+	analyzerSpan.RecordError(err)
+	analyzerSpan.Finish()
 	if err != nil {
-		log.Fatalf("Error analyzing deployment: %v", err)
+		app.Error = append(errors, fmt.Sprintf("error analyzing deployment: %v", err))
+		return app
 	}
 
-	var deployment interface{}
+	var deployment analyzer.DeploymentAnalysis
 	err = json.Unmarshal([]byte(result1), &deployment)
 	if err != nil {
-		log.Fatalf("Error unmarshalling deployment: %v", err)
+		app.Error = append(errors, fmt.Sprintf("error unmarshalling deployment: %v", err))
+		return app
 	}
 
-	app["deployment"] = deployment
+	app.Deployment = &deployment
 
-	result2, err := analyzerArgoCd.AnalyzeArgoCd(string(body2))
+	_, analyzerSpan = tracing.Start(ctx, "analyzerArgoCd.AnalyzeArgoCd")
+	rolloutResource, _ := json.Marshal(map[string]string{"manifest": rolloutManifest})
+	result2, err := analyzerArgoCd.AnalyzeArgoCd(string(rolloutResource))
+	analyzerSpan.RecordError(err)
+	analyzerSpan.Finish()
 	if err != nil {
-		log.Fatalf("Error analyzing deployment: %v", err)
+		app.Error = append(errors, fmt.Sprintf("error analyzing rollout: %v", err))
+		return app
 	}
 
-	var result2Map map[string]interface{}
-	err = json.Unmarshal([]byte(result2), &result2Map)
+	var rolloutStatus summary.RolloutStatus
+	err = json.Unmarshal([]byte(result2), &rolloutStatus)
 	if err != nil {
-		log.Fatalf("Error unmarshalling result2: %v", err)
+		app.Error = append(errors, fmt.Sprintf("error unmarshalling rollout analysis: %v", err))
+		return app
 	}
 
 	health := ""
-	nodesHealthStatus := gjson.Get(jsonString2, "health.status").String()
-	if nodesHealthStatus == "Error" {
-		health = gjson.Get(jsonString2, "health.status").String()
+	if workload.Health == "Error" {
+		health = workload.Health
 	}
 
-	app["argocd"] = map[string]interface{}{
-		"url":    "https://argocd.pismo.services/applications/argocd/" + appName + "?view=tree&orphaned=false&resource=",
-		"status": result2Map,
-		"health": health,
+	app.ArgoCD = &summary.ArgoCD{
+		Status: rolloutStatus,
+		Health: health,
+		// An aborted Argo Rollout sets status.abort on the Rollout manifest
+		Aborted: gjson.Get(rolloutManifest, "status.abort").Bool(),
 	}
-
-	app["grafana"] = map[string]string{
-		"url": "https://pismo.grafana.net/explore?schemaVersion=1&panes=%7B%22jqe%22%3A%7B%22datasource%22%3A%22grafanacloud-logs%22%2C%22queries%22%3A%5B%7B%22refId%22%3A%22A%22%2C%22expr%22%3A%22%7Bcontainer%3D%5C%22" + baseRepoName + "%5C%22%2C+env%3D%5C%22prod%5C%22%2C+version%3D%5C%221.24.0%5C%22%7D%22%2C%22queryType%22%3A%22range%22%2C%22datasource%22%3A%7B%22type%22%3A%22loki%22%2C%22uid%22%3A%22grafanacloud-logs%22%7D%2C%22editorMode%22%3A%22code%22%7D%5D%2C%22range%22%3A%7B%22from%22%3A%22now-7d%22%2C%22to%22%3A%22now%22%7D%7D%7D&orgId=1",
+	// Apps of clusters ArgoCD does not manage have no ArgoCD page to link to
+	if kubernetesClusters.For(location) == nil {
+		instance := argocdInstanceRegistry.For(location)
+		app.ArgoCD.URL = instance.URL + "/applications/argocd/" + appName + "?view=tree&orphaned=false&resource="
+		app.ArgoCD.Instance = instance.Name
+	}
+	pauseStart := gjson.Get(rolloutManifest, "status.pauseConditions.0.startTime").String()
+	if pausedSince, err := time.Parse(time.RFC3339, pauseStart); err == nil {
+		app.ArgoCD.PausedSince = &pausedSince
 	}
 
-	app["codefresh"] = map[string]string{
-		"url": "https://pismo.grafana.net/explore?schemaVersion=1&panes=%7B%22jqe%22%3A%7B%22datasource%22%3A%22grafanacloud-logs%22%2C%22queries%22%3A%5B%7B%22refId%22%3A%22A%22%2C%22expr%22%3A%22%7Bcontainer%3D%5C%22" + baseRepoName + "%5C%22%2C+env%3D%5C%22prod%5C%22%2C+version%3D%5C%221.24.0%5C%22%7D%22%2C%22queryType%22%3A%22range%22%2C%22datasource%22%3A%7B%22type%22%3A%22loki%22%2C%22uid%22%3A%22grafanacloud-logs%22%7D%2C%22editorMode%22%3A%22code%22%7D%5D%2C%22range%22%3A%7B%22from%22%3A%22now-7d%22%2C%22to%22%3A%22now%22%7D%7D%7D&orgId=1",
+	app.Grafana = &summary.Link{
+		URL: grafanaClient.ExploreURL(grafana.Target{
+			Container: baseRepoName,
+			Env:       location.Account,
+			Region:    location.Region,
+			Namespace: spr,
+			Version:   deployment.PrimaryVersion(),
+		}, "now-7d", "now"),
 	}
 
+	app.Codefresh = &summary.Codefresh{URL: codefreshSearchURL(baseRepoName)}
+
 	return app
 }
 
-func getRepoDetails(baseRepoName string) (string, string, map[string]bool) {
+func getRepoDetails(baseRepoName string) (string, string, []regions.Location) {
 	for _, repo := range repoDetailsArray {
 		if repo.BaseRepoName == baseRepoName {
-			return repo.Details.RepoBitUrl, repo.Details.Namespace, repo.Details.AppNameSuffixes
+			return repo.Details.RepoBitUrl, repo.Details.Namespace, repo.Details.Locations
 		}
 	}
-	fmt.Println("Unknown baseRepoName")
+	slog.Warn("Unknown baseRepoName", "repo", baseRepoName)
 	return "", "", nil
 }
 
-func processRepoData(baseRepoName, repoBitUrl, namespace string, appNameSuffixes map[string]bool, forceRefresh bool) ([]byte, error) {
+func processRepoData(ctx context.Context, baseRepoName, repoBitUrl, namespace string, locations []regions.Location, forceRefresh bool, creds argocdSession.Credentials) ([]byte, error) {
 	repoName := baseRepoName
+	ctx, span := tracing.Start(ctx, "processRepoData")
+	defer span.Finish()
+	span.SetAttribute("repo", baseRepoName)
+	span.SetAttribute("force", forceRefresh)
 
 	// Ensure the projects/summary directory exists
 	summaryDir := "projects/projects-summary"
 	if _, err := os.Stat(summaryDir); os.IsNotExist(err) {
 		err := os.MkdirAll(summaryDir, os.ModePerm)
 		if err != nil {
-			log.Fatalf("Error creating directory: %v", err)
+			return nil, fmt.Errorf("error creating directory %s: %w", summaryDir, err)
 		}
 	}
 
@@ -393,22 +397,71 @@ func processRepoData(baseRepoName, repoBitUrl, namespace string, appNameSuffixes
 		cacheTime = 1
 	}
 
+	// The summary file was read with the service credentials, so a caller
+	// reading ArgoCD with their own is never served it and their RBAC applies
+	shared := creds.Mode == argocdSession.ModeService
+
 	// Check if the file exists and is less than cacheTime seconds old
 	fileInfo, err := os.Stat(filename)
-	if err == nil && time.Since(fileInfo.ModTime()) < time.Duration(cacheTime)*time.Second {
+	if shared && err == nil && time.Since(fileInfo.ModTime()) < time.Duration(cacheTime)*time.Second {
 		// Read the data from the file
-		jsonData, err := ioutil.ReadFile(filename)
+		repoData, migrated, err := summary.Read(filename)
 		if err != nil {
 			return nil, fmt.Errorf("error reading from file: %w", err)
 		}
-		fmt.Println("JSON data read from", filename)
-		return jsonData, nil
+		if migrated {
+			if err := writeSummary(filename, repoData); err != nil {
+				slog.WarnContext(ctx, "Error writing upgraded summary", "repo", baseRepoName, "error", err)
+			}
+		}
+		slog.InfoContext(ctx, "Serving cached summary", "repo", baseRepoName, "file", filename, "upgraded", migrated)
+		span.SetAttribute("cache", "hit")
+		cacheRequests.Inc("summary", "hit")
+		// Flag a summary served while a newer one is being built
+		repoData.Refreshing = refreshGroup.InFlight(refreshKey(repoName, argocdSession.Credentials{Mode: argocdSession.ModeService}))
+		return json.MarshalIndent(repoData, "", "  ")
+	}
+	cacheRequests.Inc("summary", cacheResult(false, forceRefresh || !shared))
+
+	// Concurrent refreshes of the repo under the same credentials share one run
+	result, coalesced, err := refreshGroup.Do(ctx, refreshKey(repoName, creds), func(ctx context.Context) (interface{}, error) {
+		return buildRepoSummary(ctx, baseRepoName, repoBitUrl, namespace, locations, creds, filename)
+	})
+	span.SetAttribute("shared", coalesced)
+	if err != nil {
+		return nil, err
 	}
+	return result.([]byte), nil
+}
+
+// refreshKey coalesces refreshes of a repo only between callers reading
+// ArgoCD with the same credentials, so nobody is handed a summary read with
+// someone else's token
+func refreshKey(repoName string, creds argocdSession.Credentials) string {
+	return repoName + "|" + creds.Identity()
+}
+
+// buildRepoSummary refreshes the repo from git and ArgoCD and, when read with
+// the service credentials, writes its summary file. Run it through
+// refreshGroup so only one runs per repo and credentials.
+func buildRepoSummary(ctx context.Context, baseRepoName, repoBitUrl, namespace string, locations []regions.Location, creds argocdSession.Credentials, filename string) ([]byte, error) {
+	repoName := baseRepoName
+	ctx, span := tracing.Start(ctx, "buildRepoSummary")
+	defer span.Finish()
+	span.SetAttribute("repo", baseRepoName)
+
+	ctx, cancel := context.WithTimeout(ctx, refreshTimeout)
+	defer cancel()
+
+	refreshStart := time.Now()
+	defer func() {
+		refreshDuration.Observe(time.Since(refreshStart).Seconds(), baseRepoName)
+	}()
 
 	//// Process the data if the file does not exist or is older than cacheTime seconds
 	repo, err := getRepositoryBlock(repoName)
 	if err != nil {
-		fmt.Println(err)
+		slog.WarnContext(ctx, "Repo missing from catalog", "repo", repoName, "error", err)
 		//return
 	}
 
@@ -423,69 +476,164 @@ func processRepoData(baseRepoName, repoBitUrl, namespace string, appNameSuffixes
 	}
 
 	repoPath := filepath.Join("projects/projects", baseRepoName, "github")
-	repoDetails, err := repoModule.Extract(repoPath)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract repository details: %v", err)
-	}
+	var timedOut []string
+
+	gitCtx, cancelGit := context.WithTimeout(ctx, gitTimeout)
+	gitCtx, gitSpan := tracing.Start(gitCtx, "gitProcessor.Extract")
+	repoDetails, err := repoModule.Extract(gitCtx, repoPath)
+	gitSpan.RecordError(err)
+	gitSpan.Finish()
+	cancelGit()
 
 	var gitDetails gitProcessor.AnalysisResult
-	if err := json.Unmarshal(repoDetails, &gitDetails); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal repository details: %v", err)
+	switch {
+	case err != nil && ctx.Err() != nil:
+		return nil, fmt.Errorf("refresh of %s interrupted: %w", baseRepoName, ctx.Err())
+	case err != nil && errors.Is(err, context.DeadlineExceeded):
+		// Carry on without the git details rather than failing the repo
+		slog.WarnContext(ctx, "Git extraction timed out", "repo", baseRepoName, "timeout", gitTimeout)
+		timedOut = append(timedOut, "git")
+	case err != nil:
+		return nil, fmt.Errorf("failed to extract repository details: %v", err)
+	default:
+		if err := json.Unmarshal(repoDetails, &gitDetails); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal repository details: %v", err)
+		}
 	}
 
-	repoData := map[string]interface{}{
-		"repoName":      baseRepoName,
-		"repoBitUrl":    repoBitUrl,
-		"repoCodefresh": "https://g.codefresh.io/pipelines/all/?filter=pageSize:10;field:name~Name;order:asc~Asc;search:" + baseRepoName,
-		"apps":          []map[string]interface{}{},
-		"argocd": map[string]string{
-			"url": "https://argocd.pismo.services/applications?search=" + baseRepoName + "&showFavorites=false&proj=&sync=&autoSync=&health=&namespace=&cluster=&labels=",
+	repoData := &summary.Repo{
+		SchemaVersion: summary.SchemaVersion,
+		RepoName:      baseRepoName,
+		RepoBitURL:    repoBitUrl,
+		RepoCodefresh: codefreshSearchURL(baseRepoName),
+		Apps:          []summary.App{},
+		ArgoCD: summary.Link{
+			URL: argocdURL + "/applications?search=" + baseRepoName + "&showFavorites=false&proj=&sync=&autoSync=&health=&namespace=&cluster=&labels=",
 		},
-		"repoDesc":  repo.Description,
-		"repoSquad": repo.Team,
-		"github": map[string]interface{}{
-			"commit":    gitDetails.Commit,
-			"branch":    gitDetails.Branch,
-			"author":    gitDetails.Author,
-			"timestamp": gitDetails.Timestamp,
-			"tag":       gitDetails.Tag,
+		GitHub: summary.GitHub{
+			Commit:    gitDetails.Commit,
+			Branch:    gitDetails.Branch,
+			Author:    gitDetails.Author,
+			Timestamp: gitDetails.Timestamp,
+			Tag:       gitDetails.Tag,
 		},
 	}
+	repoData.RepoNamespace = namespace
+	if repo != nil {
+		repoData.RepoDesc = repo.Description
+		repoData.RepoSquad = repo.Team
+	}
+
+	// Keep the previous summary so changes can be announced once the refresh completes
+	previous, _, _ := summary.Read(filename)
+	total := len(locations)
+	done := 0
+	publishEvent(events.Event{
+		Type:    events.TypeRefreshStarted,
+		Repo:    baseRepoName,
+		Message: fmt.Sprintf("refreshing %d apps", total),
+		Data:    map[string]interface{}{"total": total},
+	})
 
 	// Limit the number of concurrent goroutines to 5 by using a semaphore pattern with a buffered channel.
 	var wg sync.WaitGroup
 	var mu sync.Mutex
 	sem := make(chan struct{}, 10) // Semaphore with a capacity of 1
+	fetchSlots.Set(float64(cap(sem)))
+
+	addApp := func(app summary.App) {
+		mu.Lock()
+		defer mu.Unlock()
+		repoData.Apps = append(repoData.Apps, app)
+		if app.TimedOut {
+			timedOut = append(timedOut, app.AppName)
+		}
+		done++
+		publishEvent(appProgressEvent(baseRepoName, app, done, total))
+	}
+
+	for _, location := range locations {
+		// Acquire a slot, unless the refresh has run out of time
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			addApp(timedOutApp(baseRepoName, location, "waiting for a fetch slot", ctx.Err()))
+			continue
+		}
 
-	for appNameSuffix, isPrimary := range appNameSuffixes {
 		wg.Add(1)
-		sem <- struct{}{} // Acquire a slot
-		go func(appNameSuffix string, isPrimary bool) {
+		go func(location regions.Location) {
 			defer wg.Done()
 			defer func() { <-sem }() // Release the slot
-
-			app := fetchImages(baseRepoName, appNameSuffix, namespace, isPrimary)
-			// a random 1 to 3 second pause
-			time.Sleep(time.Duration(rand.Intn(3)) * time.Second)
-			mu.Lock()
-			repoData["apps"] = append(repoData["apps"].([]map[string]interface{}), app)
-			mu.Unlock()
-		}(appNameSuffix, isPrimary)
+			fetchInFlight.Inc()
+			defer fetchInFlight.Dec()
+
+			appCtx, cancelApp := context.WithTimeout(ctx, appTimeout)
+			defer cancelApp()
+
+			app := fetchImages(appCtx, baseRepoName, location, namespace, creds)
+			if appCtx.Err() != nil {
+				app = timedOutApp(baseRepoName, location, "fetching from ArgoCD", appCtx.Err())
+			} else {
+				// a random 1 to 3 second pause
+				select {
+				case <-time.After(time.Duration(rand.Intn(3)) * time.Second):
+				case <-appCtx.Done():
+				}
+			}
+			addApp(app)
+		}(location)
 	}
 
 	wg.Wait()
 
+	// A caller that went away does not get a half-built summary written for them
+	if errors.Is(ctx.Err(), context.Canceled) {
+		err := fmt.Errorf("refresh of %s cancelled: %w", baseRepoName, ctx.Err())
+		publishRefreshFailed(baseRepoName, err)
+		return nil, err
+	}
+
+	if len(timedOut) > 0 {
+		sort.Strings(timedOut)
+		repoData.Partial = true
+		repoData.TimedOut = timedOut
+		slog.WarnContext(ctx, "Refresh finished with timeouts", "repo", baseRepoName, "timedOut", timedOut)
+	}
+
+	buildsCtx, cancelBuilds := context.WithTimeout(context.WithoutCancel(ctx), buildsTimeout)
+	linkBuilds(buildsCtx, baseRepoName, repoData)
+	cancelBuilds()
+
+	imagesCtx, cancelImages := context.WithTimeout(context.WithoutCancel(ctx), imagesTimeout)
+	inspectImages(imagesCtx, repoData)
+	cancelImages()
+
+	applyPolicies(ctx, repoData)
+
 	jsonData, err := json.MarshalIndent(repoData, "", "  ")
 	if err != nil {
+		publishRefreshFailed(baseRepoName, err)
 		return nil, fmt.Errorf("error marshalling to JSON: %w", err)
 	}
 
-	err = ioutil.WriteFile(filename, jsonData, 0644)
+	// The summary file is served to every caller, so only a summary read
+	// with the service credentials may fill it. One read with a user's
+	// credentials is returned to that user alone.
+	if creds.Mode != argocdSession.ModeService {
+		slog.InfoContext(ctx, "Summary built with caller credentials, not cached", "repo", baseRepoName, "mode", creds.Mode, "apps", total)
+		publishRefreshCompleted(baseRepoName, nil, repoData)
+		return jsonData, nil
+	}
+
+	err = writeFileAtomic(filename, jsonData)
 	if err != nil {
+		publishRefreshFailed(baseRepoName, err)
 		return nil, fmt.Errorf("error writing to file: %w", err)
 	}
 
-	fmt.Println("JSON data written to", filename)
+	slog.InfoContext(ctx, "Summary written", "repo", baseRepoName, "file", filename, "apps", total)
+	publishRefreshCompleted(baseRepoName, previous, repoData)
 	return jsonData, nil
 }
 
@@ -498,7 +646,8 @@ func setCORSHeaders(w http.ResponseWriter, r *http.Request) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
 	}
-	w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Credentials", "true")
 	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 }
 
@@ -529,16 +678,21 @@ func listReposHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 // checkAndPullRepo checks if the GitHub folder exists and pulls it if it doesn't.
-func checkAndPullRepo(baseRepoName string) error {
+func checkAndPullRepo(ctx context.Context, baseRepoName string) error {
 	repoPath := filepath.Join("projects/projects", baseRepoName, "github")
 	if _, err := os.Stat(repoPath); os.IsNotExist(err) {
-		fmt.Printf("Repository %s does not exist. Cloning...\n", baseRepoName)
+		slog.Info("Cloning repository", "repo", baseRepoName)
 		cloneURL := fmt.Sprintf("https://github.com/pismo/%s.git", baseRepoName)
-		cmd := exec.Command("git", "clone", cloneURL, repoPath)
-		if err := cmd.Run(); err != nil {
+		cloneCtx, cancel := context.WithTimeout(ctx, cloneTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(cloneCtx, "git", "clone", cloneURL, repoPath)
+		start := time.Now()
+		err := cmd.Run()
+		observeGit("clone", time.Since(start), err)
+		if err != nil {
 			return fmt.Errorf("error cloning repository: %v", err)
 		}
-		fmt.Printf("Repository %s cloned successfully.\n", baseRepoName)
+		slog.Info("Repository cloned", "repo", baseRepoName)
 	}
 	return nil
 }
@@ -554,25 +708,30 @@ func handleRepoRequest(w http.ResponseWriter, r *http.Request) {
 	// Check for force refresh parameter
 	forceRefresh := r.URL.Query().Get("force") == "true"
 
-	fmt.Println(forceRefresh)
-
 	baseRepoName := r.URL.Query().Get("repo")
 	if baseRepoName == "" {
 		http.Error(w, "Missing repo parameter", http.StatusBadRequest)
 		return
 	}
 
-	if err := checkAndPullRepo(baseRepoName); err != nil {
-		log.Fatalf("Error checking and pulling repository: %v", err)
+	if err := checkAndPullRepo(r.Context(), baseRepoName); err != nil {
+		http.Error(w, fmt.Sprintf("Error checking and pulling repository: %v", err), http.StatusBadGateway)
+		return
 	}
 
-	repoBitUrl, namespace, appNameSuffixes := getRepoFileDetails(baseRepoName)
+	repoBitUrl, namespace, locations := getRepoFileDetails(baseRepoName)
 	if repoBitUrl == "" {
 		http.Error(w, "Unknown baseRepoName", http.StatusBadRequest)
 		return
 	}
 
-	jsonData, err := processRepoData(baseRepoName, repoBitUrl, namespace, appNameSuffixes, forceRefresh)
+	creds, err := argocdCredentials(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	jsonData, err := processRepoData(r.Context(), baseRepoName, repoBitUrl, namespace, locations, forceRefresh, creds)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -582,44 +741,67 @@ func handleRepoRequest(w http.ResponseWriter, r *http.Request) {
 	w.Write(jsonData)
 }
 
-func getEnvironmentVersions(deploymentInfo map[string]interface{}) map[string]string {
-	envVersions := make(map[string]string)
+// repoResourceHandler serves the per-repo resources: /repos/<name>/tickets,
+// /repos/<name>/metrics, /repos/<name>/logs, /repos/<name>/builds, /repos/<name>/dr,
+// /repos/<name>/reconcile, /repos/<name>/deployments and /repos/<name>/diff
+func repoResourceHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
 
-	if apps, ok := deploymentInfo["apps"].([]interface{}); ok {
-		for _, app := range apps {
-			if appMap, ok := app.(map[string]interface{}); ok {
-				appName := appMap["appName"].(string)
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
 
-				// Skip if there's an error in the app data
-				if _, hasError := appMap["error"]; hasError {
-					continue
-				}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/repos/"), "/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.NotFound(w, r)
+		return
+	}
+	repoName, resource := parts[0], parts[1]
 
-				// Extract environment path from app name
-				parts := strings.Split(appName, "-")
-				if len(parts) <= 1 {
-					continue
-				}
+	switch resource {
+	case "tickets":
+		tickets, err := issueTracker.RepoTickets(r.Context(), repoName)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error fetching tickets: %v", err), http.StatusBadGateway)
+			return
+		}
+		writeJSON(w, tickets)
+	case "metrics":
+		appMetricsHandler(w, r, repoName)
+	case "logs":
+		appLogsHandler(w, r, repoName)
+	case "builds":
+		repoBuildsHandler(w, r, repoName)
+	case "dr":
+		repoDRHandler(w, r, repoName)
+	case "reconcile":
+		repoReconcileHandler(w, r, repoName)
+	case "deployments":
+		repoDeploymentsHandler(w, r, repoName)
+	case "diff":
+		repoDiffHandler(w, r, repoName)
+	default:
+		http.NotFound(w, r)
+	}
+}
 
-				// Remove the microservice name prefix
-				envPath := strings.TrimPrefix(appName, parts[0]+"-"+parts[1]+"-"+parts[2]+"-")
-				if envPath == "" {
-					continue
-				}
+func getEnvironmentVersions(deploymentInfo *summary.Repo) map[string]string {
+	envVersions := make(map[string]string)
 
-				// Get deployment info if it exists
-				if deployment, ok := appMap["deployment"].(map[string]interface{}); ok {
-					if deployments, ok := deployment["deployments"].([]interface{}); ok && len(deployments) > 0 {
-						// Get the first deployment's version
-						if deploy, ok := deployments[0].(map[string]interface{}); ok {
-							if version, ok := deploy["version"].(string); ok {
-								envKey := "env-" + envPath
-								envVersions[envKey] = version
-							}
-						}
-					}
-				}
-			}
+	for _, app := range deploymentInfo.Apps {
+		// Skip if there's an error in the app data
+		if app.HasErrors() {
+			continue
+		}
+
+		if app.Account == "" || app.Region == "" {
+			continue
+		}
+
+		// Get the first deployment's version if there is one
+		if app.Deployment != nil && len(app.Deployment.Deployments) > 0 {
+			envVersions["env-"+app.Location().Key()] = app.Deployment.Deployments[0].Version
 		}
 	}
 
@@ -637,49 +819,72 @@ func listReposFromFileHandler(w http.ResponseWriter, r *http.Request) {
 	// Check for force refresh parameter
 	forceRefresh := r.URL.Query().Get("force") == "true"
 
+	format, err := export.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	updatedJSON, cacheHit, err := getReposList(forceRefresh)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "Error building repos list", "error", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if format != export.FormatJSON {
+		writeExport(w, format, "fleet-overview", updatedJSON, export.Overview)
+		return
+	}
+
+	// Set response headers
+	w.Header().Set("Content-Type", "application/json")
+	if cacheHit {
+		w.Header().Set("X-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Cache", "MISS")
+	}
+	w.Write(updatedJSON)
+}
+
+// getReposList returns the /list-repos payload, served from reposListCache
+// unless forceRefresh is set or the cache has expired. The bool reports a cache hit.
+func getReposList(forceRefresh bool) ([]byte, bool, error) {
 	// Check cache if not forcing refresh
 	if !forceRefresh {
 		reposListMux.RLock()
 		if !reposListCache.Timestamp.IsZero() && time.Since(reposListCache.Timestamp) < cacheDuration {
+			data := reposListCache.Data
 			reposListMux.RUnlock()
-			w.Header().Set("Content-Type", "application/json")
-			w.Header().Set("X-Cache", "HIT")
-			w.Write(reposListCache.Data)
-			return
+			cacheRequests.Inc("repos_list", "hit")
+			return data, true, nil
 		}
 		reposListMux.RUnlock()
 	}
+	cacheRequests.Inc("repos_list", cacheResult(false, forceRefresh))
 
-	fmt.Println("Full refresh")
+	slog.Info("Full refresh of the repos list", "force", forceRefresh)
 	// Read the content of pismo.json
 	jsonData, err := ioutil.ReadFile("projects/projects/pismo.json")
 	if err != nil {
-		log.Printf("Error reading file: %v", err)
-		http.Error(w, "Error reading pismo.json", http.StatusInternalServerError)
-		return
+		return nil, false, fmt.Errorf("error reading pismo.json: %v", err)
 	}
 
 	// Parse the JSON into a map
 	var data map[string]interface{}
 	if err := json.Unmarshal(jsonData, &data); err != nil {
-		log.Printf("JSON Unmarshal error: %v", err)
-		http.Error(w, "Error parsing pismo.json", http.StatusInternalServerError)
-		return
+		return nil, false, fmt.Errorf("error parsing pismo.json: %v", err)
 	}
 
 	// Check if repositories exists and is an array
 	reposInterface, ok := data["repositories"]
 	if !ok {
-		log.Printf("No 'repositories' key found in JSON")
-		http.Error(w, "Invalid JSON structure", http.StatusInternalServerError)
-		return
+		return nil, false, fmt.Errorf("invalid JSON structure: no 'repositories' key found in pismo.json")
 	}
 
 	repos, ok := reposInterface.([]interface{})
 	if !ok {
-		log.Printf("'repositories' is not an array")
-		http.Error(w, "Invalid JSON structure", http.StatusInternalServerError)
-		return
+		return nil, false, fmt.Errorf("invalid JSON structure: 'repositories' is not an array")
 	}
 
 	// Convert repositories to the desired format
@@ -688,7 +893,7 @@ func listReposFromFileHandler(w http.ResponseWriter, r *http.Request) {
 	for i, repoInterface := range repos {
 		repo, ok := repoInterface.(map[string]interface{})
 		if !ok {
-			log.Printf("Repository at index %d is not an object", i)
+			slog.Warn("Skipping repository that is not an object", "index", i)
 			continue
 		}
 
@@ -697,21 +902,18 @@ func listReposFromFileHandler(w http.ResponseWriter, r *http.Request) {
 
 		repoName, ok := repo["repository_name"].(string)
 		if !ok {
-			log.Printf("Repository at index %d has no valid repository_name", i)
+			slog.Warn("Skipping repository without a valid repository_name", "index", i)
 			continue
 		}
 
+		var envVersions map[string]string
 		deploymentPath := filepath.Join("projects/projects-summary", repoName+".json")
 		if fileInfo, err := os.Stat(deploymentPath); err == nil && fileInfo.Size() > 0 {
 			processed = "true"
-			deploymentData, err := ioutil.ReadFile(deploymentPath)
-			if err == nil && len(deploymentData) > 0 {
-				var deploymentInfo map[string]interface{}
-				if err := json.Unmarshal(deploymentData, &deploymentInfo); err == nil {
-					if apps, ok := deploymentInfo["apps"].([]interface{}); ok && len(apps) > 0 {
-						on_env = "true"
-					}
-				}
+			if deploymentInfo, _, err := summary.Read(deploymentPath); err == nil && len(deploymentInfo.Apps) > 0 {
+				on_env = "true"
+				// Get environment versions
+				envVersions = getEnvironmentVersions(deploymentInfo)
 			}
 		}
 
@@ -729,23 +931,9 @@ func listReposFromFileHandler(w http.ResponseWriter, r *http.Request) {
 			newEntry["processed"] = processed
 		}
 
-		if fileInfo, err := os.Stat(deploymentPath); err == nil && fileInfo.Size() > 0 {
-			processed = "true"
-			deploymentData, err := ioutil.ReadFile(deploymentPath)
-			if err == nil && len(deploymentData) > 0 {
-				var deploymentInfo map[string]interface{}
-				if err := json.Unmarshal(deploymentData, &deploymentInfo); err == nil {
-					if apps, ok := deploymentInfo["apps"].([]interface{}); ok && len(apps) > 0 {
-						on_env = "true"
-						// Get environment versions
-						envVersions := getEnvironmentVersions(deploymentInfo)
-						// Add environment versions to newEntry
-						for env, version := range envVersions {
-							newEntry[env] = version
-						}
-					}
-				}
-			}
+		// Add environment versions to newEntry
+		for env, version := range envVersions {
+			newEntry[env] = version
 		}
 
 		result[i] = newEntry
@@ -760,9 +948,7 @@ func listReposFromFileHandler(w http.ResponseWriter, r *http.Request) {
 	// Convert to JSON
 	updatedJSON, err := json.Marshal(response)
 	if err != nil {
-		log.Printf("Error marshaling response: %v", err)
-		http.Error(w, "Error creating JSON response", http.StatusInternalServerError)
-		return
+		return nil, false, fmt.Errorf("error creating JSON response: %v", err)
 	}
 
 	// Update cache
@@ -773,47 +959,188 @@ func listReposFromFileHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	reposListMux.Unlock()
 
-	// Set response headers
-	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("X-Cache", "MISS")
-	w.Write(updatedJSON)
+	return updatedJSON, false, nil
 }
 
 func main() {
 	baseRepoNamePtr := flag.String("repo", "", "The base repository name")
 	webserverPtr := flag.Bool("webserver", false, "Run as a webserver")
+	exportPtr := flag.String("export", "", "Export tables instead of refreshing a repo: comma separated overview, versions, drift")
+	formatPtr := flag.String("format", "csv", "Export format: json, csv, md or xlsx")
+	outputPtr := flag.String("output", "", "Export output file (defaults to stdout)")
+	flag.StringVar(&squadsFile, "squads", squadsFile, "Path to the squads file")
+	flag.StringVar(&trackerFile, "tracker", trackerFile, "Path to the offline members and tickets file, used when -jira-url is not set")
+	jiraURLPtr := flag.String("jira-url", "", "Jira base URL, e.g. https://pismo.atlassian.net (token read from jira-token.txt)")
+	jiraUserPtr := flag.String("jira-user", "", "Jira user for basic auth; leave empty to send the token as a bearer token")
+	jiraProjectPtr := flag.String("jira-project", "", "Restrict ticket searches to this Jira project key")
+	grafanaURLPtr := flag.String("grafana-url", "https://pismo.grafana.net", "Grafana instance used for Explore links")
+	prometheusURLPtr := flag.String("prometheus-url", "", "Prometheus compatible API for app metrics (token read from grafana-token.txt)")
+	lokiURLPtr := flag.String("loki-url", "", "Loki compatible API for app error logs (token read from grafana-token.txt)")
+	grafanaUserPtr := flag.String("grafana-user", "", "User for basic auth against the Prometheus and Loki APIs; leave empty for bearer auth")
+	codefreshURLPtr := flag.String("codefresh-url", "https://g.codefresh.io", "Codefresh API base URL (API key read from codefresh-token.txt)")
+	codefreshOwnerPtr := flag.String("codefresh-owner", "pismo", "Git owner of the repos built in Codefresh")
+	flag.StringVar(&argocdURL, "argocd-url", argocdURL, "ArgoCD base URL")
+	flag.StringVar(&argocdInstancesFile, "argocd-instances", argocdInstancesFile, "Path to the ArgoCD instances config and the routes of accounts and regions to them; without it every app is read from -argocd-url")
+	flag.StringVar(&kubernetesFile, "kubernetes", kubernetesFile, "Path to the kubeconfig contexts the apps of clusters ArgoCD does not manage are read from")
+	flag.StringVar(&argocdRepoLabel, "argocd-repo-label", argocdRepoLabel, "Label ArgoCD applications carry with their repo name; when empty, a repo's applications are found by name prefix")
+	argocdExchangePtr := flag.String("argocd-exchange", "off", "Derive per-user ArgoCD tokens from OIDC sign-ins: off, passthrough (ArgoCD trusts the same issuer) or rfc8693")
+	argocdExchangeURLPtr := flag.String("argocd-exchange-url", "", "Token endpoint for rfc8693 token exchange (client secret read from argocd-exchange-secret.txt)")
+	argocdExchangeClientPtr := flag.String("argocd-exchange-client-id", "lighthouse", "Client id for rfc8693 token exchange")
+	argocdExchangeAudiencePtr := flag.String("argocd-exchange-audience", "argo-cd", "Audience requested in rfc8693 token exchange")
+	flag.StringVar(&authFile, "auth", authFile, "Path to the auth config (static tokens or OIDC); the API is open when it does not exist")
+	flag.StringVar(&registriesFile, "registries", registriesFile, "Path to the container registries config; images are resolved to digests when it exists (ECR uses the AWS_* environment credentials)")
+	flag.StringVar(&policiesFile, "policies", policiesFile, "Path to the policy rules apps are checked against on every refresh")
+	flag.StringVar(&drFile, "dr", drFile, "Path to the DR pairs declared in Lighthouse, used for repos whose clismo.yaml and terraform declare none")
+	flag.StringVar(&notificationsFile, "notifications", notificationsFile, "Path to the notification subscriptions file (notifications are off when it does not exist)")
+	logFormatPtr := flag.String("log-format", "text", "Log format: text or json")
+	logLevelPtr := flag.String("log-level", "info", "Log level: debug, info, warn or error")
+	otlpEndpointPtr := flag.String("otlp-endpoint", "", "Export spans to this OTLP/HTTP collector, e.g. http://localhost:4318 (bearer token read from otlp-token.txt)")
+	traceFilePtr := flag.String("trace-file", "", "Append spans as OTLP/JSON lines to this file for offline use")
+	flag.DurationVar(&refreshTimeout, "refresh-timeout", refreshTimeout, "Deadline for a whole repo refresh; apps not fetched by then are marked timed out")
+	flag.DurationVar(&appTimeout, "app-timeout", appTimeout, "Deadline for fetching one app from ArgoCD")
+	flag.DurationVar(&gitTimeout, "git-timeout", gitTimeout, "Deadline for reading a repo's git history")
+	flag.DurationVar(&cloneTimeout, "clone-timeout", cloneTimeout, "Deadline for cloning a repo")
+	refreshIntervalPtr := flag.Duration("refresh-interval", 0, "Refresh every processed repo in the background at this interval (0 disables)")
+	writeSchemaPtr := flag.String("write-schema", "", "Write the JSON Schema of the repo summary to this file and exit")
 	flag.Parse()
+	if err := setupLogging(*logFormatPtr, *logLevelPtr); err != nil {
+		log.Fatalf("Error configuring logging: %v", err)
+	}
+	if err := setupTracing(*otlpEndpointPtr, *traceFilePtr); err != nil {
+		exitWithError("Error configuring tracing", err)
+	}
+	setupMetrics()
 	baseRepoName := *baseRepoNamePtr
 	webserver := *webserverPtr
 
+	if err := setupGrafana(*grafanaURLPtr, *prometheusURLPtr, *lokiURLPtr, *grafanaUserPtr); err != nil {
+		exitWithError("Error configuring grafana", err)
+	}
+
+	if err := setupArgoCDDelegation(*argocdExchangePtr, *argocdExchangeURLPtr, *argocdExchangeClientPtr, *argocdExchangeAudiencePtr); err != nil {
+		exitWithError("Error configuring ArgoCD delegation", err)
+	}
+
+	if err := setupArgoCDInstances(); err != nil {
+		exitWithError("Error configuring ArgoCD instances", err)
+	}
+
+	if err := setupKubernetes(); err != nil {
+		exitWithError("Error configuring Kubernetes clusters", err)
+	}
+
+	if err := setupCI(*codefreshURLPtr, *codefreshOwnerPtr); err != nil {
+		exitWithError("Error configuring CI provider", err)
+	}
+
+	if err := setupDR(); err != nil {
+		exitWithError("Error configuring DR pairs", err)
+	}
+
+	if err := setupRegistry(); err != nil {
+		exitWithError("Error configuring registries", err)
+	}
+
+	if err := setupPolicies(); err != nil {
+		exitWithError("Error configuring policies", err)
+	}
+
+	if *writeSchemaPtr != "" {
+		if err := writeSummarySchema(*writeSchemaPtr); err != nil {
+			exitWithError("Error writing summary schema", err)
+		}
+		shutdownTracing()
+		return
+	}
+
+	if *exportPtr != "" {
+		if err := runExport(*exportPtr, *formatPtr, *outputPtr); err != nil {
+			exitWithError("Error exporting", err)
+		}
+		shutdownTracing()
+		return
+	}
+
 	if webserver {
-		http.HandleFunc("/", handleRepoRequest)
-		http.HandleFunc("/repos", listReposHandler)
-		http.HandleFunc("/list-repos", listReposFromFileHandler)
+		if err := setupAuth(); err != nil {
+			exitWithError("Error configuring auth", err)
+		}
+		if err := setupTracker(*jiraURLPtr, *jiraUserPtr, *jiraProjectPtr); err != nil {
+			exitWithError("Error configuring tracker", err)
+		}
+		if err := setupNotifications(); err != nil {
+			exitWithError("Error configuring notifications", err)
+		}
+
+		http.HandleFunc("/", requireRole(auth.Viewer, handleRepoRequest))
+		http.HandleFunc("/repos", requireRole(auth.Viewer, listReposHandler))
+		http.HandleFunc("/repos/", requireRole(auth.Viewer, repoResourceHandler))
+		http.HandleFunc("/list-repos", requireRole(auth.Viewer, listReposFromFileHandler))
+		http.HandleFunc("/version-matrix", requireRole(auth.Viewer, matrixHandler("version-matrix", export.VersionMatrix)))
+		http.HandleFunc("/drift-matrix", requireRole(auth.Viewer, matrixHandler("drift-matrix", export.DriftMatrix)))
+		http.HandleFunc("/squads", requireRole(auth.Viewer, listSquadsHandler))
+		http.HandleFunc("/squads/", requireRole(auth.Viewer, squadHandler))
+		http.HandleFunc("/ownership", requireRole(auth.Viewer, ownershipHandler))
+		http.HandleFunc("/sidecars", requireRole(auth.Viewer, sidecarsHandler))
+		http.HandleFunc("/dependencies", requireRole(auth.Viewer, dependenciesHandler))
+		http.HandleFunc("/violations", requireRole(auth.Viewer, violationsHandler))
+		http.HandleFunc("/events", requireRole(auth.Viewer, eventsHandler))
+		http.HandleFunc("/refresh", requireRole(auth.SquadOperator, refreshHandler))
+		http.HandleFunc("/metrics", requireRole(auth.Viewer, metricsRegistry.Handler()))
+		http.HandleFunc("/whoami", requireRole(auth.Viewer, whoamiHandler))
+		http.HandleFunc("/argocd/session", requireRole(auth.Viewer, argocdSessionHandler))
+		http.HandleFunc("/argocd/instances", requireRole(auth.Viewer, argocdInstancesHandler))
+		http.HandleFunc(summary.SchemaID, requireRole(auth.Viewer, summarySchemaHandler))
+
+		if *refreshIntervalPtr > 0 {
+			go runBackgroundRefresher(*refreshIntervalPtr)
+		}
 
-		fmt.Println("Starting web server on :8083")
-		if err := http.ListenAndServe(":8083", nil); err != nil {
-			fmt.Println("Error starting web server:", err)
+		server := &http.Server{Addr: ":8083", Handler: withRequestTracing(http.DefaultServeMux)}
+		go func() {
+			stop := make(chan os.Signal, 1)
+			signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+			<-stop
+			slog.Info("Shutting down web server")
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			if err := server.Shutdown(ctx); err != nil {
+				slog.Warn("Error shutting down web server", "error", err)
+			}
+		}()
+
+		slog.Info("Starting web server", "addr", ":8083")
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			exitWithError("Error starting web server", err)
 		}
+		shutdownTracing()
 		return
 	}
 
-	if err := checkAndPullRepo(baseRepoName); err != nil {
-		log.Fatalf("Error checking and pulling repository: %v", err)
+	if err := checkAndPullRepo(context.Background(), baseRepoName); err != nil {
+		exitWithError("Error checking and pulling repository", err)
 	}
 
-	repoBitUrl, namespace, appNameSuffixes := getRepoFileDetails(baseRepoName)
+	repoBitUrl, namespace, locations := getRepoFileDetails(baseRepoName)
 	if repoBitUrl == "" {
 		fmt.Println("Usage: go run main.go -repo=<repoName>")
 		fmt.Println("Available baseRepoNames:")
 		for _, repo := range repoDetailsArray {
 			fmt.Printf("  - %s\n", repo.BaseRepoName)
 		}
+		shutdownTracing()
 		return
 	}
 
-	_, err := processRepoData(baseRepoName, repoBitUrl, namespace, appNameSuffixes, true)
+	creds, err := argocdResolver.Service()
+	if err != nil {
+		exitWithError("Error reading ArgoCD credentials", err)
+	}
+
+	ctx := tracing.WithRequestID(context.Background(), tracing.NewRequestID())
+	_, err = processRepoData(ctx, baseRepoName, repoBitUrl, namespace, locations, true, creds)
 	if err != nil {
-		fmt.Println(err)
+		slog.ErrorContext(ctx, "Error refreshing repo", "repo", baseRepoName, "error", err)
 	}
+	shutdownTracing()
 }
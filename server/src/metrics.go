@@ -0,0 +1,56 @@
+package main
+
+import (
+	"argocd/pkg/gitProcessor"
+	"argocd/pkg/metrics"
+	"net/http"
+	"runtime"
+	"time"
+)
+
+// metricsRegistry backs /metrics
+var metricsRegistry = metrics.NewRegistry()
+
+var (
+	refreshDuration = metricsRegistry.NewHistogramVec("lighthouse_refresh_duration_seconds",
+		"Time to rebuild a repo summary from git and ArgoCD.", nil, "repo")
+	cacheRequests = metricsRegistry.NewCounterVec("lighthouse_cache_requests_total",
+		"Cache lookups by cache (repos_list, summary) and result (hit, miss, bypass).", "cache", "result")
+	gitDuration = metricsRegistry.NewHistogramVec("lighthouse_git_operation_duration_seconds",
+		"Time taken by git commands, by operation and result.", nil, "operation", "result")
+	fetchInFlight = metricsRegistry.NewGaugeVec("lighthouse_fetch_inflight",
+		"ArgoCD app fetches currently holding a slot of the fetch semaphore.")
+	fetchSlots = metricsRegistry.NewGaugeVec("lighthouse_fetch_slots",
+		"Capacity of the fetch semaphore.")
+)
+
+// setupMetrics instruments upstream HTTP calls and git commands. Clients
+// built without their own transport use http.DefaultTransport, so wrapping it
+// covers ArgoCD, Jira, Grafana, Codefresh and the notification sinks.
+func setupMetrics() {
+	http.DefaultTransport = metrics.NewTransport(metricsRegistry, http.DefaultTransport)
+	gitProcessor.Observe = observeGit
+
+	metricsRegistry.NewGaugeFunc("go_goroutines", "Number of goroutines that currently exist.", func() float64 {
+		return float64(runtime.NumGoroutine())
+	})
+}
+
+func observeGit(operation string, duration time.Duration, err error) {
+	result := "ok"
+	if err != nil {
+		result = "error"
+	}
+	gitDuration.Observe(duration.Seconds(), operation, result)
+}
+
+// cacheResult labels a cache lookup for lighthouse_cache_requests_total
+func cacheResult(hit, forceRefresh bool) string {
+	switch {
+	case hit:
+		return "hit"
+	case forceRefresh:
+		return "bypass"
+	}
+	return "miss"
+}
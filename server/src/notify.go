@@ -0,0 +1,99 @@
+package main
+
+import (
+	"argocd/pkg/notify"
+	"argocd/pkg/regions"
+	"argocd/pkg/squads"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// notificationsFile holds the notification subscriptions; see
+// projects/notifications.example.json
+var notificationsFile = "projects/notifications.json"
+
+// setupNotifications starts delivering change events to the configured
+// subscriptions. A missing config file leaves notifications off.
+func setupNotifications() error {
+	if _, err := os.Stat(notificationsFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	config, err := notify.LoadConfig(notificationsFile)
+	if err != nil {
+		return err
+	}
+
+	notifier, err := notify.NewNotifier(config, repoOwner, argocdAppURL)
+	if err != nil {
+		return err
+	}
+
+	go notifier.Run(eventBroker)
+	slog.Info("Delivering notifications", "subscriptions", len(config.Subscriptions), "file", notificationsFile)
+	return nil
+}
+
+// ownerCache keeps the owners repoOwner resolved for cacheDuration, as every
+// event and scoped request looks one up
+var ownerCache = struct {
+	sync.Mutex
+	owners   map[string]string
+	loadedAt time.Time
+}{owners: make(map[string]string)}
+
+// repoOwner resolves the squad that owns a repo, as shown on /ownership
+func repoOwner(repo string) string {
+	ownerCache.Lock()
+	if time.Since(ownerCache.loadedAt) > cacheDuration {
+		ownerCache.owners = make(map[string]string)
+		ownerCache.loadedAt = time.Now()
+	}
+	owner, ok := ownerCache.owners[repo]
+	ownerCache.Unlock()
+	if ok {
+		return owner
+	}
+
+	owner = resolveOwner(repo)
+	ownerCache.Lock()
+	ownerCache.owners[repo] = owner
+	ownerCache.Unlock()
+	return owner
+}
+
+func resolveOwner(repo string) string {
+	registry, err := squads.LoadSquads(squadsFile)
+	if err != nil {
+		return ""
+	}
+
+	catalogTeam := ""
+	if catalog, err := loadPismoData(); err == nil {
+		for _, entry := range catalog.Repositories {
+			if entry.RepositoryName == repo {
+				catalogTeam = entry.Team
+				break
+			}
+		}
+	}
+
+	repoDir := filepath.Join("projects/projects", repo, "github")
+	return registry.Reconcile(repo, catalogTeam, repoDir).Owner
+}
+
+func argocdAppURL(repo, app string) string {
+	instanceURL := argocdURL
+	if location, ok := regions.ParseAppName(repo, app); ok {
+		// Apps read from Kubernetes directly have no ArgoCD page
+		if kubernetesClusters.For(location) != nil {
+			return ""
+		}
+		instanceURL = argocdInstanceRegistry.For(location).URL
+	}
+	return fmt.Sprintf("%s/applications/argocd/%s?view=tree&orphaned=false&resource=", instanceURL, app)
+}
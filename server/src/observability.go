@@ -0,0 +1,140 @@
+package main
+
+import (
+	"argocd/pkg/grafana"
+	"argocd/pkg/summary"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// grafanaClient queries app metrics and logs, and builds Explore links
+var grafanaClient = grafana.NewClient(grafana.Config{ExploreURL: "https://pismo.grafana.net"})
+
+// setupGrafana configures the metrics and logs client. The API token is read
+// from grafana-token.txt when present.
+func setupGrafana(exploreURL, prometheusURL, lokiURL, user string) error {
+	token := ""
+	if tokenBytes, err := ioutil.ReadFile("grafana-token.txt"); err == nil {
+		token = strings.TrimSpace(string(tokenBytes))
+	} else if !os.IsNotExist(err) {
+		return fmt.Errorf("error reading grafana token file: %v", err)
+	}
+
+	grafanaClient = grafana.NewClient(grafana.Config{
+		ExploreURL:    exploreURL,
+		PrometheusURL: prometheusURL,
+		LokiURL:       lokiURL,
+		User:          user,
+		Token:         token,
+	})
+	return nil
+}
+
+// labelValuePattern is what a label value of a metrics or logs query may be
+var labelValuePattern = regexp.MustCompile(`^[A-Za-z0-9._:-]+$`)
+
+// maxLogLines bounds the ?limit= of /logs
+const maxLogLines = 1000
+
+// resolveTarget finds the account, region and deployed version of an app in
+// the repo's summary file; ?env= and ?version= override what the summary says
+func resolveTarget(repoName string, r *http.Request) (grafana.Target, error) {
+	appName := r.URL.Query().Get("app")
+	if appName == "" {
+		return grafana.Target{}, fmt.Errorf("missing app parameter")
+	}
+	if !strings.HasPrefix(appName, repoName+"-") {
+		return grafana.Target{}, fmt.Errorf("app %s does not belong to %s", appName, repoName)
+	}
+
+	deploymentPath := filepath.Join("projects/projects-summary", repoName+".json")
+	deploymentInfo, _, err := summary.Read(deploymentPath)
+	if err != nil {
+		return grafana.Target{}, fmt.Errorf("no summary for %s; refresh the repo first", repoName)
+	}
+
+	var target grafana.Target
+	found := false
+	for _, app := range deploymentInfo.Apps {
+		if app.AppName == appName {
+			target = grafana.Target{Container: repoName, Namespace: deploymentInfo.RepoNamespace, Env: app.Account, Region: app.Region, Version: app.Version()}
+			found = true
+			break
+		}
+	}
+	if !found {
+		return grafana.Target{}, fmt.Errorf("app %s is not in the summary of %s", appName, repoName)
+	}
+
+	if env := r.URL.Query().Get("env"); env != "" {
+		target.Env = env
+	}
+	if version := r.URL.Query().Get("version"); version != "" {
+		target.Version = version
+	}
+
+	// The labels end up in queries run with the server's Grafana credentials
+	for _, label := range []struct{ name, value string }{
+		{"container", target.Container},
+		{"env", target.Env},
+		{"region", target.Region},
+		{"namespace", target.Namespace},
+		{"version", target.Version},
+	} {
+		if label.value != "" && !labelValuePattern.MatchString(label.value) {
+			return grafana.Target{}, fmt.Errorf("invalid %s %q", label.name, label.value)
+		}
+	}
+	return target, nil
+}
+
+// appMetricsHandler serves /repos/<name>/metrics?app=<appName> with the
+// sparkline series for the version deployed to the app's environment
+func appMetricsHandler(w http.ResponseWriter, r *http.Request, repoName string) {
+	target, err := resolveTarget(repoName, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	metrics, err := grafanaClient.Sparklines(r.Context(), target)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching metrics: %v", err), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, metrics)
+}
+
+// appLogsHandler serves /repos/<name>/logs?app=<appName>&limit=<n> with the
+// app's recent error log lines, at most maxLogLines of them
+func appLogsHandler(w http.ResponseWriter, r *http.Request, repoName string) {
+	target, err := resolveTarget(repoName, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	limit := 100
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 {
+			http.Error(w, "Invalid limit parameter", http.StatusBadRequest)
+			return
+		}
+	}
+	if limit > maxLogLines {
+		limit = maxLogLines
+	}
+
+	logs, err := grafanaClient.ErrorLogs(r.Context(), target, limit)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching logs: %v", err), http.StatusBadGateway)
+		return
+	}
+	writeJSON(w, logs)
+}
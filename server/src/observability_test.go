@@ -0,0 +1,32 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolveTargetRejectsLabelsOutsideThePattern(t *testing.T) {
+	inWorkdir(t, map[string]string{
+		"projects/projects-summary/backoffice-core-bff.json": `{"schemaVersion":2,"repoName":"backoffice-core-bff","repoNamespace":"backoffice",
+			"apps":[{"appName":"backoffice-core-bff-prod-sa-east-1","account":"prod","region":"sa-east-1","env":"prod"}]}`,
+	})
+
+	cases := []struct {
+		query string
+		env   string
+		ok    bool
+	}{
+		{"app=backoffice-core-bff-prod-sa-east-1", "prod", true},
+		{"app=backoffice-core-bff-prod-sa-east-1&env=prod-ext&version=1.24.0-rc.1", "prod-ext", true},
+		{"app=backoffice-core-bff-prod-sa-east-1&env=" + `prod"}+or+{container=~".%2B`, "", false},
+		{"app=backoffice-core-bff-prod-sa-east-1&version=" + `1.24.0\`, "", false},
+		{"app=backoffice-core-bff-ext-sa-east-1", "", false},
+	}
+	for _, c := range cases {
+		target, err := resolveTarget("backoffice-core-bff", httptest.NewRequest(http.MethodGet, "/repos/backoffice-core-bff/logs?"+c.query, nil))
+		if (err == nil) != c.ok || target.Env != c.env {
+			t.Errorf("%s: target = %+v, err = %v", c.query, target, err)
+		}
+	}
+}
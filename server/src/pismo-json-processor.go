@@ -18,7 +18,8 @@ type PismoData struct {
 	TotalCount   int          `json:"total_count"`
 }
 
-func getRepositoryBlock(repoName string) (*Repository, error) {
+// loadPismoData reads the repository catalog from pismo.json
+func loadPismoData() (*PismoData, error) {
 	// Read the content of pismo.json
 	file, err := os.Open("projects/projects/pismo.json")
 	if err != nil {
@@ -33,6 +34,15 @@ func getRepositoryBlock(repoName string) (*Repository, error) {
 		return nil, fmt.Errorf("error parsing pismo.json: %v", err)
 	}
 
+	return &data, nil
+}
+
+func getRepositoryBlock(repoName string) (*Repository, error) {
+	data, err := loadPismoData()
+	if err != nil {
+		return nil, err
+	}
+
 	// Search for the repository by name
 	for _, repo := range data.Repositories {
 		if repo.RepositoryName == repoName {
@@ -46,3 +46,18 @@ type DeploymentAnalysis struct {
 	Deployments []VersionDeployment `json:"deployments"`
 }
 
+
+// PrimaryVersion returns the stable version, or the version running the most
+// pods when no deployment is labelled stable
+func (a DeploymentAnalysis) PrimaryVersion() string {
+	version, pods := "", -1
+	for _, deployment := range a.Deployments {
+		if deployment.Type == "stable" {
+			return deployment.Version
+		}
+		if deployment.PodCount > pods {
+			version, pods = deployment.Version, deployment.PodCount
+		}
+	}
+	return version
+}
@@ -0,0 +1,99 @@
+// pkg/argocdInstances/health.go
+package argocdInstances
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Health is the state of an instance: a probe of its health and version
+// endpoints, and how the calls made to it while refreshing repos went
+type Health struct {
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Status    string    `json:"status" enum:"up,down"`
+	Version   string    `json:"version,omitempty"`
+	LatencyMs int64     `json:"latencyMs"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checkedAt"`
+	Calls     CallStats `json:"calls"`
+}
+
+// CallStats counts the calls made to an instance since the server started
+type CallStats struct {
+	Total       int        `json:"total"`
+	Failed      int        `json:"failed"`
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	LastError   string     `json:"lastError,omitempty"`
+	LastErrorAt *time.Time `json:"lastErrorAt,omitempty"`
+}
+
+type callRecorder struct {
+	mu    sync.Mutex
+	stats CallStats
+}
+
+// Observe records the outcome of a call made to the instance
+func (i *Instance) Observe(err error) {
+	i.calls.mu.Lock()
+	defer i.calls.mu.Unlock()
+	now := time.Now()
+	i.calls.stats.Total++
+	if err != nil {
+		i.calls.stats.Failed++
+		i.calls.stats.LastError = err.Error()
+		i.calls.stats.LastErrorAt = &now
+		return
+	}
+	i.calls.stats.LastSuccess = &now
+}
+
+// CheckHealth probes the instance's /healthz and reads its version from
+// /api/version; neither needs a token
+func (i *Instance) CheckHealth(ctx context.Context) Health {
+	health := Health{Name: i.Name, URL: i.URL, Status: "up", CheckedAt: time.Now()}
+
+	i.calls.mu.Lock()
+	health.Calls = i.calls.stats
+	i.calls.mu.Unlock()
+
+	start := time.Now()
+	_, err := i.get(ctx, "/healthz")
+	health.LatencyMs = time.Since(start).Milliseconds()
+	if err != nil {
+		health.Status = "down"
+		health.Error = err.Error()
+		return health
+	}
+
+	if body, err := i.get(ctx, "/api/version"); err == nil {
+		var version struct {
+			Version string `json:"Version"`
+		}
+		if json.Unmarshal(body, &version) == nil {
+			health.Version = version.Version
+		}
+	}
+	return health
+}
+
+func (i *Instance) get(ctx context.Context, path string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", i.URL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	resp, err := i.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", path, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
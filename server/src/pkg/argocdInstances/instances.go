@@ -0,0 +1,229 @@
+// pkg/argocdInstances/instances.go
+package argocdInstances
+
+import (
+	"argocd/pkg/argocdSession"
+	"argocd/pkg/regions"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// DefaultName is the instance apps go to when no route matches
+const DefaultName = "default"
+
+// InstanceConfig is one ArgoCD control plane
+type InstanceConfig struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// TokenFile holds the instance's read-only service token
+	TokenFile string `json:"tokenFile,omitempty"`
+	// CallerCredentials sends the caller's own ArgoCD credentials instead of
+	// the service token, for instances sharing the default instance's SSO
+	CallerCredentials bool `json:"callerCredentials,omitempty"`
+	// CAFile is a PEM bundle trusted in addition to the system roots
+	CAFile string `json:"caFile,omitempty"`
+	// ClientCertFile and ClientKeyFile authenticate with mutual TLS
+	ClientCertFile     string `json:"clientCertFile,omitempty"`
+	ClientKeyFile      string `json:"clientKeyFile,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// Route sends the apps of matching locations to an instance. Account and
+// Region are path.Match patterns such as "itau*"; empty matches anything.
+type Route struct {
+	Account  string `json:"account,omitempty"`
+	Region   string `json:"region,omitempty"`
+	Instance string `json:"instance"`
+}
+
+type Config struct {
+	Instances []InstanceConfig `json:"instances"`
+	// Routes are tried in order; the first match wins
+	Routes []Route `json:"routes"`
+}
+
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading ArgoCD instances config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing ArgoCD instances config: %v", err)
+	}
+	return &config, nil
+}
+
+// Instance is a configured ArgoCD control plane
+type Instance struct {
+	Name   string
+	URL    string
+	Client *http.Client
+
+	token             string
+	callerCredentials bool
+	calls             callRecorder
+}
+
+// Credentials returns what to call the instance with on behalf of a caller:
+// their own credentials, or the instance's read-only service token
+func (i *Instance) Credentials(caller argocdSession.Credentials) argocdSession.Credentials {
+	if i.callerCredentials {
+		return caller
+	}
+	return argocdSession.Credentials{Token: i.token, Mode: argocdSession.ModeService, ReadOnly: true}
+}
+
+// Registry holds the ArgoCD instances and routes apps to them
+type Registry struct {
+	instances map[string]*Instance
+	names     []string
+	routes    []Route
+}
+
+// NewRegistry builds the instances of config. The default instance is
+// defaultURL with the caller's credentials unless config declares one. wrap,
+// if set, wraps the transports of instances with TLS settings of their own,
+// e.g. to instrument them; the others use http.DefaultTransport.
+func NewRegistry(config Config, defaultURL string, wrap func(http.RoundTripper) http.RoundTripper) (*Registry, error) {
+	registry := &Registry{instances: make(map[string]*Instance)}
+
+	hasDefault := false
+	for _, instance := range config.Instances {
+		if instance.Name == DefaultName {
+			hasDefault = true
+		}
+	}
+	if !hasDefault {
+		config.Instances = append([]InstanceConfig{{Name: DefaultName, URL: defaultURL, CallerCredentials: true}}, config.Instances...)
+	}
+
+	for _, instanceConfig := range config.Instances {
+		if instanceConfig.Name == "" || instanceConfig.URL == "" {
+			return nil, fmt.Errorf("ArgoCD instances need a name and a url")
+		}
+		if registry.instances[instanceConfig.Name] != nil {
+			return nil, fmt.Errorf("ArgoCD instance %s is declared twice", instanceConfig.Name)
+		}
+		instance, err := newInstance(instanceConfig, wrap)
+		if err != nil {
+			return nil, err
+		}
+		registry.instances[instance.Name] = instance
+		registry.names = append(registry.names, instance.Name)
+	}
+
+	for _, route := range config.Routes {
+		if registry.instances[route.Instance] == nil {
+			return nil, fmt.Errorf("route %s/%s goes to unknown ArgoCD instance %q", route.Account, route.Region, route.Instance)
+		}
+		for _, pattern := range []string{route.Account, route.Region} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid route pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	registry.routes = config.Routes
+	return registry, nil
+}
+
+func newInstance(config InstanceConfig, wrap func(http.RoundTripper) http.RoundTripper) (*Instance, error) {
+	instance := &Instance{
+		Name:              config.Name,
+		URL:               strings.TrimRight(config.URL, "/"),
+		callerCredentials: config.CallerCredentials,
+	}
+
+	if config.TokenFile != "" {
+		data, err := os.ReadFile(config.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading token of ArgoCD instance %s: %v", config.Name, err)
+		}
+		instance.token = strings.TrimSpace(string(data))
+	} else if !config.CallerCredentials {
+		return nil, fmt.Errorf("ArgoCD instance %s needs a tokenFile or callerCredentials", config.Name)
+	}
+
+	// Calls are bounded by the deadlines of their contexts
+	instance.Client = &http.Client{}
+	if config.CAFile == "" && config.ClientCertFile == "" && config.ClientKeyFile == "" && !config.InsecureSkipVerify {
+		return instance, nil
+	}
+
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading CA of ArgoCD instance %s: %v", config.Name, err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates in the CA file of ArgoCD instance %s", config.Name)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate of ArgoCD instance %s: %v", config.Name, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if wrap != nil {
+		transport = wrap(transport)
+	}
+	instance.Client.Transport = transport
+	return instance, nil
+}
+
+// For returns the instance serving the apps of a location
+func (r *Registry) For(location regions.Location) *Instance {
+	for _, route := range r.routes {
+		if matches(route.Account, location.Account) && matches(route.Region, location.Region) {
+			return r.instances[route.Instance]
+		}
+	}
+	return r.instances[DefaultName]
+}
+
+func matches(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
+
+// Default returns the default instance
+func (r *Registry) Default() *Instance {
+	return r.instances[DefaultName]
+}
+
+// Instances returns every instance in the order they were declared
+func (r *Registry) Instances() []*Instance {
+	instances := make([]*Instance, 0, len(r.names))
+	for _, name := range r.names {
+		instances = append(instances, r.instances[name])
+	}
+	return instances
+}
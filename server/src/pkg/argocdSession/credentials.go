@@ -0,0 +1,87 @@
+// pkg/argocdSession/credentials.go
+package argocdSession
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	// ModeSession uses an ArgoCD token the user stored in their session
+	ModeSession = "session"
+	// ModeExchange uses a token derived from the user's OIDC identity
+	ModeExchange = "exchange"
+	// ModeService uses the shared token.txt, which is read-only
+	ModeService = "service"
+)
+
+// Credentials are what an ArgoCD call runs under
+type Credentials struct {
+	Token   string `json:"-"`
+	Mode    string `json:"mode"`
+	Subject string `json:"subject,omitempty"`
+	// ReadOnly credentials may only be used for GET requests
+	ReadOnly bool `json:"readOnly"`
+}
+
+// CheckAction rejects write calls made with read-only credentials
+func (c Credentials) CheckAction(method string) error {
+	if c.ReadOnly && method != "GET" && method != "HEAD" {
+		return fmt.Errorf("%s requests need user ArgoCD credentials; the shared service token is read-only", method)
+	}
+	return nil
+}
+
+// Identity tells apart the callers whose ArgoCD reads may differ: everyone on
+// the service token shares one identity, and every user token is its own.
+// It never contains the token itself.
+func (c Credentials) Identity() string {
+	if c.Mode == ModeService {
+		return ModeService
+	}
+	sum := sha256.Sum256([]byte(c.Token))
+	return c.Mode + ":" + hex.EncodeToString(sum[:8])
+}
+
+// Resolver picks the ArgoCD credentials for a caller: a session token first,
+// then a token exchanged from their OIDC identity, then the service token
+type Resolver struct {
+	Sessions  *Store
+	Exchanger *Exchanger
+	// ServiceTokenFile is the shared token, read on every use so it can be rotated
+	ServiceTokenFile string
+}
+
+// Resolve returns the credentials for a request. sessionID and subject come
+// from the session cookie and the authenticated principal; oidcToken is the
+// caller's bearer token when they signed in with OIDC.
+func (r *Resolver) Resolve(ctx context.Context, sessionID, subject, oidcToken string) (Credentials, error) {
+	if r.Sessions != nil && sessionID != "" {
+		if session, ok := r.Sessions.Get(sessionID); ok && session.Subject == subject {
+			return Credentials{Token: session.Token, Mode: ModeSession, Subject: session.Username}, nil
+		}
+	}
+
+	if r.Exchanger != nil && oidcToken != "" {
+		token, err := r.Exchanger.Exchange(ctx, oidcToken)
+		if err != nil {
+			return Credentials{}, fmt.Errorf("error exchanging OIDC token for ArgoCD: %v", err)
+		}
+		return Credentials{Token: token, Mode: ModeExchange, Subject: subject}, nil
+	}
+
+	return r.Service()
+}
+
+// Service returns the shared read-only credentials
+func (r *Resolver) Service() (Credentials, error) {
+	tokenBytes, err := os.ReadFile(r.ServiceTokenFile)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("error reading token file: %v", err)
+	}
+	return Credentials{Token: strings.TrimSpace(string(tokenBytes)), Mode: ModeService, ReadOnly: true}, nil
+}
@@ -0,0 +1,122 @@
+// pkg/argocdSession/exchange.go
+package argocdSession
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Exchanger turns the caller's OIDC token into an ArgoCD token. With no
+// TokenURL the OIDC token is passed straight through, which works when ArgoCD
+// trusts the same issuer. Otherwise it runs an RFC 8693 token exchange.
+type Exchanger struct {
+	TokenURL     string
+	ClientID     string
+	ClientSecret string
+	Audience     string
+	HTTPClient   *http.Client
+
+	mu    sync.Mutex
+	cache map[string]exchanged
+}
+
+type exchanged struct {
+	token   string
+	expires time.Time
+}
+
+func NewExchanger(tokenURL, clientID, clientSecret, audience string) *Exchanger {
+	return &Exchanger{
+		TokenURL:     tokenURL,
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		Audience:     audience,
+		HTTPClient:   &http.Client{Timeout: 10 * time.Second},
+		cache:        make(map[string]exchanged),
+	}
+}
+
+func (e *Exchanger) Exchange(ctx context.Context, subjectToken string) (string, error) {
+	if e.TokenURL == "" {
+		return subjectToken, nil
+	}
+
+	sum := sha256.Sum256([]byte(subjectToken))
+	key := hex.EncodeToString(sum[:])
+
+	e.mu.Lock()
+	cached, ok := e.cache[key]
+	e.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.token, nil
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:token-exchange")
+	form.Set("subject_token", subjectToken)
+	form.Set("subject_token_type", "urn:ietf:params:oauth:token-type:jwt")
+	form.Set("requested_token_type", "urn:ietf:params:oauth:token-type:access_token")
+	if e.Audience != "" {
+		form.Set("audience", e.Audience)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", e.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if e.ClientID != "" {
+		req.SetBasicAuth(url.QueryEscape(e.ClientID), url.QueryEscape(e.ClientSecret))
+	}
+
+	resp, err := e.HTTPClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("error: received status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("error parsing token response: %v", err)
+	}
+	if result.AccessToken == "" {
+		return "", fmt.Errorf("token response has no access_token")
+	}
+
+	expiresIn := time.Duration(result.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 5 * time.Minute
+	}
+
+	e.mu.Lock()
+	for k, v := range e.cache {
+		if time.Now().After(v.expires) {
+			delete(e.cache, k)
+		}
+	}
+	// Refresh a little early so a token never expires mid-refresh
+	e.cache[key] = exchanged{token: result.AccessToken, expires: time.Now().Add(expiresIn * 9 / 10)}
+	e.mu.Unlock()
+
+	return result.AccessToken, nil
+}
@@ -0,0 +1,69 @@
+// pkg/argocdSession/session.go
+package argocdSession
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// Session holds a user's own ArgoCD token. Subject is the Lighthouse
+// principal that stored it, so a leaked cookie is useless to anyone else.
+type Session struct {
+	Subject  string    `json:"subject"`
+	Username string    `json:"username"`
+	Groups   []string  `json:"groups,omitempty"`
+	Token    string    `json:"-"`
+	Expires  time.Time `json:"expires"`
+}
+
+// Store keeps sessions in memory; they are lost when the server restarts
+type Store struct {
+	ttl      time.Duration
+	mu       sync.Mutex
+	sessions map[string]Session
+}
+
+func NewStore(ttl time.Duration) *Store {
+	return &Store{ttl: ttl, sessions: make(map[string]Session)}
+}
+
+// Put stores the session and returns its new id
+func (s *Store) Put(session Session) (string, error) {
+	idBytes := make([]byte, 32)
+	if _, err := rand.Read(idBytes); err != nil {
+		return "", err
+	}
+	id := hex.EncodeToString(idBytes)
+
+	if session.Expires.IsZero() || time.Until(session.Expires) > s.ttl {
+		session.Expires = time.Now().Add(s.ttl)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[id] = session
+	return id, nil
+}
+
+func (s *Store) Get(id string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	if time.Now().After(session.Expires) {
+		delete(s.sessions, id)
+		return Session{}, false
+	}
+	return session, true
+}
+
+func (s *Store) Delete(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+}
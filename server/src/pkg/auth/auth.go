@@ -0,0 +1,116 @@
+// pkg/auth/auth.go
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+type Role string
+
+const (
+	Viewer        Role = "viewer"
+	SquadOperator Role = "squad-operator"
+	Admin         Role = "admin"
+)
+
+var roleRank = map[Role]int{Viewer: 1, SquadOperator: 2, Admin: 3}
+
+func ParseRole(value string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(value)))
+	if _, ok := roleRank[role]; !ok {
+		return "", fmt.Errorf("unknown role %q, use viewer, squad-operator or admin", value)
+	}
+	return role, nil
+}
+
+// Includes reports whether the role grants at least the required role
+func (r Role) Includes(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// Binding grants a role on some squads; no squads means every squad
+type Binding struct {
+	Role   Role     `json:"role"`
+	Squads []string `json:"squads,omitempty"`
+}
+
+func (b Binding) coversSquad(squad string) bool {
+	if len(b.Squads) == 0 {
+		return true
+	}
+	for _, s := range b.Squads {
+		if s == squad {
+			return true
+		}
+	}
+	return false
+}
+
+type Principal struct {
+	Subject  string    `json:"subject"`
+	Name     string    `json:"name,omitempty"`
+	Email    string    `json:"email,omitempty"`
+	Groups   []string  `json:"groups,omitempty"`
+	Bindings []Binding `json:"bindings"`
+	// Method is static, oidc or none
+	Method string `json:"method"`
+	// Token is the raw bearer credential, kept for delegation to upstreams
+	Token string `json:"-"`
+}
+
+// Can reports whether the principal holds the role for the squad. An empty
+// squad is a fleet-wide request: viewers scoped to some squads may read the
+// fleet overviews, but anything above viewer needs an unscoped binding.
+func (p *Principal) Can(required Role, squad string) bool {
+	if p == nil {
+		return false
+	}
+	for _, binding := range p.Bindings {
+		if !binding.Role.Includes(required) {
+			continue
+		}
+		if squad == "" {
+			if required == Viewer || len(binding.Squads) == 0 {
+				return true
+			}
+			continue
+		}
+		if binding.coversSquad(squad) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authenticator identifies the caller of a request. It returns ErrNoCredentials
+// when the request carries no credentials at all.
+type Authenticator interface {
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+var ErrNoCredentials = fmt.Errorf("missing bearer token")
+
+// BearerToken reads the Authorization header, falling back to ?access_token=
+// for clients such as EventSource that cannot set headers
+func BearerToken(r *http.Request) string {
+	header := r.Header.Get("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+type contextKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the authenticated caller, or nil
+func FromContext(ctx context.Context) *Principal {
+	principal, _ := ctx.Value(contextKey{}).(*Principal)
+	return principal
+}
@@ -0,0 +1,223 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testKeys struct {
+	rsa  *rsa.PrivateKey
+	p256 *ecdsa.PrivateKey
+	p384 *ecdsa.PrivateKey
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p256, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testKeys{rsa: rsaKey, p256: p256, p384: p384}
+}
+
+func b64(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func ecJWK(kid string, key *ecdsa.PrivateKey) map[string]string {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return map[string]string{
+		"kid": kid, "kty": "EC", "use": "sig", "crv": key.Curve.Params().Name,
+		"x": b64(key.X.FillBytes(make([]byte, size))), "y": b64(key.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+// issuer serves the discovery document and key set of the test keys
+func issuer(t *testing.T, keys testKeys) *httptest.Server {
+	t.Helper()
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"jwks_uri": server.URL + "/keys"})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{
+			{"kid": "rsa", "kty": "RSA", "use": "sig", "n": b64(keys.rsa.N.Bytes()), "e": b64(big.NewInt(int64(keys.rsa.E)).Bytes())},
+			ecJWK("p256", keys.p256),
+			ecJWK("p384", keys.p384),
+			{"kid": "enc", "kty": "RSA", "use": "enc", "n": b64(keys.rsa.N.Bytes()), "e": "AQAB"},
+		}})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// sign builds a token signed with key using alg, whatever the key's curve
+func sign(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := b64(header) + "." + b64(payload)
+
+	var digest []byte
+	var hash crypto.Hash
+	if strings.HasSuffix(alg, "384") {
+		sum := sha512.Sum384([]byte(signed))
+		digest, hash = sum[:], crypto.SHA384
+	} else {
+		sum := sha256.Sum256([]byte(signed))
+		digest, hash = sum[:], crypto.SHA256
+	}
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if signature, err = rsa.SignPKCS1v15(rand.Reader, key, hash, digest); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+	}
+	return signed + "." + b64(signature)
+}
+
+func validClaims(issuerURL string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":    issuerURL,
+		"aud":    []string{"lighthouse", "other"},
+		"sub":    "00u1",
+		"name":   "Ana",
+		"exp":    time.Now().Add(time.Hour).Unix(),
+		"groups": []string{"psm-console"},
+	}
+}
+
+func TestVerifyAcceptsValidTokens(t *testing.T) {
+	keys := newTestKeys(t)
+	server := issuer(t, keys)
+	authenticator := NewOIDCAuthenticator(OIDCConfig{Issuer: server.URL + "/", Audience: "lighthouse"})
+
+	for _, c := range []struct {
+		alg, kid string
+		key      crypto.Signer
+	}{
+		{"RS256", "rsa", keys.rsa},
+		{"RS384", "rsa", keys.rsa},
+		{"ES256", "p256", keys.p256},
+		{"ES384", "p384", keys.p384},
+	} {
+		token := sign(t, c.alg, c.kid, c.key, validClaims(server.URL))
+		if _, err := authenticator.Verify(context.Background(), token); err != nil {
+			t.Errorf("%s token rejected: %v", c.alg, err)
+		}
+	}
+}
+
+func TestVerifyRejectsInvalidTokens(t *testing.T) {
+	keys := newTestKeys(t)
+	server := issuer(t, keys)
+	authenticator := NewOIDCAuthenticator(OIDCConfig{Issuer: server.URL, Audience: "lighthouse"})
+
+	with := func(change func(claims map[string]interface{})) map[string]interface{} {
+		claims := validClaims(server.URL)
+		change(claims)
+		return claims
+	}
+	tampered := sign(t, "RS256", "rsa", keys.rsa, validClaims(server.URL))
+	parts := strings.Split(tampered, ".")
+	forged, _ := json.Marshal(with(func(c map[string]interface{}) { c["groups"] = []string{"lighthouse-admins"} }))
+	tampered = parts[0] + "." + b64(forged) + "." + parts[2]
+
+	cases := map[string]string{
+		"other audience":  sign(t, "RS256", "rsa", keys.rsa, with(func(c map[string]interface{}) { c["aud"] = "grafana" })),
+		"no audience":     sign(t, "RS256", "rsa", keys.rsa, with(func(c map[string]interface{}) { delete(c, "aud") })),
+		"other issuer":    sign(t, "RS256", "rsa", keys.rsa, with(func(c map[string]interface{}) { c["iss"] = "https://evil.example" })),
+		"expired":         sign(t, "RS256", "rsa", keys.rsa, with(func(c map[string]interface{}) { c["exp"] = time.Now().Add(-time.Hour).Unix() })),
+		"no expiry":       sign(t, "RS256", "rsa", keys.rsa, with(func(c map[string]interface{}) { delete(c, "exp") })),
+		"not yet valid":   sign(t, "RS256", "rsa", keys.rsa, with(func(c map[string]interface{}) { c["nbf"] = time.Now().Add(time.Hour).Unix() })),
+		"tampered claims": tampered,
+		"unknown key":     sign(t, "RS256", "rotated", keys.rsa, validClaims(server.URL)),
+		"encryption key":  sign(t, "RS256", "enc", keys.rsa, validClaims(server.URL)),
+		"ES256 on P-384":  sign(t, "ES256", "p384", keys.p384, validClaims(server.URL)),
+		"ES384 on P-256":  sign(t, "ES384", "p256", keys.p256, validClaims(server.URL)),
+		"RS256 on EC key": sign(t, "RS256", "p256", keys.rsa, validClaims(server.URL)),
+		"alg none":        strings.Join(append(strings.Split(sign(t, "none", "rsa", keys.rsa, validClaims(server.URL)), ".")[:2], ""), "."),
+		"malformed":       "not-a-token",
+	}
+	for name, token := range cases {
+		if _, err := authenticator.Verify(context.Background(), token); err == nil {
+			t.Errorf("%s: token accepted", name)
+		}
+	}
+}
+
+func TestAuthenticateMapsGroupsToBindings(t *testing.T) {
+	keys := newTestKeys(t)
+	server := issuer(t, keys)
+	authenticator := NewOIDCAuthenticator(OIDCConfig{
+		Issuer:          server.URL,
+		Audience:        "lighthouse",
+		DefaultBindings: []Binding{{Role: Viewer}},
+		Bindings: []GroupBinding{
+			{Group: "lighthouse-admins", Binding: Binding{Role: Admin}},
+			{Group: "psm-console", Binding: Binding{Role: SquadOperator, Squads: []string{"psm-console"}}},
+		},
+	})
+
+	r := httptest.NewRequest("GET", "/whoami", nil)
+	r.Header.Set("Authorization", "Bearer "+sign(t, "ES256", "p256", keys.p256, validClaims(server.URL)))
+	principal, err := authenticator.Authenticate(r)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if principal.Subject != "00u1" || principal.Name != "Ana" {
+		t.Errorf("principal = %+v", principal)
+	}
+	if !principal.Can(SquadOperator, "psm-console") || principal.Can(SquadOperator, "psm-accounts") || principal.Can(Admin, "") {
+		t.Errorf("bindings = %+v", principal.Bindings)
+	}
+}
+
+func TestOIDCConfigNeedsAnAudience(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "auth.json")
+	if err := os.WriteFile(path, []byte(`{"mode":"oidc","oidc":{"issuer":"https://pismo.okta.com"}}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadConfig(path); err == nil {
+		t.Error("LoadConfig accepted an oidc config without an audience")
+	}
+	if _, err := NewAuthenticator(&Config{Mode: "oidc", OIDC: &OIDCConfig{Issuer: "https://pismo.okta.com"}}); err == nil {
+		t.Error("NewAuthenticator accepted an oidc config without an audience")
+	}
+}
@@ -0,0 +1,35 @@
+// pkg/auth/static.go
+package auth
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+)
+
+type StaticAuthenticator struct {
+	tokens []StaticToken
+}
+
+func NewStaticAuthenticator(tokens []StaticToken) *StaticAuthenticator {
+	return &StaticAuthenticator{tokens: tokens}
+}
+
+func (a *StaticAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token := BearerToken(r)
+	if token == "" {
+		return nil, ErrNoCredentials
+	}
+
+	for _, static := range a.tokens {
+		if subtle.ConstantTimeCompare([]byte(static.Token), []byte(token)) == 1 {
+			return &Principal{
+				Subject:  static.Subject,
+				Bindings: static.Bindings,
+				Method:   "static",
+				Token:    token,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid token")
+}
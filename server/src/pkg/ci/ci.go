@@ -0,0 +1,72 @@
+// pkg/ci/ci.go
+package ci
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+type Build struct {
+	ID            string    `json:"id"`
+	Pipeline      string    `json:"pipeline"`
+	Status        string    `json:"status"`
+	Branch        string    `json:"branch"`
+	Commit        string    `json:"commit"`
+	CommitMessage string    `json:"commitMessage,omitempty"`
+	Author        string    `json:"author,omitempty"`
+	Started       time.Time `json:"started"`
+	Finished      time.Time `json:"finished,omitempty"`
+	Duration      float64   `json:"durationSeconds"`
+	ImageTags     []string  `json:"imageTags,omitempty"`
+	URL           string    `json:"url"`
+}
+
+// Provider lists the most recent builds of a repo, newest first. Codefresh is
+// the only implementation today; GitHub Actions can slot in behind the same interface.
+type Provider interface {
+	Name() string
+	RecentBuilds(ctx context.Context, repo string, limit int) ([]Build, error)
+}
+
+// FindBuildForTag returns the newest build that produced the image tag. Builds
+// without image information are matched on a branch or git tag named after
+// the image tag, or a commit SHA the tag starts with.
+func FindBuildForTag(builds []Build, imageTag string) *Build {
+	if imageTag == "" {
+		return nil
+	}
+
+	for i := range builds {
+		for _, tag := range builds[i].ImageTags {
+			if tag == imageTag {
+				return &builds[i]
+			}
+		}
+	}
+
+	for i := range builds {
+		build := &builds[i]
+		if build.Branch == imageTag || strings.TrimPrefix(build.Branch, "v") == imageTag {
+			return build
+		}
+		if len(imageTag) >= 7 && strings.HasPrefix(build.Commit, imageTag) {
+			return build
+		}
+	}
+
+	return nil
+}
+
+// ImageTag returns the tag part of an image reference, "" if it has none
+func ImageTag(image string) string {
+	if at := strings.Index(image, "@"); at >= 0 {
+		image = image[:at]
+	}
+	slash := strings.LastIndex(image, "/")
+	colon := strings.LastIndex(image, ":")
+	if colon <= slash {
+		return ""
+	}
+	return image[colon+1:]
+}
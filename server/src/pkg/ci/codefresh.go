@@ -0,0 +1,182 @@
+// pkg/ci/codefresh.go
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CodefreshClient reads builds (workflows) and the images they pushed from
+// the Codefresh REST API
+type CodefreshClient struct {
+	BaseURL    string
+	Token      string
+	RepoOwner  string
+	HTTPClient *http.Client
+}
+
+func NewCodefreshClient(baseURL, token, repoOwner string) *CodefreshClient {
+	return &CodefreshClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		RepoOwner:  repoOwner,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+func (c *CodefreshClient) Name() string {
+	return "codefresh"
+}
+
+type codefreshWorkflow struct {
+	ID            string    `json:"id"`
+	Status        string    `json:"status"`
+	PipelineName  string    `json:"pipelineName"`
+	BranchName    string    `json:"branchName"`
+	Revision      string    `json:"revision"`
+	CommitMessage string    `json:"commitMessage"`
+	CommitAuthor  string    `json:"commitAuthor"`
+	Created       time.Time `json:"created"`
+	Started       time.Time `json:"started"`
+	Finished      time.Time `json:"finished"`
+}
+
+type codefreshImage struct {
+	WorkflowID string `json:"workflowId"`
+	Tags       []struct {
+		Tag string `json:"tag"`
+	} `json:"tags"`
+}
+
+func (c *CodefreshClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	// Codefresh API keys are sent without a scheme
+	req.Header.Set("Authorization", c.Token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error: received status code %d", resp.StatusCode)
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error parsing codefresh response: %v", err)
+	}
+	return nil
+}
+
+func (c *CodefreshClient) RecentBuilds(ctx context.Context, repo string, limit int) ([]Build, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	query.Set("page", "1")
+	query.Set("repoName", repo)
+	if c.RepoOwner != "" {
+		query.Set("repoOwner", c.RepoOwner)
+	}
+
+	var workflows struct {
+		Docs []codefreshWorkflow `json:"docs"`
+	}
+	if err := c.get(ctx, "/api/workflow", query, &workflows); err != nil {
+		return nil, err
+	}
+
+	tagsByWorkflow, err := c.imageTags(ctx, repo, limit)
+	if err != nil {
+		// Builds are still useful without the tags they produced
+		slog.WarnContext(ctx, "Error fetching image tags of builds", "provider", c.Name(), "repo", repo, "error", err)
+		tagsByWorkflow = nil
+	}
+
+	builds := make([]Build, 0, len(workflows.Docs))
+	for _, workflow := range workflows.Docs {
+		started := workflow.Started
+		if started.IsZero() {
+			started = workflow.Created
+		}
+		build := Build{
+			ID:            workflow.ID,
+			Pipeline:      workflow.PipelineName,
+			Status:        normaliseStatus(workflow.Status),
+			Branch:        workflow.BranchName,
+			Commit:        workflow.Revision,
+			CommitMessage: workflow.CommitMessage,
+			Author:        workflow.CommitAuthor,
+			Started:       started,
+			Finished:      workflow.Finished,
+			ImageTags:     tagsByWorkflow[workflow.ID],
+			URL:           c.BaseURL + "/build/" + workflow.ID,
+		}
+		if !workflow.Finished.IsZero() && !started.IsZero() {
+			build.Duration = workflow.Finished.Sub(started).Seconds()
+		}
+		builds = append(builds, build)
+	}
+
+	sort.SliceStable(builds, func(i, j int) bool { return builds[i].Started.After(builds[j].Started) })
+	return builds, nil
+}
+
+// imageTags maps each workflow to the image tags it pushed for the repo
+func (c *CodefreshClient) imageTags(ctx context.Context, repo string, limit int) (map[string][]string, error) {
+	query := url.Values{}
+	query.Set("limit", strconv.Itoa(limit))
+	// Only the repo's own image, not those of repos it is a prefix of, such
+	// as crm-core-bff for crm-core; the name may carry an owner or registry
+	query.Set("imageDisplayNameRegex", "(^|/)"+regexp.QuoteMeta(repo)+"$")
+
+	var images struct {
+		Docs []codefreshImage `json:"docs"`
+	}
+	if err := c.get(ctx, "/api/images", query, &images); err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string][]string)
+	for _, image := range images.Docs {
+		for _, tag := range image.Tags {
+			tags[image.WorkflowID] = append(tags[image.WorkflowID], tag.Tag)
+		}
+	}
+	return tags, nil
+}
+
+// normaliseStatus maps Codefresh workflow states onto success, failed,
+// running, pending and terminated
+func normaliseStatus(status string) string {
+	switch status {
+	case "success":
+		return "success"
+	case "error", "failure":
+		return "failed"
+	case "running", "elected":
+		return "running"
+	case "pending", "delayed", "pending-approval":
+		return "pending"
+	case "terminated", "terminating":
+		return "terminated"
+	}
+	return status
+}
@@ -0,0 +1,161 @@
+package ci
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"regexp"
+	"testing"
+	"time"
+)
+
+// codefreshStandIn serves the workflows and images of crm-core, and the
+// images of crm-core-bff its name is a prefix of
+func codefreshStandIn(t *testing.T, failImages bool) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/workflow", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "cf-key" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		query := r.URL.Query()
+		if query.Get("repoName") != "crm-core" || query.Get("repoOwner") != "pismo" || query.Get("limit") != "10" {
+			http.Error(w, "unexpected query "+r.URL.RawQuery, http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"docs":[
+			{"id":"wf-1","status":"success","pipelineName":"crm-core/build","branchName":"main","revision":"1111111aaaa",
+			 "created":"2026-10-19T09:00:00Z","started":"2026-10-19T09:01:00Z","finished":"2026-10-19T09:06:00Z"},
+			{"id":"wf-3","status":"elected","pipelineName":"crm-core/build","branchName":"v1.25.0",
+			 "created":"2026-10-19T12:00:00Z"},
+			{"id":"wf-2","status":"error","pipelineName":"crm-core/build","branchName":"main",
+			 "created":"2026-10-19T10:00:00Z","started":"2026-10-19T10:00:30Z","finished":"2026-10-19T10:02:00Z"}
+		]}`))
+	})
+	mux.HandleFunc("/api/images", func(w http.ResponseWriter, r *http.Request) {
+		if failImages {
+			http.Error(w, "images unavailable", http.StatusInternalServerError)
+			return
+		}
+		pattern, err := regexp.Compile(r.URL.Query().Get("imageDisplayNameRegex"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		images := []struct {
+			DisplayName string `json:"imageDisplayName"`
+			WorkflowID  string `json:"workflowId"`
+			Tags        []struct {
+				Tag string `json:"tag"`
+			} `json:"tags"`
+		}{
+			{DisplayName: "pismo/crm-core", WorkflowID: "wf-1", Tags: []struct {
+				Tag string `json:"tag"`
+			}{{"1.24.0"}, {"1111111"}}},
+			{DisplayName: "pismo/crm-core-bff", WorkflowID: "wf-1", Tags: []struct {
+				Tag string `json:"tag"`
+			}{{"9.9.9"}}},
+		}
+		var docs []interface{}
+		for _, image := range images {
+			if pattern.MatchString(image.DisplayName) {
+				docs = append(docs, image)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"docs": docs})
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCodefreshRecentBuilds(t *testing.T) {
+	server := codefreshStandIn(t, false)
+	client := NewCodefreshClient(server.URL+"/", "cf-key", "pismo")
+
+	builds, err := client.RecentBuilds(context.Background(), "crm-core", 10)
+	if err != nil {
+		t.Fatalf("RecentBuilds: %v", err)
+	}
+
+	var ids, statuses []string
+	for _, build := range builds {
+		ids = append(ids, build.ID)
+		statuses = append(statuses, build.Status)
+	}
+	if !reflect.DeepEqual(ids, []string{"wf-3", "wf-2", "wf-1"}) || !reflect.DeepEqual(statuses, []string{"running", "failed", "success"}) {
+		t.Errorf("builds = %v with statuses %v, want newest first", ids, statuses)
+	}
+
+	first := builds[2]
+	// crm-core-bff's 9.9.9 is not crm-core's
+	if !reflect.DeepEqual(first.ImageTags, []string{"1.24.0", "1111111"}) {
+		t.Errorf("image tags = %v", first.ImageTags)
+	}
+	if first.Duration != 300 || first.URL != server.URL+"/build/wf-1" {
+		t.Errorf("duration = %v, url = %s", first.Duration, first.URL)
+	}
+	// A build not started yet is dated by its creation
+	if !builds[0].Started.Equal(time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)) || builds[0].Duration != 0 {
+		t.Errorf("pending build = %+v", builds[0])
+	}
+}
+
+func TestCodefreshRecentBuildsWithoutImages(t *testing.T) {
+	server := codefreshStandIn(t, true)
+
+	builds, err := NewCodefreshClient(server.URL, "cf-key", "pismo").RecentBuilds(context.Background(), "crm-core", 10)
+	if err != nil || len(builds) != 3 {
+		t.Fatalf("builds = %+v, err = %v", builds, err)
+	}
+	for _, build := range builds {
+		if build.ImageTags != nil {
+			t.Errorf("build %s has tags %v", build.ID, build.ImageTags)
+		}
+	}
+
+	if _, err := NewCodefreshClient(server.URL, "wrong-key", "pismo").RecentBuilds(context.Background(), "crm-core", 10); err == nil {
+		t.Error("builds read with a wrong API key")
+	}
+}
+
+func TestFindBuildForTag(t *testing.T) {
+	builds := []Build{
+		{ID: "tagged", Branch: "main", Commit: "2222222bbbb", ImageTags: []string{"1.24.0"}},
+		{ID: "release-branch", Branch: "v1.25.0", Commit: "3333333cccc"},
+		{ID: "commit", Branch: "main", Commit: "4444444dddd"},
+	}
+	for tag, want := range map[string]string{
+		"1.24.0":  "tagged",
+		"1.25.0":  "release-branch",
+		"4444444": "commit",
+		"444":     "",
+		"9.9.9":   "",
+		"":        "",
+	} {
+		got := ""
+		if build := FindBuildForTag(builds, tag); build != nil {
+			got = build.ID
+		}
+		if got != want {
+			t.Errorf("FindBuildForTag(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestImageTag(t *testing.T) {
+	for image, want := range map[string]string{
+		"crm-core:1.24.0":                        "1.24.0",
+		"registry.local:5000/crm-core:1.24.0":    "1.24.0",
+		"registry.local:5000/crm-core":           "",
+		"crm-core:1.24.0@sha256:abc":             "1.24.0",
+		"registry.local:5000/crm-core@sha256:ab": "",
+	} {
+		if got := ImageTag(image); got != want {
+			t.Errorf("ImageTag(%q) = %q, want %q", image, got, want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// pkg/cluster/argocd.go
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// ArgoCD reads workloads through an ArgoCD instance's application API
+type ArgoCD struct {
+	name    string
+	baseURL string
+	get     func(ctx context.Context, url string) ([]byte, error)
+}
+
+// NewArgoCD returns the provider of the ArgoCD instance name at baseURL. get
+// fetches a URL of the instance with the caller's token.
+func NewArgoCD(name, baseURL string, get func(ctx context.Context, url string) ([]byte, error)) *ArgoCD {
+	return &ArgoCD{name: name, baseURL: strings.TrimRight(baseURL, "/"), get: get}
+}
+
+func (a *ArgoCD) Name() string {
+	return "argocd/" + a.name
+}
+
+var syncErrorPattern = regexp.MustCompile(`"phase":"Error","message":"([^"]+)"`)
+
+// Workload reads the app's resource tree and its Rollout. HPAs and events
+// take a call each on top, and leave a warning when they fail.
+func (a *ArgoCD) Workload(ctx context.Context, ref Ref) (*Workload, error) {
+	app := url.PathEscape(ref.App)
+	treeURL := fmt.Sprintf("%s/api/v1/applications/%s/resource-tree?appNamespace=argocd", a.baseURL, app)
+	tree, err := a.get(ctx, treeURL)
+	if err != nil {
+		return nil, err
+	}
+	rollout, err := a.get(ctx, a.resourceURL(ref, "argoproj.io", "v1alpha1", "Rollout", ref.Rollout))
+	if err != nil {
+		return nil, err
+	}
+
+	workload := &Workload{
+		Rollout: []byte(gjson.GetBytes(rollout, "manifest").String()),
+		Health:  gjson.GetBytes(rollout, "health.status").String(),
+		Raw:     map[string][]byte{"resource-tree": tree, "rollout": rollout},
+	}
+
+	if gjson.GetBytes(tree, "phase").String() == "Error" {
+		workload.Errors = append(workload.Errors, gjson.GetBytes(tree, "message").String())
+	}
+	if strings.Contains(string(rollout), "Error") {
+		if match := syncErrorPattern.FindStringSubmatch(string(rollout)); len(match) > 1 {
+			workload.Errors = append(workload.Errors, "Error found in application response: "+match[1])
+		} else {
+			workload.Errors = append(workload.Errors, "Error found in application response, but no specific message could be extracted.")
+		}
+	}
+	if gjson.GetBytes(rollout, "operationState.phase").String() == "Error" {
+		workload.Errors = append(workload.Errors, gjson.GetBytes(rollout, "operationState.message").String())
+	}
+
+	rolloutUID := ""
+	for _, node := range gjson.GetBytes(tree, "nodes").Array() {
+		switch node.Get("kind").String() {
+		case "Pod":
+			workload.Pods = append(workload.Pods, argocdPod(node))
+		case "Rollout":
+			if node.Get("name").String() == ref.Rollout {
+				rolloutUID = node.Get("uid").String()
+			}
+		case "HorizontalPodAutoscaler":
+			name := node.Get("name").String()
+			body, err := a.get(ctx, a.resourceURL(ref, "autoscaling", node.Get("version").String(), "HorizontalPodAutoscaler", name))
+			if err != nil {
+				workload.Warnings = append(workload.Warnings, fmt.Sprintf("error reading HPA %s: %v", name, err))
+				continue
+			}
+			if hpa, target, err := parseHPA([]byte(gjson.GetBytes(body, "manifest").String())); err != nil {
+				workload.Warnings = append(workload.Warnings, err.Error())
+			} else if target == ref.Rollout {
+				workload.HPAs = append(workload.HPAs, hpa)
+			}
+		}
+	}
+
+	// Without the rollout's UID ArgoCD returns the events of the application
+	query := url.Values{"appNamespace": {"argocd"}}
+	if rolloutUID != "" {
+		query.Set("resourceName", ref.Rollout)
+		query.Set("resourceNamespace", ref.Namespace)
+		query.Set("resourceUID", rolloutUID)
+	}
+	events, err := a.get(ctx, fmt.Sprintf("%s/api/v1/applications/%s/events?%s", a.baseURL, app, query.Encode()))
+	if err == nil {
+		workload.Events, err = parseEvents(events)
+	}
+	if err != nil {
+		workload.Warnings = append(workload.Warnings, fmt.Sprintf("error reading events: %v", err))
+	}
+
+	return workload, nil
+}
+
+func (a *ArgoCD) resourceURL(ref Ref, group, version, kind, name string) string {
+	if version == "" {
+		version = "v2"
+	}
+	query := url.Values{
+		"name":         {ref.Rollout},
+		"appNamespace": {"argocd"},
+		"namespace":    {ref.Namespace},
+		"resourceName": {name},
+		"group":        {group},
+		"version":      {version},
+		"kind":         {kind},
+	}
+	return fmt.Sprintf("%s/api/v1/applications/%s/resource?%s", a.baseURL, url.PathEscape(ref.App), query.Encode())
+}
+
+// argocdPod reads a Pod node of the resource tree, whose node name and
+// status ArgoCD lists under info
+func argocdPod(node gjson.Result) Pod {
+	pod := Pod{Name: node.Get("name").String(), Labels: map[string]string{}}
+	for key, value := range node.Get("networkingInfo.labels").Map() {
+		pod.Labels[key] = value.String()
+	}
+	for _, image := range node.Get("images").Array() {
+		pod.Images = append(pod.Images, image.String())
+	}
+	for _, info := range node.Get("info").Array() {
+		switch info.Get("name").String() {
+		case "Node":
+			pod.Node = info.Get("value").String()
+		case "Status Reason":
+			pod.Status = info.Get("value").String()
+		}
+	}
+	return pod
+}
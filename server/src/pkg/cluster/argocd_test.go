@@ -0,0 +1,140 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// argocdStandIn serves the application API of an ArgoCD instance for
+// bffRef, recording the event queries it gets
+type argocdStandIn struct {
+	*httptest.Server
+	mu          sync.Mutex
+	eventQuery  string
+	failHPA     bool
+	syncFailure bool
+}
+
+func newArgoCDStandIn(t *testing.T) *argocdStandIn {
+	t.Helper()
+	standIn := &argocdStandIn{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/applications/"+bffRef.App+"/resource-tree", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"nodes":[
+			{"kind":"Rollout","name":"backoffice-core-bff","uid":"rollout-uid"},
+			{"kind":"Pod","name":"bff-1","images":["backoffice-core-bff:1.24.0"],
+			 "networkingInfo":{"labels":{"app":"backoffice-core-bff"}},
+			 "info":[{"name":"Node","value":"node-a"},{"name":"Status Reason","value":"Running"}]},
+			{"kind":"HorizontalPodAutoscaler","name":"bff","version":"v2"}
+		]}`)
+	})
+	mux.HandleFunc("/api/v1/applications/"+bffRef.App+"/resource", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("namespace") != bffRef.Namespace || query.Get("appNamespace") != "argocd" {
+			http.Error(w, "bad query", http.StatusBadRequest)
+			return
+		}
+		standIn.mu.Lock()
+		failHPA, syncFailure := standIn.failHPA, standIn.syncFailure
+		standIn.mu.Unlock()
+		switch query.Get("kind") {
+		case "Rollout":
+			if syncFailure {
+				fmt.Fprint(w, `{"manifest":"{}","operationState":{"phase":"Error","message":"one or more objects failed to apply"}}`)
+				return
+			}
+			fmt.Fprint(w, `{"manifest":"{\"kind\":\"Rollout\"}","health":{"status":"Healthy"}}`)
+		case "HorizontalPodAutoscaler":
+			if failHPA {
+				http.Error(w, "permission denied", http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"manifest":"{\"metadata\":{\"name\":\"bff\"},\"spec\":{\"scaleTargetRef\":{\"name\":\"backoffice-core-bff\"},\"minReplicas\":2,\"maxReplicas\":6}}"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	mux.HandleFunc("/api/v1/applications/"+bffRef.App+"/events", func(w http.ResponseWriter, r *http.Request) {
+		standIn.mu.Lock()
+		standIn.eventQuery = r.URL.RawQuery
+		standIn.mu.Unlock()
+		fmt.Fprint(w, `{"items":[{"type":"Normal","reason":"RolloutCompleted","lastTimestamp":"2026-10-19T10:00:00Z"}]}`)
+	})
+	standIn.Server = httptest.NewServer(mux)
+	t.Cleanup(standIn.Close)
+	return standIn
+}
+
+// get fetches a URL as the server's getURLContent does, failing on non-2xx
+func get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("%s returned %d", url, resp.StatusCode)
+	}
+	return body, err
+}
+
+func TestArgoCDWorkload(t *testing.T) {
+	standIn := newArgoCDStandIn(t)
+	provider := NewArgoCD("default", standIn.URL+"/", get)
+
+	workload, err := provider.Workload(context.Background(), bffRef)
+	if err != nil {
+		t.Fatalf("Workload: %v", err)
+	}
+
+	if workload.Health != "Healthy" || string(workload.Rollout) != `{"kind":"Rollout"}` || len(workload.Errors) != 0 {
+		t.Errorf("health = %s, rollout = %s, errors = %v", workload.Health, workload.Rollout, workload.Errors)
+	}
+	if len(workload.Pods) != 1 || workload.Pods[0].Node != "node-a" || workload.Pods[0].Status != "Running" || workload.Pods[0].Labels["app"] != "backoffice-core-bff" {
+		t.Errorf("pods = %+v", workload.Pods)
+	}
+	if len(workload.HPAs) != 1 || workload.HPAs[0].MinReplicas != 2 || workload.HPAs[0].MaxReplicas != 6 {
+		t.Errorf("HPAs = %+v", workload.HPAs)
+	}
+	if len(workload.Events) != 1 || workload.Events[0].Reason != "RolloutCompleted" {
+		t.Errorf("events = %+v", workload.Events)
+	}
+	// The events are narrowed to the rollout found in the resource tree
+	if !strings.Contains(standIn.eventQuery, "resourceUID=rollout-uid") {
+		t.Errorf("events query = %s", standIn.eventQuery)
+	}
+}
+
+func TestArgoCDWorkloadReportsFailures(t *testing.T) {
+	standIn := newArgoCDStandIn(t)
+	standIn.failHPA = true
+	standIn.syncFailure = true
+
+	workload, err := NewArgoCD("default", standIn.URL, get).Workload(context.Background(), bffRef)
+	if err != nil {
+		t.Fatalf("Workload: %v", err)
+	}
+	if len(workload.Warnings) != 1 || !strings.Contains(workload.Warnings[0], "error reading HPA bff") {
+		t.Errorf("warnings = %v", workload.Warnings)
+	}
+	if len(workload.Errors) == 0 || workload.Errors[len(workload.Errors)-1] != "one or more objects failed to apply" {
+		t.Errorf("errors = %v", workload.Errors)
+	}
+
+	unknown := bffRef
+	unknown.App = "backoffice-core-bff-prod-sa-east-1"
+	if _, err := NewArgoCD("default", standIn.URL, get).Workload(context.Background(), unknown); err == nil {
+		t.Error("workload of an app ArgoCD does not know was read")
+	}
+}
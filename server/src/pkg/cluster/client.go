@@ -0,0 +1,234 @@
+// pkg/cluster/client.go
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RESTClientset calls the Kubernetes API of a kubeconfig context
+type RESTClientset struct {
+	server string
+	client *http.Client
+	user   KubeconfigUser
+
+	mu         sync.Mutex
+	execToken  string
+	execExpiry time.Time
+}
+
+// execTokenLifetime is how long a token from a credential plugin that does
+// not say when it expires is reused
+const execTokenLifetime = 5 * time.Minute
+
+// NewRESTClientset returns the clientset of a kubeconfig cluster and user.
+// wrap, if set, wraps its transport, e.g. to instrument it.
+func NewRESTClientset(cluster KubeconfigCluster, user KubeconfigUser, wrap func(http.RoundTripper) http.RoundTripper) (*RESTClientset, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         cluster.TLSServerName,
+		InsecureSkipVerify: cluster.InsecureSkipTLSVerify == "true",
+	}
+
+	ca, err := fileOrData(cluster.CertificateAuthority, cluster.CertificateAuthorityData)
+	if err != nil {
+		return nil, fmt.Errorf("error reading cluster CA: %v", err)
+	}
+	if ca != nil {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates in the cluster CA")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	cert, err := fileOrData(user.ClientCertificate, user.ClientCertificateData)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client certificate: %v", err)
+	}
+	key, err := fileOrData(user.ClientKey, user.ClientKeyData)
+	if err != nil {
+		return nil, fmt.Errorf("error reading client key: %v", err)
+	}
+	if cert != nil || key != nil {
+		pair, err := tls.X509KeyPair(cert, key)
+		if err != nil {
+			return nil, fmt.Errorf("error loading client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{pair}
+	}
+
+	var transport http.RoundTripper = &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		TLSClientConfig:     tlsConfig,
+		ForceAttemptHTTP2:   true,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+	}
+	if wrap != nil {
+		transport = wrap(transport)
+	}
+
+	return &RESTClientset{
+		server: strings.TrimRight(cluster.Server, "/"),
+		// Calls are bounded by the deadlines of their contexts
+		client: &http.Client{Transport: transport},
+		user:   user,
+	}, nil
+}
+
+// fileOrData returns the contents of file, or the base64 decoded data
+func fileOrData(file, data string) ([]byte, error) {
+	switch {
+	case data != "":
+		return base64.StdEncoding.DecodeString(data)
+	case file != "":
+		return os.ReadFile(file)
+	}
+	return nil, nil
+}
+
+func (c *RESTClientset) Rollout(ctx context.Context, namespace, name string) ([]byte, error) {
+	return c.get(ctx, path.Join("/apis/argoproj.io/v1alpha1/namespaces", namespace, "rollouts", name), nil)
+}
+
+func (c *RESTClientset) Pods(ctx context.Context, namespace string, labels map[string]string) ([]byte, error) {
+	selector := make([]string, 0, len(labels))
+	for key, value := range labels {
+		selector = append(selector, key+"="+value)
+	}
+	sort.Strings(selector)
+	return c.get(ctx, path.Join("/api/v1/namespaces", namespace, "pods"), url.Values{"labelSelector": {strings.Join(selector, ",")}})
+}
+
+func (c *RESTClientset) HorizontalPodAutoscalers(ctx context.Context, namespace string) ([]byte, error) {
+	return c.get(ctx, path.Join("/apis/autoscaling/v2/namespaces", namespace, "horizontalpodautoscalers"), nil)
+}
+
+func (c *RESTClientset) Events(ctx context.Context, namespace, kind, name string) ([]byte, error) {
+	selector := "involvedObject.kind=" + kind + ",involvedObject.name=" + name
+	return c.get(ctx, path.Join("/api/v1/namespaces", namespace, "events"), url.Values{"fieldSelector": {selector}})
+}
+
+func (c *RESTClientset) get(ctx context.Context, apiPath string, query url.Values) ([]byte, error) {
+	target := c.server + apiPath
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", target, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	token, err := c.token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, ErrNotFound
+	case resp.StatusCode == http.StatusUnauthorized:
+		// Let the next call run the credential plugin again
+		c.mu.Lock()
+		c.execToken = ""
+		c.mu.Unlock()
+	}
+	if resp.StatusCode != http.StatusOK {
+		var status struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(body, &status) == nil && status.Message != "" {
+			return nil, fmt.Errorf("%s returned status %d: %s", apiPath, resp.StatusCode, status.Message)
+		}
+		return nil, fmt.Errorf("%s returned status %d", apiPath, resp.StatusCode)
+	}
+	return body, nil
+}
+
+// token returns the bearer token of the user: a static one, the contents of
+// its token file, which is read on every call as it may be rotated, or the
+// token of its credential plugin
+func (c *RESTClientset) token(ctx context.Context) (string, error) {
+	switch {
+	case c.user.Token != "":
+		return c.user.Token, nil
+	case c.user.TokenFile != "":
+		data, err := os.ReadFile(c.user.TokenFile)
+		if err != nil {
+			return "", fmt.Errorf("error reading token file: %v", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case c.user.Exec == nil:
+		return "", nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.execToken != "" && time.Now().Before(c.execExpiry) {
+		return c.execToken, nil
+	}
+
+	plugin := c.user.Exec
+	cmd := exec.CommandContext(ctx, plugin.Command, plugin.Args...)
+	cmd.Env = os.Environ()
+	for _, env := range plugin.Env {
+		cmd.Env = append(cmd.Env, env.Name+"="+env.Value)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("error running credential plugin %s: %v: %s", plugin.Command, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var credential struct {
+		Status struct {
+			Token               string    `json:"token"`
+			ExpirationTimestamp time.Time `json:"expirationTimestamp"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(output, &credential); err != nil {
+		return "", fmt.Errorf("error parsing the output of credential plugin %s: %v", plugin.Command, err)
+	}
+	if credential.Status.Token == "" {
+		return "", fmt.Errorf("credential plugin %s returned no token", plugin.Command)
+	}
+
+	c.execToken = credential.Status.Token
+	c.execExpiry = time.Now().Add(execTokenLifetime)
+	if !credential.Status.ExpirationTimestamp.IsZero() {
+		// Renew a little early so a token does not expire mid-call
+		c.execExpiry = credential.Status.ExpirationTimestamp.Add(-30 * time.Second)
+	}
+	return c.execToken, nil
+}
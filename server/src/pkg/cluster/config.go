@@ -0,0 +1,106 @@
+// pkg/cluster/config.go
+package cluster
+
+import (
+	"argocd/pkg/regions"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+)
+
+// Route reads the apps of matching locations from a kubeconfig context
+// instead of ArgoCD. Account and Region are path.Match patterns such as
+// "onprem*"; empty matches anything.
+type Route struct {
+	Account string `json:"account,omitempty"`
+	Region  string `json:"region,omitempty"`
+	Context string `json:"context"`
+}
+
+type Config struct {
+	// Kubeconfig is the file the contexts are read from
+	Kubeconfig string `json:"kubeconfig"`
+	// Routes are tried in order; the first match wins. Locations no route
+	// matches are read from ArgoCD.
+	Routes []Route `json:"routes"`
+}
+
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Kubernetes config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing Kubernetes config: %v", err)
+	}
+	if config.Kubeconfig == "" {
+		return nil, fmt.Errorf("Kubernetes config needs a kubeconfig")
+	}
+	for _, route := range config.Routes {
+		if route.Context == "" {
+			return nil, fmt.Errorf("route %s/%s needs a context", route.Account, route.Region)
+		}
+		for _, pattern := range []string{route.Account, route.Region} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("invalid route pattern %q: %v", pattern, err)
+			}
+		}
+	}
+	return &config, nil
+}
+
+// Clusters holds a Kubernetes provider per routed context
+type Clusters struct {
+	providers map[string]*Kubernetes
+	routes    []Route
+}
+
+// NewClusters builds a clientset for every context the routes name
+func NewClusters(config Config, wrap func(http.RoundTripper) http.RoundTripper) (*Clusters, error) {
+	kubeconfig, err := LoadKubeconfig(config.Kubeconfig)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := &Clusters{providers: make(map[string]*Kubernetes), routes: config.Routes}
+	for _, route := range config.Routes {
+		if clusters.providers[route.Context] != nil {
+			continue
+		}
+		cluster, user, err := kubeconfig.Context(route.Context)
+		if err != nil {
+			return nil, err
+		}
+		clientset, err := NewRESTClientset(cluster, user, wrap)
+		if err != nil {
+			return nil, fmt.Errorf("error setting up kubeconfig context %s: %v", route.Context, err)
+		}
+		clusters.providers[route.Context] = NewKubernetes(route.Context, clientset)
+	}
+	return clusters, nil
+}
+
+// For returns the provider of a location, or nil when it is read from ArgoCD
+func (c *Clusters) For(location regions.Location) *Kubernetes {
+	if c == nil {
+		return nil
+	}
+	for _, route := range c.routes {
+		if matches(route.Account, location.Account) && matches(route.Region, location.Region) {
+			return c.providers[route.Context]
+		}
+	}
+	return nil
+}
+
+func matches(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, value)
+	return matched
+}
@@ -0,0 +1,132 @@
+// pkg/cluster/fake.go
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// FakeClientset serves Kubernetes objects from memory, so the Kubernetes
+// provider can be exercised without a cluster
+type FakeClientset struct {
+	mu      sync.Mutex
+	objects []fakeObject
+	// Errors makes the method of the same name fail, e.g. "Events"
+	Errors map[string]error
+}
+
+type fakeObject struct {
+	kind      string
+	namespace string
+	name      string
+	labels    map[string]string
+	// involved is the kind/name an Event is about
+	involved string
+	raw      json.RawMessage
+}
+
+// NewFakeClientset returns a clientset holding objects, each the JSON of a
+// Rollout, Pod, HorizontalPodAutoscaler or Event
+func NewFakeClientset(objects ...string) (*FakeClientset, error) {
+	fake := &FakeClientset{Errors: map[string]error{}}
+	for _, object := range objects {
+		if err := fake.Add(object); err != nil {
+			return nil, err
+		}
+	}
+	return fake, nil
+}
+
+// Add stores an object, replacing one of the same kind, namespace and name
+func (f *FakeClientset) Add(object string) error {
+	var meta struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		InvolvedObject struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"involvedObject"`
+	}
+	if err := json.Unmarshal([]byte(object), &meta); err != nil {
+		return fmt.Errorf("error parsing fake object: %v", err)
+	}
+	if meta.Kind == "" || meta.Metadata.Name == "" {
+		return fmt.Errorf("fake objects need a kind and a name")
+	}
+
+	stored := fakeObject{
+		kind:      meta.Kind,
+		namespace: meta.Metadata.Namespace,
+		name:      meta.Metadata.Name,
+		labels:    meta.Metadata.Labels,
+		involved:  meta.InvolvedObject.Kind + "/" + meta.InvolvedObject.Name,
+		raw:       json.RawMessage(object),
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, existing := range f.objects {
+		if existing.kind == stored.kind && existing.namespace == stored.namespace && existing.name == stored.name {
+			f.objects[i] = stored
+			return nil
+		}
+	}
+	f.objects = append(f.objects, stored)
+	return nil
+}
+
+func (f *FakeClientset) Rollout(ctx context.Context, namespace, name string) ([]byte, error) {
+	if err := f.Errors["Rollout"]; err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, object := range f.objects {
+		if object.kind == "Rollout" && object.namespace == namespace && object.name == name {
+			return object.raw, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (f *FakeClientset) Pods(ctx context.Context, namespace string, labels map[string]string) ([]byte, error) {
+	return f.list("Pods", "Pod", namespace, func(object fakeObject) bool {
+		for key, value := range labels {
+			if object.labels[key] != value {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (f *FakeClientset) HorizontalPodAutoscalers(ctx context.Context, namespace string) ([]byte, error) {
+	return f.list("HorizontalPodAutoscalers", "HorizontalPodAutoscaler", namespace, func(fakeObject) bool { return true })
+}
+
+func (f *FakeClientset) Events(ctx context.Context, namespace, kind, name string) ([]byte, error) {
+	return f.list("Events", "Event", namespace, func(object fakeObject) bool {
+		return object.involved == kind+"/"+name
+	})
+}
+
+func (f *FakeClientset) list(method, kind, namespace string, keep func(fakeObject) bool) ([]byte, error) {
+	if err := f.Errors[method]; err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	items := []json.RawMessage{}
+	for _, object := range f.objects {
+		if object.kind == kind && object.namespace == namespace && keep(object) {
+			items = append(items, object.raw)
+		}
+	}
+	return json.Marshal(map[string]interface{}{"kind": kind + "List", "items": items})
+}
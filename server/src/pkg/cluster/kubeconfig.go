@@ -0,0 +1,135 @@
+// pkg/cluster/kubeconfig.go
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Kubeconfig is the part of a kubeconfig file a context needs
+type Kubeconfig struct {
+	Clusters []struct {
+		Name    string            `json:"name"`
+		Cluster KubeconfigCluster `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		Name string         `json:"name"`
+		User KubeconfigUser `json:"user"`
+	} `json:"users"`
+	Contexts []struct {
+		Name    string `json:"name"`
+		Context struct {
+			Cluster string `json:"cluster"`
+			User    string `json:"user"`
+		} `json:"context"`
+	} `json:"contexts"`
+}
+
+type KubeconfigCluster struct {
+	Server                   string `json:"server"`
+	CertificateAuthority     string `json:"certificate-authority"`
+	CertificateAuthorityData string `json:"certificate-authority-data"`
+	TLSServerName            string `json:"tls-server-name"`
+	InsecureSkipTLSVerify    string `json:"insecure-skip-tls-verify"`
+}
+
+type KubeconfigUser struct {
+	Token                 string          `json:"token"`
+	TokenFile             string          `json:"tokenFile"`
+	ClientCertificate     string          `json:"client-certificate"`
+	ClientCertificateData string          `json:"client-certificate-data"`
+	ClientKey             string          `json:"client-key"`
+	ClientKeyData         string          `json:"client-key-data"`
+	Exec                  *KubeconfigExec `json:"exec"`
+}
+
+// KubeconfigExec is a credential plugin such as aws eks get-token
+type KubeconfigExec struct {
+	Command string   `json:"command"`
+	Args    []string `json:"args"`
+	Env     []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	} `json:"env"`
+}
+
+// LoadKubeconfig reads a kubeconfig in YAML or JSON
+func LoadKubeconfig(path string) (*Kubeconfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading kubeconfig: %v", err)
+	}
+
+	var document interface{}
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "{") {
+		err = json.Unmarshal(data, &document)
+	} else {
+		document, err = parseYAML(string(data))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing kubeconfig %s: %v", path, err)
+	}
+
+	// YAML scalars are all read as strings, so JSON ones are turned into
+	// strings too before decoding
+	normalized, err := json.Marshal(scalarsToStrings(document))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing kubeconfig %s: %v", path, err)
+	}
+	var config Kubeconfig
+	if err := json.Unmarshal(normalized, &config); err != nil {
+		return nil, fmt.Errorf("error parsing kubeconfig %s: %v", path, err)
+	}
+	return &config, nil
+}
+
+func scalarsToStrings(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = scalarsToStrings(item)
+		}
+		return v
+	case []interface{}:
+		for i, item := range v {
+			v[i] = scalarsToStrings(item)
+		}
+		return v
+	case nil:
+		return ""
+	case string:
+		return v
+	}
+	return fmt.Sprint(value)
+}
+
+// Context returns the cluster and user of a context
+func (k *Kubeconfig) Context(name string) (KubeconfigCluster, KubeconfigUser, error) {
+	for _, context := range k.Contexts {
+		if context.Name != name {
+			continue
+		}
+
+		var cluster *KubeconfigCluster
+		for i := range k.Clusters {
+			if k.Clusters[i].Name == context.Context.Cluster {
+				cluster = &k.Clusters[i].Cluster
+			}
+		}
+		if cluster == nil || cluster.Server == "" {
+			return KubeconfigCluster{}, KubeconfigUser{}, fmt.Errorf("kubeconfig context %s names unknown cluster %q", name, context.Context.Cluster)
+		}
+
+		// A context without a user calls the API anonymously
+		user := KubeconfigUser{}
+		for i := range k.Users {
+			if k.Users[i].Name == context.Context.User {
+				user = k.Users[i].User
+			}
+		}
+		return *cluster, user, nil
+	}
+	return KubeconfigCluster{}, KubeconfigUser{}, fmt.Errorf("kubeconfig has no context %q", name)
+}
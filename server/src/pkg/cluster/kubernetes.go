@@ -0,0 +1,105 @@
+// pkg/cluster/kubernetes.go
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/tidwall/gjson"
+)
+
+// ErrNotFound is returned by a Clientset for objects that do not exist
+var ErrNotFound = errors.New("not found")
+
+// Clientset is the part of the Kubernetes API a workload is read from. Each
+// method returns the object or list as the API serves it. NewFakeClientset
+// serves objects from memory for tests.
+type Clientset interface {
+	Rollout(ctx context.Context, namespace, name string) ([]byte, error)
+	// Pods lists the pods of namespace with all of labels
+	Pods(ctx context.Context, namespace string, labels map[string]string) ([]byte, error)
+	HorizontalPodAutoscalers(ctx context.Context, namespace string) ([]byte, error)
+	// Events lists the events about the object kind/name
+	Events(ctx context.Context, namespace, kind, name string) ([]byte, error)
+}
+
+// Kubernetes reads workloads from a cluster's API directly, for clusters
+// ArgoCD does not manage
+type Kubernetes struct {
+	name      string
+	clientset Clientset
+}
+
+// NewKubernetes returns the provider of the cluster name
+func NewKubernetes(name string, clientset Clientset) *Kubernetes {
+	return &Kubernetes{name: name, clientset: clientset}
+}
+
+func (k *Kubernetes) Name() string {
+	return "kubernetes/" + k.name
+}
+
+// Workload reads the app's Rollout, then the pods its selector matches, the
+// HPAs that scale it and its events
+func (k *Kubernetes) Workload(ctx context.Context, ref Ref) (*Workload, error) {
+	rollout, err := k.clientset.Rollout(ctx, ref.Namespace, ref.Rollout)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rollout %s/%s from %s: %w", ref.Namespace, ref.Rollout, k.name, err)
+	}
+
+	workload := &Workload{
+		Rollout: rollout,
+		Health:  gjson.GetBytes(rollout, "status.phase").String(),
+		Raw:     map[string][]byte{"rollout": rollout},
+	}
+	if message := gjson.GetBytes(rollout, "status.message").String(); workload.Health == "Degraded" && message != "" {
+		workload.Errors = append(workload.Errors, message)
+	}
+
+	selector := map[string]string{}
+	for key, value := range gjson.GetBytes(rollout, "spec.selector.matchLabels").Map() {
+		selector[key] = value.String()
+	}
+	if len(selector) == 0 {
+		return nil, fmt.Errorf("rollout %s/%s has no label selector", ref.Namespace, ref.Rollout)
+	}
+	pods, err := k.clientset.Pods(ctx, ref.Namespace, selector)
+	if err != nil {
+		return nil, fmt.Errorf("error listing pods of %s/%s from %s: %w", ref.Namespace, ref.Rollout, k.name, err)
+	}
+	workload.Raw["pods"] = pods
+	for _, item := range gjson.GetBytes(pods, "items").Array() {
+		pod, err := parsePod(json.RawMessage(item.Raw))
+		if err != nil {
+			return nil, err
+		}
+		workload.Pods = append(workload.Pods, pod)
+	}
+
+	if hpas, err := k.clientset.HorizontalPodAutoscalers(ctx, ref.Namespace); err != nil {
+		workload.Warnings = append(workload.Warnings, fmt.Sprintf("error listing HPAs: %v", err))
+	} else {
+		for _, item := range gjson.GetBytes(hpas, "items").Array() {
+			hpa, target, err := parseHPA([]byte(item.Raw))
+			if err != nil {
+				workload.Warnings = append(workload.Warnings, err.Error())
+				continue
+			}
+			if target == ref.Rollout && gjson.Get(item.Raw, "spec.scaleTargetRef.kind").String() == "Rollout" {
+				workload.HPAs = append(workload.HPAs, hpa)
+			}
+		}
+	}
+
+	events, err := k.clientset.Events(ctx, ref.Namespace, "Rollout", ref.Rollout)
+	if err == nil {
+		workload.Events, err = parseEvents(events)
+	}
+	if err != nil {
+		workload.Warnings = append(workload.Warnings, fmt.Sprintf("error reading events: %v", err))
+	}
+
+	return workload, nil
+}
@@ -0,0 +1,101 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var bffRef = Ref{App: "backoffice-core-bff-sandbox-us-east-1", Rollout: "backoffice-core-bff", Namespace: "backoffice"}
+
+func bffCluster(t *testing.T) *FakeClientset {
+	t.Helper()
+	fake, err := NewFakeClientset(
+		`{"kind":"Rollout","metadata":{"name":"backoffice-core-bff","namespace":"backoffice"},
+		  "spec":{"selector":{"matchLabels":{"app":"backoffice-core-bff"}}},
+		  "status":{"phase":"Degraded","message":"ProgressDeadlineExceeded"}}`,
+		`{"kind":"Pod","metadata":{"name":"bff-1","namespace":"backoffice","labels":{"app":"backoffice-core-bff","rollouts-pod-template-hash":"abc"}},
+		  "spec":{"nodeName":"node-a","initContainers":[{"image":"vault-agent:1.2"}],"containers":[{"image":"backoffice-core-bff:1.24.0"}]},
+		  "status":{"phase":"Running"}}`,
+		`{"kind":"Pod","metadata":{"name":"bff-2","namespace":"backoffice","labels":{"app":"backoffice-core-bff"}},
+		  "spec":{"containers":[{"image":"backoffice-core-bff:1.25.0"}]},
+		  "status":{"phase":"Running","containerStatuses":[{"state":{"waiting":{"reason":"CrashLoopBackOff"}}}]}}`,
+		`{"kind":"Pod","metadata":{"name":"other-1","namespace":"backoffice","labels":{"app":"backoffice-other"}}}`,
+		`{"kind":"Pod","metadata":{"name":"bff-elsewhere","namespace":"sandbox","labels":{"app":"backoffice-core-bff"}}}`,
+		`{"kind":"HorizontalPodAutoscaler","metadata":{"name":"bff","namespace":"backoffice"},
+		  "spec":{"scaleTargetRef":{"kind":"Rollout","name":"backoffice-core-bff"},"minReplicas":2,"maxReplicas":6},
+		  "status":{"currentReplicas":2,"desiredReplicas":3}}`,
+		`{"kind":"HorizontalPodAutoscaler","metadata":{"name":"other","namespace":"backoffice"},
+		  "spec":{"scaleTargetRef":{"kind":"Rollout","name":"backoffice-other"},"maxReplicas":4}}`,
+		`{"kind":"Event","metadata":{"name":"e1","namespace":"backoffice"},"involvedObject":{"kind":"Rollout","name":"backoffice-core-bff"},
+		  "type":"Warning","reason":"RolloutAborted","message":"aborted","lastTimestamp":"2026-10-19T10:00:00Z"}`,
+		`{"kind":"Event","metadata":{"name":"e2","namespace":"backoffice"},"involvedObject":{"kind":"Rollout","name":"backoffice-core-bff"},
+		  "type":"Normal","reason":"RolloutUpdated","eventTime":"2026-10-19T11:00:00Z"}`,
+		`{"kind":"Event","metadata":{"name":"e3","namespace":"backoffice"},"involvedObject":{"kind":"Pod","name":"bff-1"},"reason":"Pulled"}`,
+	)
+	if err != nil {
+		t.Fatalf("NewFakeClientset: %v", err)
+	}
+	return fake
+}
+
+func TestKubernetesWorkload(t *testing.T) {
+	provider := NewKubernetes("eks-sandbox-use1", bffCluster(t))
+
+	workload, err := provider.Workload(context.Background(), bffRef)
+	if err != nil {
+		t.Fatalf("Workload: %v", err)
+	}
+
+	if workload.Health != "Degraded" || len(workload.Errors) != 1 || workload.Errors[0] != "ProgressDeadlineExceeded" {
+		t.Errorf("health = %s, errors = %v", workload.Health, workload.Errors)
+	}
+	if len(workload.Pods) != 2 {
+		t.Fatalf("pods = %+v, want the 2 the selector matches in the namespace", workload.Pods)
+	}
+	first, second := workload.Pods[0], workload.Pods[1]
+	if first.Node != "node-a" || first.Status != "Running" || strings.Join(first.Images, ",") != "vault-agent:1.2,backoffice-core-bff:1.24.0" {
+		t.Errorf("first pod = %+v", first)
+	}
+	if second.Status != "CrashLoopBackOff" {
+		t.Errorf("crash looping pod status = %s", second.Status)
+	}
+	if len(workload.HPAs) != 1 || workload.HPAs[0] != (HPA{Name: "bff", MinReplicas: 2, MaxReplicas: 6, CurrentReplicas: 2, DesiredReplicas: 3}) {
+		t.Errorf("HPAs = %+v", workload.HPAs)
+	}
+	if len(workload.Events) != 2 || workload.Events[0].Reason != "RolloutUpdated" || workload.Events[1].Reason != "RolloutAborted" {
+		t.Errorf("events = %+v, want the rollout's, newest first", workload.Events)
+	}
+	if len(workload.Warnings) != 0 {
+		t.Errorf("warnings = %v", workload.Warnings)
+	}
+}
+
+func TestKubernetesWorkloadDegradesOnOptionalParts(t *testing.T) {
+	fake := bffCluster(t)
+	fake.Errors["Events"] = errors.New("forbidden")
+	fake.Errors["HorizontalPodAutoscalers"] = errors.New("forbidden")
+
+	workload, err := NewKubernetes("eks-sandbox-use1", fake).Workload(context.Background(), bffRef)
+	if err != nil {
+		t.Fatalf("Workload: %v", err)
+	}
+	if len(workload.Pods) != 2 || len(workload.Warnings) != 2 {
+		t.Errorf("pods = %d, warnings = %v", len(workload.Pods), workload.Warnings)
+	}
+}
+
+func TestKubernetesWorkloadFailsWithoutItsRolloutOrPods(t *testing.T) {
+	fake := bffCluster(t)
+	missing := bffRef
+	missing.Rollout = "backoffice-gone"
+	if _, err := NewKubernetes("eks-sandbox-use1", fake).Workload(context.Background(), missing); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing rollout: err = %v, want ErrNotFound", err)
+	}
+
+	fake.Errors["Pods"] = errors.New("connection refused")
+	if _, err := NewKubernetes("eks-sandbox-use1", fake).Workload(context.Background(), bffRef); err == nil {
+		t.Error("workload read without its pods")
+	}
+}
@@ -0,0 +1,133 @@
+// pkg/cluster/objects.go
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// parsePod reads a Kubernetes Pod
+func parsePod(object json.RawMessage) (Pod, error) {
+	var pod struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName       string `json:"nodeName"`
+			InitContainers []struct {
+				Image string `json:"image"`
+			} `json:"initContainers"`
+			Containers []struct {
+				Image string `json:"image"`
+			} `json:"containers"`
+		} `json:"spec"`
+		Status struct {
+			Phase             string `json:"phase"`
+			ContainerStatuses []struct {
+				State struct {
+					Waiting *struct {
+						Reason string `json:"reason"`
+					} `json:"waiting"`
+				} `json:"state"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(object, &pod); err != nil {
+		return Pod{}, fmt.Errorf("error parsing pod: %v", err)
+	}
+
+	result := Pod{
+		Name:   pod.Metadata.Name,
+		Node:   pod.Spec.NodeName,
+		Labels: pod.Metadata.Labels,
+		Status: pod.Status.Phase,
+	}
+	for _, container := range append(pod.Spec.InitContainers, pod.Spec.Containers...) {
+		result.Images = append(result.Images, container.Image)
+	}
+	// A pod stuck in CrashLoopBackOff or ImagePullBackOff is still Running or
+	// Pending; the waiting reason says more
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "" {
+			result.Status = status.State.Waiting.Reason
+			break
+		}
+	}
+	return result, nil
+}
+
+// parseHPA reads an autoscaling/v2 HorizontalPodAutoscaler, returning the
+// name of the workload it scales alongside it
+func parseHPA(object []byte) (HPA, string, error) {
+	var hpa struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			ScaleTargetRef struct {
+				Name string `json:"name"`
+			} `json:"scaleTargetRef"`
+			MinReplicas *int `json:"minReplicas"`
+			MaxReplicas int  `json:"maxReplicas"`
+		} `json:"spec"`
+		Status struct {
+			CurrentReplicas int `json:"currentReplicas"`
+			DesiredReplicas int `json:"desiredReplicas"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(object, &hpa); err != nil {
+		return HPA{}, "", fmt.Errorf("error parsing HPA: %v", err)
+	}
+
+	result := HPA{
+		Name:            hpa.Metadata.Name,
+		MinReplicas:     1,
+		MaxReplicas:     hpa.Spec.MaxReplicas,
+		CurrentReplicas: hpa.Status.CurrentReplicas,
+		DesiredReplicas: hpa.Status.DesiredReplicas,
+	}
+	if hpa.Spec.MinReplicas != nil {
+		result.MinReplicas = *hpa.Spec.MinReplicas
+	}
+	return result, hpa.Spec.ScaleTargetRef.Name, nil
+}
+
+// parseEvents reads a core/v1 EventList, as returned by Kubernetes and by
+// ArgoCD's application events API, keeping the most recent ones
+func parseEvents(body []byte) ([]Event, error) {
+	var list struct {
+		Items []struct {
+			Type           string    `json:"type"`
+			Reason         string    `json:"reason"`
+			Message        string    `json:"message"`
+			Count          int       `json:"count"`
+			FirstTimestamp time.Time `json:"firstTimestamp"`
+			LastTimestamp  time.Time `json:"lastTimestamp"`
+			EventTime      time.Time `json:"eventTime"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("error parsing events: %v", err)
+	}
+
+	events := []Event{}
+	for _, item := range list.Items {
+		event := Event{
+			Type:     item.Type,
+			Reason:   item.Reason,
+			Message:  item.Message,
+			Count:    item.Count,
+			LastSeen: item.LastTimestamp,
+		}
+		// Events from the events.k8s.io API only set eventTime
+		for _, seen := range []time.Time{item.EventTime, item.FirstTimestamp} {
+			if event.LastSeen.IsZero() {
+				event.LastSeen = seen
+			}
+		}
+		events = append(events, event)
+	}
+	return recentEvents(events), nil
+}
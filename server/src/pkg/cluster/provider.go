@@ -0,0 +1,120 @@
+// pkg/cluster/provider.go
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+)
+
+// Provider supplies the pods, rollout, HPAs and events of an app. ArgoCD
+// serves the clusters it manages; Kubernetes reads a cluster's API directly.
+type Provider interface {
+	// Name says where workloads are read from, e.g. argocd/default or
+	// kubernetes/eks-sandbox-use1
+	Name() string
+	Workload(ctx context.Context, ref Ref) (*Workload, error)
+}
+
+// Ref names the workload of an app
+type Ref struct {
+	// App is the ArgoCD application name, <repo>-<account>-<region>
+	App string
+	// Rollout is the name of the app's Argo Rollout, which is the repo name
+	Rollout   string
+	Namespace string
+}
+
+// Workload is what runs for an app
+type Workload struct {
+	Pods []Pod
+	// Rollout is the manifest of the app's Argo Rollout
+	Rollout json.RawMessage
+	HPAs    []HPA
+	Events  []Event
+	// Health is the app's health as the provider reports it: the ArgoCD health
+	// status, or the rollout phase when read from Kubernetes
+	Health string
+	// Errors are problems the provider reports with the app, such as a failed sync
+	Errors []string
+	// Warnings are the parts of the workload that could not be read, such as
+	// the events, which leave the rest of it usable
+	Warnings []string
+	// Raw are the responses the workload was read from, by name, for debugging
+	Raw map[string][]byte
+}
+
+// Pod is a running pod of the app
+type Pod struct {
+	Name   string            `json:"name"`
+	Node   string            `json:"node,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+	// Images are the images of the pod's init and regular containers
+	Images []string `json:"images,omitempty"`
+	// Status is the pod's phase, or why a container is not running
+	Status string `json:"status,omitempty"`
+}
+
+// HPA is a horizontal pod autoscaler scaling the app's rollout
+type HPA struct {
+	Name            string `json:"name"`
+	MinReplicas     int    `json:"minReplicas"`
+	MaxReplicas     int    `json:"maxReplicas"`
+	CurrentReplicas int    `json:"currentReplicas"`
+	DesiredReplicas int    `json:"desiredReplicas"`
+}
+
+// Event is a Kubernetes event about the app's rollout
+type Event struct {
+	Type     string    `json:"type" enum:"Normal,Warning"`
+	Reason   string    `json:"reason"`
+	Message  string    `json:"message"`
+	Count    int       `json:"count,omitempty"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+// ResourceTree renders pods in the shape of ArgoCD's resource tree, which is
+// what analyzer.AnalyzeDeployment and inventory.FromArgoCD read
+func ResourceTree(pods []Pod) string {
+	type info struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+	}
+	type node struct {
+		Kind           string `json:"kind"`
+		Name           string `json:"name"`
+		NetworkingInfo struct {
+			Labels map[string]string `json:"labels,omitempty"`
+		} `json:"networkingInfo"`
+		Info   []info   `json:"info,omitempty"`
+		Images []string `json:"images,omitempty"`
+	}
+
+	nodes := make([]node, 0, len(pods))
+	for _, pod := range pods {
+		n := node{Kind: "Pod", Name: pod.Name, Images: pod.Images}
+		n.NetworkingInfo.Labels = pod.Labels
+		if pod.Node != "" {
+			n.Info = append(n.Info, info{Name: "Node", Value: pod.Node})
+		}
+		if pod.Status != "" {
+			n.Info = append(n.Info, info{Name: "Status Reason", Value: pod.Status})
+		}
+		nodes = append(nodes, n)
+	}
+	tree, _ := json.Marshal(map[string]interface{}{"nodes": nodes})
+	return string(tree)
+}
+
+// maxEvents is how many of the most recent events a workload keeps
+const maxEvents = 10
+
+// recentEvents keeps the most recent events, newest first
+func recentEvents(events []Event) []Event {
+	sort.SliceStable(events, func(i, j int) bool { return events[i].LastSeen.After(events[j].LastSeen) })
+	if len(events) > maxEvents {
+		events = events[:maxEvents]
+	}
+	return events
+}
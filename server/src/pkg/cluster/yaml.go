@@ -0,0 +1,245 @@
+// pkg/cluster/yaml.go
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// yamlLine is a line of a YAML document without its indentation and comment
+type yamlLine struct {
+	number  int
+	indent  int
+	content string
+}
+
+// parseYAML reads the block-style YAML that kubectl and the cloud CLIs write
+// kubeconfigs in: nested mappings and sequences of plain or quoted scalars,
+// flow lists of scalars and literal blocks. Scalars are returned as strings.
+func parseYAML(data string) (interface{}, error) {
+	var lines []yamlLine
+	for number, raw := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		content := stripComment(raw)
+		trimmed := strings.TrimLeft(content, " ")
+		if strings.TrimSpace(trimmed) == "" || trimmed == "---" {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs are not allowed in YAML indentation", number+1)
+		}
+		lines = append(lines, yamlLine{number: number + 1, indent: len(content) - len(trimmed), content: strings.TrimRight(trimmed, " ")})
+	}
+	if len(lines) == 0 {
+		return map[string]interface{}{}, nil
+	}
+
+	p := &yamlParser{lines: lines}
+	value, err := p.node(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return value, nil
+}
+
+// stripComment drops a # comment that is not inside quotes
+func stripComment(line string) string {
+	quote := byte(0)
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func isSequenceItem(content string) bool {
+	return content == "-" || strings.HasPrefix(content, "- ")
+}
+
+func (p *yamlParser) node(indent int) (interface{}, error) {
+	if isSequenceItem(p.lines[p.pos].content) {
+		return p.sequence(indent)
+	}
+	return p.mapping(indent)
+}
+
+func (p *yamlParser) mapping(indent int) (interface{}, error) {
+	result := map[string]interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && !isSequenceItem(p.lines[p.pos].content) {
+		line := p.lines[p.pos]
+		key, value, ok := splitKey(line.content)
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected a key", line.number)
+		}
+		p.pos++
+
+		switch {
+		case value == "|" || value == "|-" || value == ">" || value == ">-":
+			result[key] = p.block(indent, value)
+		case value != "":
+			scalar, err := parseScalar(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line.number, err)
+			}
+			result[key] = scalar
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			child, err := p.node(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = child
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].content):
+			// Sequences may sit at the indentation of their key
+			child, err := p.sequence(indent)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = child
+		default:
+			result[key] = ""
+		}
+	}
+	return result, nil
+}
+
+func (p *yamlParser) sequence(indent int) (interface{}, error) {
+	result := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSequenceItem(p.lines[p.pos].content) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(strings.TrimPrefix(line.content, "-"), " ")
+		if rest == "" {
+			p.pos++
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				child, err := p.node(p.lines[p.pos].indent)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, child)
+			} else {
+				result = append(result, "")
+			}
+			continue
+		}
+
+		if _, _, ok := splitKey(rest); ok || isSequenceItem(rest) {
+			// The item is a mapping or sequence starting on the dash's line, so
+			// its first entry is read as if it were on a line of its own
+			p.lines[p.pos] = yamlLine{number: line.number, indent: indent + len(line.content) - len(rest), content: rest}
+			child, err := p.node(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, child)
+			continue
+		}
+
+		scalar, err := parseScalar(rest)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line.number, err)
+		}
+		result = append(result, scalar)
+		p.pos++
+	}
+	return result, nil
+}
+
+// block reads a literal (|) or folded (>) block scalar of the lines indented
+// deeper than its key
+func (p *yamlParser) block(indent int, style string) string {
+	var lines []string
+	for p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		lines = append(lines, p.lines[p.pos].content)
+		p.pos++
+	}
+	separator := "\n"
+	if strings.HasPrefix(style, ">") {
+		separator = " "
+	}
+	text := strings.Join(lines, separator)
+	if !strings.HasSuffix(style, "-") {
+		text += "\n"
+	}
+	return text
+}
+
+// splitKey splits "key: value" and "key:", with the key optionally quoted
+func splitKey(content string) (string, string, bool) {
+	keyEnd := -1
+	if content[0] == '"' || content[0] == '\'' {
+		if end := strings.IndexByte(content[1:], content[0]); end >= 0 {
+			keyEnd = end + 2
+		}
+	} else {
+		keyEnd = strings.Index(content, ": ")
+		if keyEnd < 0 && strings.HasSuffix(content, ":") {
+			keyEnd = len(content) - 1
+		}
+	}
+	if keyEnd < 0 || keyEnd >= len(content) || content[keyEnd] != ':' {
+		return "", "", false
+	}
+	if keyEnd+1 < len(content) && content[keyEnd+1] != ' ' {
+		return "", "", false
+	}
+
+	key, err := parseScalar(content[:keyEnd])
+	if err != nil {
+		return "", "", false
+	}
+	name, ok := key.(string)
+	if !ok {
+		return "", "", false
+	}
+	return name, strings.TrimSpace(content[keyEnd+1:]), true
+}
+
+func parseScalar(value string) (interface{}, error) {
+	switch {
+	case value == "~" || value == "null":
+		return "", nil
+	case value == "{}":
+		return map[string]interface{}{}, nil
+	case strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]"):
+		items := []interface{}{}
+		inner := strings.TrimSpace(value[1 : len(value)-1])
+		if inner == "" {
+			return items, nil
+		}
+		for _, item := range strings.Split(inner, ",") {
+			scalar, err := parseScalar(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, scalar)
+		}
+		return items, nil
+	case strings.HasPrefix(value, `"`):
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid double-quoted string %s", value)
+		}
+		return unquoted, nil
+	case strings.HasPrefix(value, "'"):
+		if len(value) < 2 || !strings.HasSuffix(value, "'") {
+			return nil, fmt.Errorf("invalid single-quoted string %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	}
+	return value, nil
+}
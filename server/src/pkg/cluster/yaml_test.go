@@ -0,0 +1,187 @@
+package cluster
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// eksKubeconfig is what aws eks update-kubeconfig writes: sequences at the
+// indentation of their key and an exec credential plugin
+const eksKubeconfig = `apiVersion: v1
+clusters:
+- cluster:
+    certificate-authority-data: LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0tCg==
+    server: https://0A1B2C3D4E.gr7.sa-east-1.eks.amazonaws.com
+  name: arn:aws:eks:sa-east-1:123456789012:cluster/prod
+contexts:
+- context:
+    cluster: arn:aws:eks:sa-east-1:123456789012:cluster/prod
+    user: arn:aws:eks:sa-east-1:123456789012:cluster/prod
+  name: arn:aws:eks:sa-east-1:123456789012:cluster/prod
+current-context: arn:aws:eks:sa-east-1:123456789012:cluster/prod
+kind: Config
+preferences: {}
+users:
+- name: arn:aws:eks:sa-east-1:123456789012:cluster/prod
+  user:
+    exec:
+      apiVersion: client.authentication.k8s.io/v1beta1
+      args:
+      - --region
+      - sa-east-1
+      - eks
+      - get-token
+      - --cluster-name
+      - prod
+      - --output
+      - json
+      command: aws
+      env:
+      - name: AWS_PROFILE
+        value: prod
+      interactiveMode: IfAvailable
+      provideClusterInfo: false
+`
+
+// kubectlKubeconfig is hand-edited: indented sequences, quoted scalars,
+// comments, flow lists and a literal block
+const kubectlKubeconfig = `---
+# dev cluster, created by kind
+apiVersion: v1
+kind: Config
+clusters:
+  - name: "kind-dev"   # quoted name
+    cluster:
+      server: 'https://127.0.0.1:6443'
+      tls-server-name: "kind-control-plane"
+      insecure-skip-tls-verify: true
+users:
+  - name: kind-dev
+    user:
+      token: "abc#def \"quoted\""
+      client-key-data: |
+        LS0tLS1CRUdJTiBSU0Eg
+        UFJJVkFURSBLRVktLS0t
+  - name: 'it''s-gke'
+    user:
+      exec:
+        command: gke-gcloud-auth-plugin
+        args: [--use_application_default_credentials, "--verbosity=0"]
+        env: []
+contexts:
+  - name: dev
+    context:
+      cluster: kind-dev
+      user: kind-dev
+  - name: gke
+    context:
+      cluster: kind-dev
+      user: it's-gke
+`
+
+func writeKubeconfig(t *testing.T, data string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadKubeconfigFromEKS(t *testing.T) {
+	config, err := LoadKubeconfig(writeKubeconfig(t, eksKubeconfig))
+	if err != nil {
+		t.Fatalf("LoadKubeconfig: %v", err)
+	}
+	cluster, user, err := config.Context("arn:aws:eks:sa-east-1:123456789012:cluster/prod")
+	if err != nil {
+		t.Fatalf("Context: %v", err)
+	}
+	if cluster.Server != "https://0A1B2C3D4E.gr7.sa-east-1.eks.amazonaws.com" || cluster.CertificateAuthorityData != "LS0tLS1CRUdJTiBDRVJUSUZJQ0FURS0tLS0tCg==" {
+		t.Errorf("cluster = %+v", cluster)
+	}
+	if user.Exec == nil {
+		t.Fatalf("user = %+v, want an exec plugin", user)
+	}
+	wantArgs := []string{"--region", "sa-east-1", "eks", "get-token", "--cluster-name", "prod", "--output", "json"}
+	if user.Exec.Command != "aws" || !reflect.DeepEqual(user.Exec.Args, wantArgs) {
+		t.Errorf("exec = %s %v", user.Exec.Command, user.Exec.Args)
+	}
+	if len(user.Exec.Env) != 1 || user.Exec.Env[0].Name != "AWS_PROFILE" || user.Exec.Env[0].Value != "prod" {
+		t.Errorf("exec env = %+v", user.Exec.Env)
+	}
+}
+
+func TestLoadKubeconfigFromKubectl(t *testing.T) {
+	config, err := LoadKubeconfig(writeKubeconfig(t, kubectlKubeconfig))
+	if err != nil {
+		t.Fatalf("LoadKubeconfig: %v", err)
+	}
+
+	cluster, user, err := config.Context("dev")
+	if err != nil {
+		t.Fatalf("Context(dev): %v", err)
+	}
+	want := KubeconfigCluster{Server: "https://127.0.0.1:6443", TLSServerName: "kind-control-plane", InsecureSkipTLSVerify: "true"}
+	if cluster != want {
+		t.Errorf("cluster = %+v, want %+v", cluster, want)
+	}
+	if user.Token != `abc#def "quoted"` || user.ClientKeyData != "LS0tLS1CRUdJTiBSU0Eg\nUFJJVkFURSBLRVktLS0t\n" {
+		t.Errorf("user = %+v", user)
+	}
+
+	_, user, err = config.Context("gke")
+	if err != nil {
+		t.Fatalf("Context(gke): %v", err)
+	}
+	if user.Exec == nil || user.Exec.Command != "gke-gcloud-auth-plugin" ||
+		!reflect.DeepEqual(user.Exec.Args, []string{"--use_application_default_credentials", "--verbosity=0"}) || len(user.Exec.Env) != 0 {
+		t.Errorf("exec = %+v", user.Exec)
+	}
+
+	if _, _, err := config.Context("prod"); err == nil {
+		t.Error("found a context that is not declared")
+	}
+}
+
+func TestParseYAMLBlocks(t *testing.T) {
+	document, err := parseYAML("literal: |\n  line one\n  line two\nstripped: |-\n  kept\nfolded: >\n  one\n  two\nempty:\nnone: ~\n")
+	if err != nil {
+		t.Fatalf("parseYAML: %v", err)
+	}
+	want := map[string]interface{}{
+		"literal":  "line one\nline two\n",
+		"stripped": "kept",
+		"folded":   "one two\n",
+		"empty":    "",
+		"none":     "",
+	}
+	if !reflect.DeepEqual(document, want) {
+		t.Errorf("document = %#v, want %#v", document, want)
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	for name, c := range map[string]struct {
+		data string
+		want string
+	}{
+		"tab indentation":       {"users:\n\t- name: dev\n", "line 2: tabs are not allowed"},
+		"tab in a nested entry": {"users:\n- name: dev\n  user:\n  \ttoken: abc\n", "line 4: tabs are not allowed"},
+		"not a key":             {"apiVersion: v1\njust text\n", "line 2: expected a key"},
+		"unexpected indent":     {"apiVersion: v1\n  kind: Config\n", "line 2: unexpected indentation"},
+		"unterminated quote":    {"server: \"https://127.0.0.1\n", "line 1: invalid double-quoted string"},
+	} {
+		_, err := parseYAML(c.data)
+		if err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("%s: err = %v, want %q", name, err, c.want)
+		}
+	}
+
+	if _, err := LoadKubeconfig(writeKubeconfig(t, "clusters:\n\t- name: dev\n")); err == nil || !strings.Contains(err.Error(), "error parsing kubeconfig") {
+		t.Errorf("LoadKubeconfig err = %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// pkg/coalesce/coalesce.go
+package coalesce
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs at most one call per key at a time; callers asking for a key
+// that is already running wait for that call's result instead of starting
+// their own. The shared call keeps running while anyone is still waiting for
+// it, and is cancelled once every caller has gone.
+type Group struct {
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done    chan struct{}
+	value   interface{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+func NewGroup() *Group {
+	return &Group{calls: make(map[string]*call)}
+}
+
+// Do runs fn for key, or joins the call already running for it. fn gets a
+// context carrying the first caller's values but not its cancellation.
+// shared reports whether the result came from a call started by someone else.
+func (g *Group) Do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (value interface{}, shared bool, err error) {
+	g.mu.Lock()
+	c, running := g.calls[key]
+	if running {
+		c.waiters++
+	} else {
+		runCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		c = &call{done: make(chan struct{}), waiters: 1, cancel: cancel}
+		g.calls[key] = c
+
+		go func() {
+			c.value, c.err = fn(runCtx)
+
+			g.mu.Lock()
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+			g.mu.Unlock()
+
+			cancel()
+			close(c.done)
+		}()
+	}
+	g.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.value, running, c.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			c.cancel()
+			// Later callers start afresh rather than joining a cancelled call
+			if g.calls[key] == c {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, running, ctx.Err()
+	}
+}
+
+// InFlight reports whether a call for key is running
+func (g *Group) InFlight(key string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	_, running := g.calls[key]
+	return running
+}
@@ -0,0 +1,155 @@
+package coalesce
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingCall runs until release is closed or its context is cancelled
+type blockingCall struct {
+	started   chan struct{}
+	release   chan struct{}
+	cancelled chan struct{}
+	runs      int32
+}
+
+func newBlockingCall() *blockingCall {
+	return &blockingCall{started: make(chan struct{}, 10), release: make(chan struct{}), cancelled: make(chan struct{}, 10)}
+}
+
+func (b *blockingCall) fn(ctx context.Context) (interface{}, error) {
+	atomic.AddInt32(&b.runs, 1)
+	b.started <- struct{}{}
+	select {
+	case <-b.release:
+		return "summary", nil
+	case <-ctx.Done():
+		b.cancelled <- struct{}{}
+		return nil, ctx.Err()
+	}
+}
+
+func wait(t *testing.T, ch chan struct{}, what string) {
+	t.Helper()
+	select {
+	case <-ch:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", what)
+	}
+}
+
+func TestDoSharesOneCallBetweenCallers(t *testing.T) {
+	group := NewGroup()
+	call := newBlockingCall()
+
+	type result struct {
+		value  interface{}
+		shared bool
+		err    error
+	}
+	results := make(chan result, 2)
+	do := func() {
+		value, shared, err := group.Do(context.Background(), "backoffice-core-bff", call.fn)
+		results <- result{value, shared, err}
+	}
+	go do()
+	wait(t, call.started, "the call to start")
+	go do()
+	for !joined(group, "backoffice-core-bff", 2) {
+		time.Sleep(time.Millisecond)
+	}
+	close(call.release)
+
+	sharedResults := 0
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err != nil || r.value != "summary" {
+			t.Errorf("result = %+v", r)
+		}
+		if r.shared {
+			sharedResults++
+		}
+	}
+	if runs := atomic.LoadInt32(&call.runs); sharedResults != 1 || runs != 1 {
+		t.Errorf("%d runs with %d shared results, want 1 run shared once", runs, sharedResults)
+	}
+	if group.InFlight("backoffice-core-bff") {
+		t.Error("call still in flight after finishing")
+	}
+}
+
+func TestCallSurvivesWhileAWaiterRemains(t *testing.T) {
+	group := NewGroup()
+	call := newBlockingCall()
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, _, err := group.Do(firstCtx, "backoffice-core-bff", call.fn)
+		firstErr <- err
+	}()
+	wait(t, call.started, "the call to start")
+
+	second := make(chan interface{}, 1)
+	go func() {
+		value, _, _ := group.Do(context.Background(), "backoffice-core-bff", call.fn)
+		second <- value
+	}()
+	for !joined(group, "backoffice-core-bff", 2) {
+		time.Sleep(time.Millisecond)
+	}
+
+	// The first caller leaving returns to it alone
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Errorf("first caller got %v, want context.Canceled", err)
+	}
+	select {
+	case <-call.cancelled:
+		t.Fatal("call cancelled while the second caller was waiting")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(call.release)
+	if value := <-second; value != "summary" {
+		t.Errorf("second caller got %v", value)
+	}
+}
+
+func TestCallIsCancelledOnceEveryCallerLeaves(t *testing.T) {
+	group := NewGroup()
+	call := newBlockingCall()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := group.Do(ctx, "backoffice-core-bff", call.fn)
+		done <- err
+	}()
+	wait(t, call.started, "the call to start")
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("caller got %v, want context.Canceled", err)
+	}
+	wait(t, call.cancelled, "the call to be cancelled")
+
+	// A later caller starts afresh instead of joining the cancelled call
+	fresh := newBlockingCall()
+	close(fresh.release)
+	value, shared, err := group.Do(context.Background(), "backoffice-core-bff", fresh.fn)
+	if err != nil || shared || value != "summary" {
+		t.Errorf("fresh call = %v, shared %v, err %v", value, shared, err)
+	}
+}
+
+// joined reports whether waiters callers are waiting on the call for key
+func joined(group *Group, key string, waiters int) bool {
+	group.mu.Lock()
+	defer group.mu.Unlock()
+	c, running := group.calls[key]
+	return running && c.waiters == waiters
+}
@@ -0,0 +1,173 @@
+// pkg/depgraph/depgraph.go
+package depgraph
+
+import (
+	"argocd/pkg/gitProcessor"
+	"sort"
+	"strings"
+)
+
+// Node kinds; the others are the gitProcessor.Outbound kinds
+const (
+	// NodeService is a repo of the fleet
+	NodeService = "service"
+)
+
+// Node is a service of the fleet, or a host, topic or queue outside of it
+type Node struct {
+	ID    string `json:"id"`
+	Label string `json:"label"`
+	Kind  string `json:"kind" enum:"service,http,graphql,sns,sqs"`
+	// Scanned is false for services only known as the target of an edge
+	Scanned bool `json:"scanned"`
+}
+
+// Edge is a dependency of a scanned service
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind" enum:"http,graphql,sns,sqs"`
+	// Targets are the hosts, URLs or ARNs found, one per account for topics
+	// and queues
+	Targets []string `json:"targets"`
+	EnvVars []string `json:"envVars,omitempty"`
+	// Sources are where the targets were found, as file:line in the repo
+	Sources []string `json:"sources"`
+}
+
+// Graph is the fleet's dependencies
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+	// Unscanned lists the repos without a checkout to scan
+	Unscanned []string `json:"unscanned,omitempty"`
+}
+
+// Build assembles the dependencies found in each scanned repo. Hosts whose
+// first label is, without an -ext or -int suffix, the name of a fleet repo
+// are resolved to that service, so that console-audit-api.pismolabs.io is
+// an edge to console-audit-api. Topics and queues are one node by name, as
+// each account has its own of the same name.
+func Build(scanned map[string][]gitProcessor.Outbound, fleet []string, unscanned []string) Graph {
+	repos := make(map[string]bool, len(fleet))
+	for _, repo := range fleet {
+		repos[repo] = true
+	}
+
+	nodes := make(map[string]*Node)
+	node := func(id, label, kind string) {
+		if nodes[id] == nil {
+			nodes[id] = &Node{ID: id, Label: label, Kind: kind}
+		}
+	}
+	edges := make(map[string]*Edge)
+
+	for repo, outbounds := range scanned {
+		from := NodeService + ":" + repo
+		node(from, repo, NodeService)
+		nodes[from].Scanned = true
+
+		for _, outbound := range outbounds {
+			to := outbound.Kind + ":" + outbound.Name
+			if outbound.Kind == gitProcessor.OutboundHTTP || outbound.Kind == gitProcessor.OutboundGraphQL {
+				if service := resolveHost(outbound.Name, repos); service != "" {
+					to = NodeService + ":" + service
+					node(to, service, NodeService)
+				}
+			}
+			if to == from {
+				continue
+			}
+			if nodes[to] == nil {
+				node(to, outbound.Name, outbound.Kind)
+			}
+
+			key := from + " " + to + " " + outbound.Kind
+			edge := edges[key]
+			if edge == nil {
+				edge = &Edge{From: from, To: to, Kind: outbound.Kind}
+				edges[key] = edge
+			}
+			edge.Targets = appendUnique(edge.Targets, outbound.Target)
+			edge.EnvVars = appendUnique(edge.EnvVars, outbound.EnvVars...)
+			edge.Sources = appendUnique(edge.Sources, outbound.Sources...)
+		}
+	}
+
+	graph := Graph{Nodes: []Node{}, Edges: []Edge{}, Unscanned: unscanned}
+	for _, node := range nodes {
+		graph.Nodes = append(graph.Nodes, *node)
+	}
+	for _, edge := range edges {
+		sort.Strings(edge.Targets)
+		sort.Strings(edge.EnvVars)
+		sort.Strings(edge.Sources)
+		graph.Edges = append(graph.Edges, *edge)
+	}
+	sort.Slice(graph.Nodes, func(i, j int) bool { return graph.Nodes[i].ID < graph.Nodes[j].ID })
+	sort.Slice(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Kind < b.Kind
+	})
+	sort.Strings(graph.Unscanned)
+	return graph
+}
+
+// resolveHost returns the fleet repo a host is the service of, if any
+func resolveHost(host string, repos map[string]bool) string {
+	label := strings.SplitN(host, ".", 2)[0]
+	for _, candidate := range []string{label, strings.TrimSuffix(label, "-ext"), strings.TrimSuffix(label, "-int")} {
+		if repos[candidate] {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// Around keeps the edges from and to a service, with their nodes
+func (g Graph) Around(repo string) Graph {
+	id := NodeService + ":" + repo
+	kept := map[string]bool{id: true}
+	around := Graph{Nodes: []Node{}, Edges: []Edge{}}
+	for _, edge := range g.Edges {
+		if edge.From == id || edge.To == id {
+			around.Edges = append(around.Edges, edge)
+			kept[edge.From], kept[edge.To] = true, true
+		}
+	}
+	// A topic or queue also links the other services using it
+	for _, edge := range g.Edges {
+		if kept[edge.To] && !kept[edge.From] && (edge.Kind == gitProcessor.OutboundSNS || edge.Kind == gitProcessor.OutboundSQS) {
+			around.Edges = append(around.Edges, edge)
+			kept[edge.From] = true
+		}
+	}
+	for _, node := range g.Nodes {
+		if kept[node.ID] {
+			around.Nodes = append(around.Nodes, node)
+		}
+	}
+	return around
+}
+
+func appendUnique(list []string, values ...string) []string {
+	for _, value := range values {
+		found := false
+		for _, item := range list {
+			if item == value {
+				found = true
+				break
+			}
+		}
+		if !found {
+			list = append(list, value)
+		}
+	}
+	return list
+}
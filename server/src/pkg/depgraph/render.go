@@ -0,0 +1,111 @@
+// pkg/depgraph/render.go
+package depgraph
+
+import (
+	"argocd/pkg/gitProcessor"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatDOT     Format = "dot"
+	FormatMermaid Format = "mermaid"
+)
+
+// ParseFormat maps a ?format= value onto a supported Format
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "json":
+		return FormatJSON, nil
+	case "dot", "graphviz":
+		return FormatDOT, nil
+	case "mermaid", "mmd":
+		return FormatMermaid, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, use json, dot or mermaid", value)
+}
+
+// ContentType returns the HTTP content type for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatDOT:
+		return "text/vnd.graphviz; charset=utf-8"
+	case FormatMermaid:
+		return "text/plain; charset=utf-8"
+	}
+	return "application/json"
+}
+
+// Write renders the graph in the given format
+func Write(w io.Writer, format Format, graph Graph) error {
+	switch format {
+	case FormatDOT:
+		_, err := io.WriteString(w, graph.DOT())
+		return err
+	case FormatMermaid:
+		_, err := io.WriteString(w, graph.Mermaid())
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(graph)
+}
+
+// dotShapes draws services as boxes and what lies outside the fleet by kind
+var dotShapes = map[string]string{
+	NodeService:                  "box",
+	gitProcessor.OutboundHTTP:    "ellipse",
+	gitProcessor.OutboundGraphQL: "ellipse",
+	gitProcessor.OutboundSNS:     "hexagon",
+	gitProcessor.OutboundSQS:     "cylinder",
+}
+
+// DOT renders the graph for Graphviz
+func (g Graph) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph dependencies {\n  rankdir=LR;\n")
+	for _, node := range g.Nodes {
+		style := ""
+		if node.Kind == NodeService && !node.Scanned {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&b, "  %q [label=%q, shape=%s%s];\n", node.ID, node.Label, dotShapes[node.Kind], style)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %q -> %q [label=%q];\n", edge.From, edge.To, edge.Kind)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// Mermaid renders the graph as a Mermaid flowchart. Mermaid ids cannot hold
+// the colons and dots of node ids, so nodes are numbered.
+func (g Graph) Mermaid() string {
+	var b strings.Builder
+	b.WriteString("flowchart LR\n")
+	ids := make(map[string]string, len(g.Nodes))
+	for i, node := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[node.ID] = id
+		label := strings.ReplaceAll(node.Label, `"`, "#quot;")
+		switch node.Kind {
+		case NodeService:
+			fmt.Fprintf(&b, "  %s[\"%s\"]\n", id, label)
+		case gitProcessor.OutboundSNS:
+			fmt.Fprintf(&b, "  %s{{\"%s\"}}\n", id, label)
+		case gitProcessor.OutboundSQS:
+			fmt.Fprintf(&b, "  %s[(\"%s\")]\n", id, label)
+		default:
+			fmt.Fprintf(&b, "  %s([\"%s\"])\n", id, label)
+		}
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(&b, "  %s -->|%s| %s\n", ids[edge.From], edge.Kind, ids[edge.To])
+	}
+	return b.String()
+}
@@ -0,0 +1,196 @@
+// pkg/deployments/deployments.go
+package deployments
+
+import (
+	"argocd/pkg/gitProcessor"
+	"argocd/pkg/regions"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Sync is one entry of an ArgoCD application's history
+type Sync struct {
+	ID          int64
+	Revision    string
+	DeployedAt  time.Time
+	StartedAt   *time.Time
+	InitiatedBy string
+	Automated   bool
+}
+
+// ParseHistory reads the history of an ArgoCD application, as returned by
+// /api/v1/applications/<name>, newest first. Entries written by older ArgoCD
+// versions carry no initiator; the newest one then takes it from the last
+// sync operation.
+func ParseHistory(body []byte) ([]Sync, error) {
+	type initiator struct {
+		Username  string `json:"username"`
+		Automated bool   `json:"automated"`
+	}
+	var app struct {
+		Status struct {
+			History []struct {
+				ID              int64      `json:"id"`
+				Revision        string     `json:"revision"`
+				Revisions       []string   `json:"revisions"`
+				DeployedAt      time.Time  `json:"deployedAt"`
+				DeployStartedAt *time.Time `json:"deployStartedAt"`
+				InitiatedBy     *initiator `json:"initiatedBy"`
+			} `json:"history"`
+			OperationState *struct {
+				Operation struct {
+					InitiatedBy initiator `json:"initiatedBy"`
+				} `json:"operation"`
+				FinishedAt *time.Time `json:"finishedAt"`
+			} `json:"operationState"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(body, &app); err != nil {
+		return nil, fmt.Errorf("error parsing ArgoCD application: %v", err)
+	}
+
+	syncs := make([]Sync, 0, len(app.Status.History))
+	for _, entry := range app.Status.History {
+		sync := Sync{
+			ID:         entry.ID,
+			Revision:   entry.Revision,
+			DeployedAt: entry.DeployedAt,
+			StartedAt:  entry.DeployStartedAt,
+		}
+		// Multi-source apps list a revision per source; the first is the repo's
+		if sync.Revision == "" && len(entry.Revisions) > 0 {
+			sync.Revision = entry.Revisions[0]
+		}
+		if entry.InitiatedBy != nil {
+			sync.InitiatedBy, sync.Automated = entry.InitiatedBy.Username, entry.InitiatedBy.Automated
+		}
+		syncs = append(syncs, sync)
+	}
+
+	sort.Slice(syncs, func(i, j int) bool { return syncs[i].DeployedAt.After(syncs[j].DeployedAt) })
+	if operation := app.Status.OperationState; len(syncs) > 0 && operation != nil && operation.FinishedAt != nil &&
+		syncs[0].InitiatedBy == "" && !syncs[0].Automated && operation.FinishedAt.Equal(syncs[0].DeployedAt) {
+		syncs[0].InitiatedBy = operation.Operation.InitiatedBy.Username
+		syncs[0].Automated = operation.Operation.InitiatedBy.Automated
+	}
+	return syncs, nil
+}
+
+// AppHistory is the history of one of the repo's apps
+type AppHistory struct {
+	Location regions.Location
+	App      string
+	Syncs    []Sync
+}
+
+// Deployment is a revision synced to an app
+type Deployment struct {
+	App     string `json:"app"`
+	Account string `json:"account"`
+	Region  string `json:"region"`
+	// HistoryID is the id of the entry in the app's ArgoCD history
+	HistoryID int64  `json:"historyId"`
+	Revision  string `json:"revision"`
+	// Version is the tag of the revision, or the revision itself when ArgoCD
+	// tracks a tag; empty when the revision is an untagged commit
+	Version     string               `json:"version,omitempty"`
+	Commit      *gitProcessor.Commit `json:"commit,omitempty"`
+	DeployedAt  time.Time            `json:"deployedAt"`
+	StartedAt   *time.Time           `json:"startedAt,omitempty"`
+	InitiatedBy string               `json:"initiatedBy,omitempty"`
+	Automated   bool                 `json:"automated"`
+	// Summary reads like "1.24.0 synced to prod-sa-east-1 at 2024-05-02 14:02 UTC by automation"
+	Summary string `json:"summary"`
+}
+
+// Environment is the deployments of every app of an env, newest first
+type Environment struct {
+	Env         string       `json:"env"`
+	Deployments []Deployment `json:"deployments"`
+}
+
+// Log is a repo's deployments, by env
+type Log struct {
+	Repo         string        `json:"repo"`
+	Environments []Environment `json:"environments"`
+	// Unreachable lists the apps whose history could not be read
+	Unreachable []string `json:"unreachable,omitempty"`
+}
+
+// Build joins the history of each app with the repo's commits and tags
+func Build(repo string, histories []AppHistory, commits []gitProcessor.Commit, tags []gitProcessor.Tag, unreachable []string) Log {
+	byHash := make(map[string]gitProcessor.Commit, len(commits))
+	for _, commit := range commits {
+		byHash[commit.Hash] = commit
+	}
+	// Tags are listed newest first, so a commit with several tags gets its newest
+	tagOf := make(map[string]string)
+	tagNames := make(map[string]bool)
+	for _, tag := range tags {
+		tagNames[tag.Name] = true
+		if _, ok := tagOf[tag.Commit]; !ok && tag.Commit != "" {
+			tagOf[tag.Commit] = tag.Name
+		}
+	}
+
+	byEnv := make(map[string][]Deployment)
+	for _, history := range histories {
+		for _, sync := range history.Syncs {
+			deployment := Deployment{
+				App:         history.App,
+				Account:     history.Location.Account,
+				Region:      history.Location.Region,
+				HistoryID:   sync.ID,
+				Revision:    sync.Revision,
+				Version:     tagOf[sync.Revision],
+				DeployedAt:  sync.DeployedAt,
+				StartedAt:   sync.StartedAt,
+				InitiatedBy: sync.InitiatedBy,
+				Automated:   sync.Automated,
+			}
+			if deployment.Version == "" && tagNames[sync.Revision] {
+				deployment.Version = sync.Revision
+			}
+			if commit, ok := byHash[sync.Revision]; ok {
+				deployment.Commit = &commit
+			}
+			deployment.Summary = summarize(deployment, history.Location)
+			byEnv[history.Location.Env] = append(byEnv[history.Location.Env], deployment)
+		}
+	}
+
+	log := Log{Repo: repo, Environments: []Environment{}, Unreachable: unreachable}
+	for env, deployments := range byEnv {
+		sort.SliceStable(deployments, func(i, j int) bool { return deployments[i].DeployedAt.After(deployments[j].DeployedAt) })
+		log.Environments = append(log.Environments, Environment{Env: env, Deployments: deployments})
+	}
+	sort.Slice(log.Environments, func(i, j int) bool { return log.Environments[i].Env < log.Environments[j].Env })
+	return log
+}
+
+func summarize(deployment Deployment, location regions.Location) string {
+	what := deployment.Version
+	if what == "" {
+		what = deployment.Revision
+		if len(what) > 7 {
+			what = what[:7]
+		}
+	}
+	if what == "" {
+		what = "unknown revision"
+	}
+
+	who := deployment.InitiatedBy
+	switch {
+	case deployment.Automated:
+		who = "automation"
+	case who == "":
+		who = "unknown"
+	}
+
+	return fmt.Sprintf("%s synced to %s at %s by %s", what, location.Key(),
+		deployment.DeployedAt.UTC().Format("2006-01-02 15:04 MST"), strings.TrimSpace(who))
+}
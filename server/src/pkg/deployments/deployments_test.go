@@ -0,0 +1,130 @@
+package deployments
+
+import (
+	"argocd/pkg/gitProcessor"
+	"argocd/pkg/regions"
+	"testing"
+	"time"
+)
+
+var (
+	tagged   = "1111111aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"
+	retagged = "2222222bbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"
+	untagged = "3333333ccccccccccccccccccccccccccccccccc"
+	unknown  = "4444444ddddddddddddddddddddddddddddddddd"
+)
+
+func at(hour int) time.Time {
+	return time.Date(2026, 10, 19, hour, 2, 0, 0, time.UTC)
+}
+
+func TestBuildJoinsSyncsWithTagsAndCommits(t *testing.T) {
+	commits := []gitProcessor.Commit{
+		{Hash: tagged, Author: "Ana", Message: "release 1.23.0"},
+		{Hash: retagged, Author: "Bruno", Message: "release 1.24.0"},
+		{Hash: untagged, Author: "Carla", Message: "fix retries"},
+	}
+	// Newest first, as Revisions lists them; retagged carries two tags
+	tags := []gitProcessor.Tag{
+		{Name: "1.24.1", Commit: retagged},
+		{Name: "1.24.0", Commit: retagged},
+		{Name: "1.23.0", Commit: tagged},
+	}
+	prod := regions.NewLocation("prod", "sa-east-1")
+	ext := regions.NewLocation("ext", "sa-east-1")
+	histories := []AppHistory{
+		{Location: prod, App: "backoffice-core-bff-prod-sa-east-1", Syncs: []Sync{
+			{ID: 3, Revision: retagged, DeployedAt: at(14), Automated: true},
+			{ID: 1, Revision: tagged, DeployedAt: at(9), InitiatedBy: "ana@pismo.io"},
+		}},
+		{Location: ext, App: "backoffice-core-bff-ext-sa-east-1", Syncs: []Sync{
+			{ID: 8, Revision: untagged, DeployedAt: at(12)},
+			// ArgoCD tracking a tag reports the tag as the revision
+			{ID: 7, Revision: "1.23.0", DeployedAt: at(8)},
+			{ID: 6, Revision: unknown, DeployedAt: at(7)},
+		}},
+	}
+
+	log := Build("backoffice-core-bff", histories, commits, tags, []string{"backoffice-core-bff-stag-sa-east-1"})
+
+	if log.Repo != "backoffice-core-bff" || len(log.Unreachable) != 1 {
+		t.Errorf("log = %+v", log)
+	}
+	if len(log.Environments) != 2 || log.Environments[0].Env != "ext" || log.Environments[1].Env != "prod" {
+		t.Fatalf("environments = %+v, want ext then prod", log.Environments)
+	}
+
+	extDeployments := log.Environments[0].Deployments
+	prodDeployments := log.Environments[1].Deployments
+	cases := []struct {
+		name       string
+		deployment Deployment
+		version    string
+		author     string
+		summary    string
+	}{
+		{"newest tag of a commit", prodDeployments[0], "1.24.1", "Bruno", "1.24.1 synced to prod-sa-east-1 at 2026-10-19 14:02 UTC by automation"},
+		{"tagged commit", prodDeployments[1], "1.23.0", "Ana", "1.23.0 synced to prod-sa-east-1 at 2026-10-19 09:02 UTC by ana@pismo.io"},
+		{"untagged commit", extDeployments[0], "", "Carla", "3333333 synced to ext-sa-east-1 at 2026-10-19 12:02 UTC by unknown"},
+		{"tag as revision", extDeployments[1], "1.23.0", "", "1.23.0 synced to ext-sa-east-1 at 2026-10-19 08:02 UTC by unknown"},
+		{"commit outside the history", extDeployments[2], "", "", "4444444 synced to ext-sa-east-1 at 2026-10-19 07:02 UTC by unknown"},
+	}
+	for _, c := range cases {
+		if c.deployment.Version != c.version {
+			t.Errorf("%s: version = %q, want %q", c.name, c.deployment.Version, c.version)
+		}
+		author := ""
+		if c.deployment.Commit != nil {
+			author = c.deployment.Commit.Author
+		}
+		if author != c.author {
+			t.Errorf("%s: commit author = %q, want %q", c.name, author, c.author)
+		}
+		if c.deployment.Summary != c.summary {
+			t.Errorf("%s: summary = %q, want %q", c.name, c.deployment.Summary, c.summary)
+		}
+	}
+	if prodDeployments[0].Account != "prod" || prodDeployments[0].Region != "sa-east-1" || prodDeployments[0].HistoryID != 3 {
+		t.Errorf("deployment = %+v", prodDeployments[0])
+	}
+}
+
+func TestBuildSortsMergedAppsNewestFirst(t *testing.T) {
+	primary := regions.NewLocation("prod", "sa-east-1")
+	failover := regions.NewLocation("prod", "us-east-1")
+	failover.Primary = false
+	histories := []AppHistory{
+		{Location: primary, App: "backoffice-core-bff-prod-sa-east-1", Syncs: []Sync{{ID: 1, Revision: tagged, DeployedAt: at(9)}}},
+		{Location: failover, App: "backoffice-core-bff-prod-us-east-1", Syncs: []Sync{{ID: 1, Revision: tagged, DeployedAt: at(10)}}},
+	}
+
+	log := Build("backoffice-core-bff", histories, nil, nil, nil)
+	if len(log.Environments) != 1 {
+		t.Fatalf("environments = %+v, want one prod", log.Environments)
+	}
+	deployments := log.Environments[0].Deployments
+	if len(deployments) != 2 || deployments[0].App != "backoffice-core-bff-prod-us-east-1" || deployments[1].App != "backoffice-core-bff-prod-sa-east-1" {
+		t.Errorf("deployments = %+v", deployments)
+	}
+}
+
+func TestParseHistoryTakesTheInitiatorFromTheLastOperation(t *testing.T) {
+	body := []byte(`{"status":{
+		"history":[
+			{"id":1,"revision":"` + tagged + `","deployedAt":"2026-10-19T09:02:00Z","initiatedBy":{"username":"ana@pismo.io"}},
+			{"id":2,"revisions":["` + untagged + `","chart"],"deployedAt":"2026-10-19T12:02:00Z"}
+		],
+		"operationState":{"operation":{"initiatedBy":{"automated":true}},"finishedAt":"2026-10-19T12:02:00Z"}
+	}}`)
+
+	syncs, err := ParseHistory(body)
+	if err != nil {
+		t.Fatalf("ParseHistory: %v", err)
+	}
+	if len(syncs) != 2 || syncs[0].ID != 2 || syncs[0].Revision != untagged || !syncs[0].Automated {
+		t.Errorf("newest sync = %+v", syncs[0])
+	}
+	if syncs[1].InitiatedBy != "ana@pismo.io" || syncs[1].Automated {
+		t.Errorf("older sync = %+v", syncs[1])
+	}
+}
@@ -0,0 +1,222 @@
+// pkg/dr/pairs.go
+package dr
+
+import (
+	"argocd/pkg/regions"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Pair is a primary region of an account and the region that takes over
+// from it in a disaster
+type Pair struct {
+	Account  string `json:"account"`
+	Primary  string `json:"primary"`
+	Failover string `json:"failover"`
+	// Source is where the pair was declared: lighthouse, clismo or terraform
+	Source string `json:"source"`
+}
+
+func (p Pair) PrimaryLocation() regions.Location {
+	return regions.NewLocation(p.Account, p.Primary)
+}
+
+func (p Pair) FailoverLocation() regions.Location {
+	location := regions.NewLocation(p.Account, p.Failover)
+	location.Primary = false
+	return location
+}
+
+// Config declares DR pairs in Lighthouse itself, for repos whose clismo.yaml
+// and terraform say nothing about DR. Defaults apply to every repo deployed
+// to the pair's primary region.
+type Config struct {
+	Defaults []Pair            `json:"defaults,omitempty"`
+	Repos    map[string][]Pair `json:"repos,omitempty"`
+}
+
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading DR config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing DR config: %v", err)
+	}
+
+	for repo, pairs := range config.Repos {
+		for i, pair := range pairs {
+			if err := pair.validate(); err != nil {
+				return nil, fmt.Errorf("repo %s pair %d: %v", repo, i, err)
+			}
+		}
+	}
+	for i, pair := range config.Defaults {
+		if err := pair.validate(); err != nil {
+			return nil, fmt.Errorf("default pair %d: %v", i, err)
+		}
+	}
+	return &config, nil
+}
+
+func (p Pair) validate() error {
+	switch {
+	case p.Account == "" || p.Primary == "" || p.Failover == "":
+		return fmt.Errorf("account, primary and failover are required")
+	case p.Primary == p.Failover:
+		return fmt.Errorf("primary and failover are both %s", p.Primary)
+	}
+	return nil
+}
+
+// Resolve returns the DR pairs of a repo. Each account's pairs come from the
+// first source that declares any: the Lighthouse config, then clismo.yaml,
+// then the terraform failover_region variables. repoDir is the checkout of
+// the repo and regionDetails its terraform regions.
+func Resolve(config *Config, repo, repoDir string, regionDetails []regions.RegionDetails) []Pair {
+	deployed := make(map[string]bool)
+	for _, region := range regionDetails {
+		deployed[region.AccountDefault+"/"+region.RegionDefault] = true
+	}
+
+	var lighthouse []Pair
+	if config != nil {
+		lighthouse = append(lighthouse, config.Repos[repo]...)
+		for _, pair := range config.Defaults {
+			if deployed[pair.Account+"/"+pair.Primary] {
+				lighthouse = append(lighthouse, pair)
+			}
+		}
+	}
+
+	sources := []struct {
+		name  string
+		pairs []Pair
+	}{
+		{"lighthouse", lighthouse},
+		{"clismo", fromClismo(filepath.Join(repoDir, "clismo.yaml"), regionDetails)},
+		{"terraform", fromTerraform(regionDetails)},
+	}
+
+	claimed := make(map[string]string)
+	seen := make(map[string]bool)
+	var pairs []Pair
+	for _, source := range sources {
+		for _, pair := range source.pairs {
+			if owner, ok := claimed[pair.Account]; ok && owner != source.name {
+				continue
+			}
+			key := pair.Account + "/" + pair.Primary + "/" + pair.Failover
+			if seen[key] {
+				continue
+			}
+			claimed[pair.Account] = source.name
+			seen[key] = true
+			pair.Source = source.name
+			pairs = append(pairs, pair)
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Account != pairs[j].Account {
+			return pairs[i].Account < pairs[j].Account
+		}
+		return pairs[i].Primary < pairs[j].Primary
+	})
+	return pairs
+}
+
+// fromTerraform reads the failover_region variables of the terraform regions
+func fromTerraform(regionDetails []regions.RegionDetails) []Pair {
+	var pairs []Pair
+	for _, region := range regionDetails {
+		if region.FailoverRegion != "" && region.FailoverRegion != region.RegionDefault {
+			pairs = append(pairs, Pair{Account: region.AccountDefault, Primary: region.RegionDefault, Failover: region.FailoverRegion})
+		}
+	}
+	return pairs
+}
+
+var (
+	clismoItemRe     = regexp.MustCompile(`^(\s*)-\s*name:\s*["']?([^"'\s#]+)`)
+	clismoFailoverRe = regexp.MustCompile(`^(\s*)failover:\s*["']?([^"'\s#]+)`)
+)
+
+// fromClismo reads failover keys from the environments of clismo.yaml:
+//
+//	environments:
+//	  - name: prod
+//	    regions:
+//	      - name: sa-east-1
+//	        failover: us-east-1
+//
+// clismo names environments after the terraform directories, so the account
+// is looked up from the terraform region at scripts/terraform/<env>/<region>.
+func fromClismo(clismoPath string, regionDetails []regions.RegionDetails) []Pair {
+	file, err := os.Open(clismoPath)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var pairs []Pair
+	inEnvironments := false
+	envIndent, regionIndent := -1, -1
+	env, region := "", ""
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+
+		if indent == 0 {
+			inEnvironments = strings.HasPrefix(trimmed, "environments:")
+			envIndent, regionIndent = -1, -1
+			continue
+		}
+		if !inEnvironments {
+			continue
+		}
+
+		if match := clismoItemRe.FindStringSubmatch(line); match != nil {
+			itemIndent := len(match[1])
+			switch {
+			case envIndent == -1 || itemIndent <= envIndent:
+				envIndent, regionIndent = itemIndent, -1
+				env, region = match[2], ""
+			default:
+				regionIndent = itemIndent
+				region = match[2]
+			}
+			continue
+		}
+
+		if match := clismoFailoverRe.FindStringSubmatch(line); match != nil && region != "" && len(match[1]) > regionIndent {
+			if match[2] != region {
+				pairs = append(pairs, Pair{Account: clismoAccount(env, region, regionDetails), Primary: region, Failover: match[2]})
+			}
+		}
+	}
+	return pairs
+}
+
+func clismoAccount(env, region string, regionDetails []regions.RegionDetails) string {
+	for _, details := range regionDetails {
+		if filepath.Base(details.Path) == region && filepath.Base(filepath.Dir(details.Path)) == env {
+			return details.AccountDefault
+		}
+	}
+	return env
+}
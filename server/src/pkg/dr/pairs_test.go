@@ -0,0 +1,88 @@
+package dr
+
+import (
+	"argocd/pkg/regions"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const clismoYAML = `name: crm-core
+environments:
+  # prod is declared in Lighthouse, so these are ignored
+  - name: prod
+    regions:
+      - name: sa-east-1
+        failover: us-west-2
+  - name: ext
+    regions:
+      - name: "sa-east-1"
+        failover: 'us-east-1' # DR drill 2026
+      - name: us-east-1
+        failover: us-east-1
+pipelines:
+  - name: sa-east-1
+    failover: eu-west-1
+`
+
+func TestResolvePrecedence(t *testing.T) {
+	repoDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(repoDir, "clismo.yaml"), []byte(clismoYAML), 0644); err != nil {
+		t.Fatal(err)
+	}
+	regionDetails := []regions.RegionDetails{
+		{Path: "scripts/terraform/prod/sa-east-1", AccountDefault: "prod", RegionDefault: "sa-east-1", FailoverRegion: "us-east-2"},
+		{Path: "scripts/terraform/ext/sa-east-1", AccountDefault: "dev-ext", RegionDefault: "sa-east-1", FailoverRegion: "us-east-2"},
+		{Path: "scripts/terraform/stg/sa-east-1", AccountDefault: "stg", RegionDefault: "sa-east-1", FailoverRegion: "us-east-1"},
+		{Path: "scripts/terraform/stg/us-east-1", AccountDefault: "stg", RegionDefault: "us-east-1", FailoverRegion: "us-east-1"},
+	}
+	config := &Config{
+		Repos: map[string][]Pair{"crm-core": {{Account: "prod", Primary: "sa-east-1", Failover: "us-east-1"}}},
+		Defaults: []Pair{
+			{Account: "prod", Primary: "sa-east-1", Failover: "us-east-1"},
+			// crm-core is not deployed to sandbox
+			{Account: "sandbox", Primary: "sa-east-1", Failover: "us-east-1"},
+		},
+	}
+
+	want := []Pair{
+		{Account: "dev-ext", Primary: "sa-east-1", Failover: "us-east-1", Source: "clismo"},
+		{Account: "prod", Primary: "sa-east-1", Failover: "us-east-1", Source: "lighthouse"},
+		{Account: "stg", Primary: "sa-east-1", Failover: "us-east-1", Source: "terraform"},
+	}
+	if got := Resolve(config, "crm-core", repoDir, regionDetails); !reflect.DeepEqual(got, want) {
+		t.Errorf("pairs = %+v, want %+v", got, want)
+	}
+
+	// Without Lighthouse config or clismo.yaml, terraform declares them all
+	want = []Pair{
+		{Account: "dev-ext", Primary: "sa-east-1", Failover: "us-east-2", Source: "terraform"},
+		{Account: "prod", Primary: "sa-east-1", Failover: "us-east-2", Source: "terraform"},
+		{Account: "stg", Primary: "sa-east-1", Failover: "us-east-1", Source: "terraform"},
+	}
+	if got := Resolve(nil, "crm-core", t.TempDir(), regionDetails); !reflect.DeepEqual(got, want) {
+		t.Errorf("terraform pairs = %+v, want %+v", got, want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+	for name, c := range map[string]struct {
+		config string
+		ok     bool
+	}{
+		"valid":            {`{"defaults":[{"account":"prod","primary":"sa-east-1","failover":"us-east-1"}]}`, true},
+		"missing failover": {`{"repos":{"crm-core":[{"account":"prod","primary":"sa-east-1"}]}}`, false},
+		"same region":      {`{"defaults":[{"account":"prod","primary":"sa-east-1","failover":"sa-east-1"}]}`, false},
+		"not json":         {`defaults: []`, false},
+	} {
+		path := filepath.Join(dir, name+".json")
+		if err := os.WriteFile(path, []byte(c.config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); (err == nil) != c.ok {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
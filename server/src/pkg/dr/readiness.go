@@ -0,0 +1,98 @@
+// pkg/dr/readiness.go
+package dr
+
+import (
+	"argocd/pkg/summary"
+	"argocd/pkg/version"
+	"fmt"
+)
+
+// Readiness says whether the failover region of a pair could take over now:
+// it must run the primary's version with at least one replica
+type Readiness struct {
+	Pair
+	Ready            bool   `json:"ready"`
+	PrimaryApp       string `json:"primaryApp"`
+	FailoverApp      string `json:"failoverApp"`
+	PrimaryVersion   string `json:"primaryVersion,omitempty"`
+	FailoverVersion  string `json:"failoverVersion,omitempty"`
+	PrimaryReplicas  int    `json:"primaryReplicas"`
+	FailoverReplicas int    `json:"failoverReplicas"`
+	// Behind is how far the failover version is behind the primary's, when
+	// both are release versions
+	Behind   *version.Gap `json:"behind,omitempty"`
+	Problems []string     `json:"problems,omitempty"`
+}
+
+// Check works out the readiness of each pair from the repo's summary
+func Check(repo *summary.Repo, pairs []Pair) []Readiness {
+	apps := make(map[string]summary.App)
+	for _, app := range repo.Apps {
+		apps[app.AppName] = app
+	}
+
+	results := make([]Readiness, 0, len(pairs))
+	for _, pair := range pairs {
+		result := Readiness{
+			Pair:        pair,
+			PrimaryApp:  pair.PrimaryLocation().AppName(repo.RepoName),
+			FailoverApp: pair.FailoverLocation().AppName(repo.RepoName),
+		}
+		problem := func(format string, args ...interface{}) {
+			result.Problems = append(result.Problems, fmt.Sprintf(format, args...))
+		}
+
+		primary, hasPrimary := apps[result.PrimaryApp]
+		failover, hasFailover := apps[result.FailoverApp]
+		if hasPrimary {
+			result.PrimaryVersion = primary.Version()
+			result.PrimaryReplicas = replicas(primary)
+		}
+		if hasFailover {
+			result.FailoverVersion = failover.Version()
+			result.FailoverReplicas = replicas(failover)
+		}
+
+		switch {
+		case !hasFailover:
+			problem("%s is not in the summary; refresh the repo", result.FailoverApp)
+		case failover.HasErrors():
+			problem("%s has errors: %v", result.FailoverApp, failover.Error)
+		case result.FailoverReplicas == 0:
+			problem("%s has no replicas running", result.FailoverApp)
+		case result.FailoverVersion == "":
+			problem("the failover version of %s is unknown", result.FailoverApp)
+		}
+
+		switch {
+		case !hasPrimary || result.PrimaryVersion == "":
+			problem("the primary version of %s is unknown", result.PrimaryApp)
+		case result.FailoverVersion == "":
+			// already reported above
+		case result.FailoverVersion != result.PrimaryVersion:
+			primaryVersion, primaryOK := version.Parse(result.PrimaryVersion)
+			failoverVersion, failoverOK := version.Parse(result.FailoverVersion)
+			switch {
+			case !primaryOK || !failoverOK:
+				problem("failover runs %s but primary runs %s", result.FailoverVersion, result.PrimaryVersion)
+			case version.Compare(failoverVersion, primaryVersion) < 0:
+				gap := version.Behind(failoverVersion, primaryVersion)
+				result.Behind = &gap
+				problem("failover is %s behind primary (%s vs %s)", gap, result.FailoverVersion, result.PrimaryVersion)
+			default:
+				problem("failover is ahead of primary (%s vs %s)", result.FailoverVersion, result.PrimaryVersion)
+			}
+		}
+
+		result.Ready = len(result.Problems) == 0
+		results = append(results, result)
+	}
+	return results
+}
+
+func replicas(app summary.App) int {
+	if app.Deployment == nil {
+		return 0
+	}
+	return app.Deployment.TotalPods
+}
@@ -0,0 +1,71 @@
+package dr
+
+import (
+	"argocd/pkg/analyzer"
+	"argocd/pkg/summary"
+	"reflect"
+	"testing"
+)
+
+func app(name, version string, pods int, errors ...string) summary.App {
+	app := summary.App{AppName: name, Error: errors}
+	if version != "" || pods > 0 {
+		app.Deployment = &analyzer.DeploymentAnalysis{TotalPods: pods}
+		if version != "" {
+			app.Deployment.Deployments = []analyzer.VersionDeployment{{Version: version, Type: "stable", PodCount: pods}}
+		}
+	}
+	return app
+}
+
+func TestCheck(t *testing.T) {
+	pair := Pair{Account: "prod", Primary: "sa-east-1", Failover: "us-east-1", Source: "terraform"}
+	primary := app("crm-core-prod-sa-east-1", "1.25.0", 3)
+
+	cases := []struct {
+		name     string
+		failover summary.App
+		primary  summary.App
+		ready    bool
+		problems []string
+	}{
+		{"same version with replicas", app("crm-core-prod-us-east-1", "1.25.0", 1), primary, true, nil},
+		{"missing failover", summary.App{}, primary, false, []string{"crm-core-prod-us-east-1 is not in the summary; refresh the repo"}},
+		{"failover with errors", app("crm-core-prod-us-east-1", "1.25.0", 1, "timeout reading rollout"), primary, false,
+			[]string{"crm-core-prod-us-east-1 has errors: [timeout reading rollout]"}},
+		{"scaled to zero", app("crm-core-prod-us-east-1", "1.25.0", 0), primary, false, []string{"crm-core-prod-us-east-1 has no replicas running"}},
+		// Pods are running but no version could be read from them
+		{"unknown failover version", app("crm-core-prod-us-east-1", "", 2), primary, false, []string{"the failover version of crm-core-prod-us-east-1 is unknown"}},
+		{"unknown primary version", app("crm-core-prod-us-east-1", "1.25.0", 1), summary.App{}, false, []string{"the primary version of crm-core-prod-sa-east-1 is unknown"}},
+		{"behind", app("crm-core-prod-us-east-1", "1.23.4", 1), primary, false, []string{"failover is 2 minor versions behind primary (1.23.4 vs 1.25.0)"}},
+		{"ahead", app("crm-core-prod-us-east-1", "1.26.0", 1), primary, false, []string{"failover is ahead of primary (1.26.0 vs 1.25.0)"}},
+		{"not release versions", app("crm-core-prod-us-east-1", "feature-x", 1), primary, false, []string{"failover runs feature-x but primary runs 1.25.0"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := &summary.Repo{RepoName: "crm-core", Apps: []summary.App{c.primary, c.failover}}
+			results := Check(repo, []Pair{pair})
+			if len(results) != 1 {
+				t.Fatalf("results = %+v", results)
+			}
+			result := results[0]
+			if result.Ready != c.ready || !reflect.DeepEqual(result.Problems, c.problems) {
+				t.Errorf("ready = %v, problems = %q, want %v, %q", result.Ready, result.Problems, c.ready, c.problems)
+			}
+			if result.PrimaryApp != "crm-core-prod-sa-east-1" || result.FailoverApp != "crm-core-prod-us-east-1" || result.Source != "terraform" {
+				t.Errorf("result = %+v", result)
+			}
+		})
+	}
+}
+
+func TestCheckReportsTheGap(t *testing.T) {
+	repo := &summary.Repo{RepoName: "crm-core", Apps: []summary.App{
+		app("crm-core-prod-sa-east-1", "2.0.0", 4),
+		app("crm-core-prod-us-east-1", "1.9.0", 2),
+	}}
+	result := Check(repo, []Pair{{Account: "prod", Primary: "sa-east-1", Failover: "us-east-1"}})[0]
+	if result.Behind == nil || result.Behind.Major != 1 || result.PrimaryReplicas != 4 || result.FailoverReplicas != 2 {
+		t.Errorf("result = %+v", result)
+	}
+}
@@ -0,0 +1,379 @@
+// pkg/drift/drift.go
+package drift
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Resource statuses
+const (
+	StatusModified = "modified"
+	// StatusMissing is a resource that is desired but not live
+	StatusMissing = "missing"
+	// StatusExtra is a live resource that is no longer desired, which a sync
+	// with pruning deletes
+	StatusExtra  = "extra"
+	StatusInSync = "inSync"
+)
+
+// Change kinds
+const (
+	ChangeChanged = "changed"
+	// ChangeMissing is a field that is desired but not live
+	ChangeMissing = "missing"
+	// ChangeExtra is a live field that is not desired
+	ChangeExtra = "extra"
+)
+
+// Change is one field that differs between the live and desired state
+type Change struct {
+	// Path is the field, e.g. spec.template.spec.containers[name=app].image;
+	// list items with a name are matched by it, others by index
+	Path    string      `json:"path"`
+	Kind    string      `json:"kind" enum:"changed,missing,extra"`
+	Live    interface{} `json:"live,omitempty"`
+	Desired interface{} `json:"desired,omitempty"`
+}
+
+// ResourceDiff is how one resource of the app drifted
+type ResourceDiff struct {
+	Group     string   `json:"group,omitempty"`
+	Kind      string   `json:"kind"`
+	Namespace string   `json:"namespace,omitempty"`
+	Name      string   `json:"name"`
+	Status    string   `json:"status" enum:"modified,missing,extra,inSync"`
+	Changes   []Change `json:"changes,omitempty"`
+}
+
+// Resource is one of ArgoCD's managed resources of an app, with its live and
+// desired state decoded
+type Resource struct {
+	Group     string
+	Kind      string
+	Namespace string
+	Name      string
+	Live      map[string]interface{}
+	Desired   map[string]interface{}
+	// Predicted is set when Desired is the live state with the desired one
+	// applied, which ArgoCD computes with the cluster's defaults; fields only
+	// found live are then real drift rather than defaults
+	Predicted bool
+}
+
+// ParseManagedResources reads the response of ArgoCD's
+// /api/v1/applications/<name>/managed-resources. ArgoCD's normalized and
+// predicted live states are preferred, as they already have the cluster's
+// defaults and the app's ignoreDifferences applied.
+func ParseManagedResources(body []byte) ([]Resource, error) {
+	var list struct {
+		Items []struct {
+			Group               string `json:"group"`
+			Kind                string `json:"kind"`
+			Namespace           string `json:"namespace"`
+			Name                string `json:"name"`
+			LiveState           string `json:"liveState"`
+			TargetState         string `json:"targetState"`
+			NormalizedLiveState string `json:"normalizedLiveState"`
+			PredictedLiveState  string `json:"predictedLiveState"`
+			Hook                bool   `json:"hook"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("error parsing ArgoCD managed resources: %v", err)
+	}
+
+	resources := make([]Resource, 0, len(list.Items))
+	for _, item := range list.Items {
+		// Hooks run on every sync and are never in sync with anything
+		if item.Hook {
+			continue
+		}
+		resource := Resource{Group: item.Group, Kind: item.Kind, Namespace: item.Namespace, Name: item.Name}
+		live, desired := item.LiveState, item.TargetState
+		if item.NormalizedLiveState != "" && item.PredictedLiveState != "" && item.PredictedLiveState != "null" {
+			live, desired = item.NormalizedLiveState, item.PredictedLiveState
+			resource.Predicted = true
+		}
+
+		var err error
+		if resource.Live, err = decodeState(live); err != nil {
+			return nil, fmt.Errorf("error parsing live state of %s/%s: %v", item.Kind, item.Name, err)
+		}
+		if resource.Desired, err = decodeState(desired); err != nil {
+			return nil, fmt.Errorf("error parsing desired state of %s/%s: %v", item.Kind, item.Name, err)
+		}
+		resources = append(resources, resource)
+	}
+	return resources, nil
+}
+
+// decodeState decodes a state ArgoCD sends as a JSON string, "null" for none
+func decodeState(state string) (map[string]interface{}, error) {
+	if state == "" || state == "null" {
+		return nil, nil
+	}
+	var object map[string]interface{}
+	if err := json.Unmarshal([]byte(state), &object); err != nil {
+		return nil, err
+	}
+	return object, nil
+}
+
+// noise are the fields the API server or controllers maintain, which differ
+// from the desired state without anything having drifted
+var noise = [][]string{
+	{"status"},
+	{"metadata", "managedFields"},
+	{"metadata", "resourceVersion"},
+	{"metadata", "uid"},
+	{"metadata", "generation"},
+	{"metadata", "creationTimestamp"},
+	{"metadata", "selfLink"},
+	{"metadata", "ownerReferences"},
+	{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	{"metadata", "annotations", "deployment.kubernetes.io/revision"},
+	{"metadata", "annotations", "rollout.argoproj.io/revision"},
+	{"metadata", "annotations", "argocd.argoproj.io/tracking-id"},
+	{"metadata", "labels", "app.kubernetes.io/instance"},
+}
+
+// Report is the drift of an app's resources
+type Report struct {
+	App      string `json:"app"`
+	Instance string `json:"instance,omitempty"`
+	InSync   bool   `json:"inSync"`
+	// Resources are the resources that drifted, or all of them when asked
+	Resources []ResourceDiff `json:"resources"`
+}
+
+// Build diffs every resource of an app. Resources in sync are left out
+// unless all is set.
+func Build(app, instance string, resources []Resource, all bool) Report {
+	report := Report{App: app, Instance: instance, InSync: true, Resources: []ResourceDiff{}}
+	for _, resource := range resources {
+		diff := Diff(resource)
+		if diff.Status != StatusInSync {
+			report.InSync = false
+		} else if !all {
+			continue
+		}
+		report.Resources = append(report.Resources, diff)
+	}
+	sort.SliceStable(report.Resources, func(i, j int) bool {
+		a, b := report.Resources[i], report.Resources[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	return report
+}
+
+// Diff compares the live and desired state of a resource, without noise
+func Diff(resource Resource) ResourceDiff {
+	result := ResourceDiff{
+		Group:     resource.Group,
+		Kind:      resource.Kind,
+		Namespace: resource.Namespace,
+		Name:      resource.Name,
+		Status:    StatusInSync,
+	}
+	switch {
+	case resource.Live == nil && resource.Desired == nil:
+		return result
+	case resource.Live == nil:
+		result.Status = StatusMissing
+		return result
+	case resource.Desired == nil:
+		result.Status = StatusExtra
+		return result
+	}
+
+	live, desired := withoutNoise(resource.Live), withoutNoise(resource.Desired)
+	d := &differ{extraIsDrift: resource.Predicted}
+	d.compare("", live, desired)
+	if len(d.changes) > 0 {
+		result.Status = StatusModified
+		result.Changes = d.changes
+	}
+	return result
+}
+
+// withoutNoise returns a copy of object without the noise fields
+func withoutNoise(object map[string]interface{}) map[string]interface{} {
+	copied := deepCopy(object).(map[string]interface{})
+	for _, path := range noise {
+		parent := copied
+		for _, key := range path[:len(path)-1] {
+			child, ok := parent[key].(map[string]interface{})
+			if !ok {
+				parent = nil
+				break
+			}
+			parent = child
+		}
+		if parent == nil {
+			continue
+		}
+		delete(parent, path[len(path)-1])
+		// An annotations or labels map left empty is no difference either
+		if len(path) == 3 && len(parent) == 0 {
+			delete(copied["metadata"].(map[string]interface{}), path[1])
+		}
+	}
+	return copied
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			copied[key] = deepCopy(item)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(v))
+		for i, item := range v {
+			copied[i] = deepCopy(item)
+		}
+		return copied
+	}
+	return value
+}
+
+type differ struct {
+	// extraIsDrift reports fields only found live. Against the raw desired
+	// manifest they are mostly defaults the API server filled in.
+	extraIsDrift bool
+	changes      []Change
+}
+
+func (d *differ) compare(path string, live, desired interface{}) {
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			d.add(path, ChangeChanged, live, desired)
+			return
+		}
+		keys := make([]string, 0, len(desiredValue)+len(liveValue))
+		for key := range desiredValue {
+			keys = append(keys, key)
+		}
+		for key := range liveValue {
+			if _, ok := desiredValue[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			d.compareField(joinKey(path, key), liveValue, desiredValue, key)
+		}
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok {
+			d.add(path, ChangeChanged, live, desired)
+			return
+		}
+		d.compareList(path, liveValue, desiredValue)
+	default:
+		if !reflect.DeepEqual(live, desired) {
+			d.add(path, ChangeChanged, live, desired)
+		}
+	}
+}
+
+func (d *differ) compareField(path string, live, desired map[string]interface{}, key string) {
+	liveValue, inLive := live[key]
+	desiredValue, inDesired := desired[key]
+	switch {
+	case !inLive:
+		d.add(path, ChangeMissing, nil, desiredValue)
+	case !inDesired:
+		if d.extraIsDrift {
+			d.add(path, ChangeExtra, liveValue, nil)
+		}
+	default:
+		d.compare(path, liveValue, desiredValue)
+	}
+}
+
+// compareList matches the items of lists of named objects, such as
+// containers and env vars, by name so that reordering them is no drift
+func (d *differ) compareList(path string, live, desired []interface{}) {
+	liveByName, liveNamed := byName(live)
+	desiredByName, desiredNamed := byName(desired)
+	if !liveNamed || !desiredNamed {
+		for i := 0; i < len(live) || i < len(desired); i++ {
+			itemPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(live):
+				d.add(itemPath, ChangeMissing, nil, desired[i])
+			case i >= len(desired):
+				if d.extraIsDrift {
+					d.add(itemPath, ChangeExtra, live[i], nil)
+				}
+			default:
+				d.compare(itemPath, live[i], desired[i])
+			}
+		}
+		return
+	}
+
+	names := make([]string, 0, len(desiredByName)+len(liveByName))
+	for name := range desiredByName {
+		names = append(names, name)
+	}
+	for name := range liveByName {
+		if _, ok := desiredByName[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		d.compareField(fmt.Sprintf("%s[name=%s]", path, name), liveByName, desiredByName, name)
+	}
+}
+
+// byName indexes a list of objects that all have a distinct name
+func byName(list []interface{}) (map[string]interface{}, bool) {
+	if len(list) == 0 {
+		return map[string]interface{}{}, true
+	}
+	indexed := make(map[string]interface{}, len(list))
+	for _, item := range list {
+		object, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		name, ok := object["name"].(string)
+		if !ok || name == "" {
+			return nil, false
+		}
+		if _, duplicate := indexed[name]; duplicate {
+			return nil, false
+		}
+		indexed[name] = item
+	}
+	return indexed, true
+}
+
+func joinKey(path, key string) string {
+	if strings.ContainsAny(key, ".[]") {
+		key = "[" + key + "]"
+		return path + key
+	}
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func (d *differ) add(path, kind string, live, desired interface{}) {
+	d.changes = append(d.changes, Change{Path: path, Kind: kind, Live: live, Desired: desired})
+}
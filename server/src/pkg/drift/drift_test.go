@@ -0,0 +1,165 @@
+package drift
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func object(t *testing.T, source string) map[string]interface{} {
+	t.Helper()
+	var decoded map[string]interface{}
+	if err := json.Unmarshal([]byte(source), &decoded); err != nil {
+		t.Fatalf("bad fixture: %v", err)
+	}
+	return decoded
+}
+
+const desiredRollout = `{
+	"apiVersion": "argoproj.io/v1alpha1", "kind": "Rollout",
+	"metadata": {"name": "backoffice-core-bff", "labels": {"team": "psm-console"}},
+	"spec": {"replicas": 3, "template": {"spec": {"containers": [
+		{"name": "app", "image": "backoffice-core-bff:1.24.0", "args": ["serve", "--port=8080"],
+		 "env": [{"name": "LOG_LEVEL", "value": "info"}, {"name": "REGION", "value": "sa-east-1"}]},
+		{"name": "istio-proxy", "image": "istio-proxy:1.20"}
+	]}}}
+}`
+
+func TestDiffIgnoresNoiseAndReordering(t *testing.T) {
+	// The API server's bookkeeping, and containers and env vars reordered
+	live := object(t, `{
+		"apiVersion": "argoproj.io/v1alpha1", "kind": "Rollout",
+		"metadata": {"name": "backoffice-core-bff", "uid": "0f1e", "resourceVersion": "981", "generation": 7,
+			"creationTimestamp": "2026-10-01T00:00:00Z", "managedFields": [{"manager": "argocd"}],
+			"ownerReferences": [{"kind": "Application"}],
+			"labels": {"team": "psm-console", "app.kubernetes.io/instance": "backoffice-core-bff-prod-sa-east-1"},
+			"annotations": {"rollout.argoproj.io/revision": "12", "kubectl.kubernetes.io/last-applied-configuration": "{}"}},
+		"spec": {"replicas": 3, "template": {"spec": {"containers": [
+			{"name": "istio-proxy", "image": "istio-proxy:1.20"},
+			{"name": "app", "image": "backoffice-core-bff:1.24.0", "args": ["serve", "--port=8080"],
+			 "env": [{"name": "REGION", "value": "sa-east-1"}, {"name": "LOG_LEVEL", "value": "info"}]}
+		]}}},
+		"status": {"phase": "Healthy", "readyReplicas": 3}
+	}`)
+
+	diff := Diff(Resource{Kind: "Rollout", Name: "backoffice-core-bff", Live: live, Desired: object(t, desiredRollout)})
+	if diff.Status != StatusInSync || len(diff.Changes) != 0 {
+		t.Errorf("diff = %+v, want in sync", diff)
+	}
+}
+
+func TestDiffReportsChangesByPath(t *testing.T) {
+	live := object(t, `{
+		"apiVersion": "argoproj.io/v1alpha1", "kind": "Rollout",
+		"metadata": {"name": "backoffice-core-bff", "labels": {"team": "psm-console"},
+			"annotations": {"notes.pismo.io/hotfix": "true"}},
+		"spec": {"replicas": 5, "template": {"spec": {"containers": [
+			{"name": "istio-proxy", "image": "istio-proxy:1.20"},
+			{"name": "app", "image": "backoffice-core-bff:1.24.0-hotfix", "args": ["serve", "--port=9090", "--debug"],
+			 "env": [{"name": "LOG_LEVEL", "value": "debug"}]}
+		]}}}
+	}`)
+
+	for _, c := range []struct {
+		predicted bool
+		want      []Change
+	}{
+		// Against the raw manifest, live-only fields are taken for defaults
+		{false, []Change{
+			{Path: "spec.replicas", Kind: ChangeChanged, Live: 5.0, Desired: 3.0},
+			{Path: "spec.template.spec.containers[name=app].args[1]", Kind: ChangeChanged, Live: "--port=9090", Desired: "--port=8080"},
+			{Path: "spec.template.spec.containers[name=app].env[name=LOG_LEVEL].value", Kind: ChangeChanged, Live: "debug", Desired: "info"},
+			{Path: "spec.template.spec.containers[name=app].env[name=REGION]", Kind: ChangeMissing, Desired: map[string]interface{}{"name": "REGION", "value": "sa-east-1"}},
+			{Path: "spec.template.spec.containers[name=app].image", Kind: ChangeChanged, Live: "backoffice-core-bff:1.24.0-hotfix", Desired: "backoffice-core-bff:1.24.0"},
+		}},
+		// Against ArgoCD's predicted state they are drift
+		{true, []Change{
+			{Path: "metadata.annotations", Kind: ChangeExtra, Live: map[string]interface{}{"notes.pismo.io/hotfix": "true"}},
+			{Path: "spec.replicas", Kind: ChangeChanged, Live: 5.0, Desired: 3.0},
+			{Path: "spec.template.spec.containers[name=app].args[1]", Kind: ChangeChanged, Live: "--port=9090", Desired: "--port=8080"},
+			{Path: "spec.template.spec.containers[name=app].args[2]", Kind: ChangeExtra, Live: "--debug"},
+			{Path: "spec.template.spec.containers[name=app].env[name=LOG_LEVEL].value", Kind: ChangeChanged, Live: "debug", Desired: "info"},
+			{Path: "spec.template.spec.containers[name=app].env[name=REGION]", Kind: ChangeMissing, Desired: map[string]interface{}{"name": "REGION", "value": "sa-east-1"}},
+			{Path: "spec.template.spec.containers[name=app].image", Kind: ChangeChanged, Live: "backoffice-core-bff:1.24.0-hotfix", Desired: "backoffice-core-bff:1.24.0"},
+		}},
+	} {
+		diff := Diff(Resource{Kind: "Rollout", Name: "backoffice-core-bff", Live: live, Desired: object(t, desiredRollout), Predicted: c.predicted})
+		if diff.Status != StatusModified {
+			t.Errorf("predicted=%v: status = %s", c.predicted, diff.Status)
+		}
+		if !reflect.DeepEqual(diff.Changes, c.want) {
+			t.Errorf("predicted=%v: changes =\n%+v\nwant\n%+v", c.predicted, diff.Changes, c.want)
+		}
+	}
+}
+
+func TestDiffMatchesListsByNameOnlyWhenEveryItemHasOne(t *testing.T) {
+	desired := map[string]interface{}{"ports": []interface{}{
+		map[string]interface{}{"name": "http", "port": 8080.0},
+		map[string]interface{}{"port": 9090.0},
+	}}
+	live := map[string]interface{}{"ports": []interface{}{
+		map[string]interface{}{"port": 9090.0},
+		map[string]interface{}{"name": "http", "port": 8080.0},
+	}}
+
+	// One port has no name, so the lists are compared by index
+	diff := Diff(Resource{Kind: "Service", Name: "backoffice-core-bff", Live: live, Desired: desired})
+	paths := []string{}
+	for _, change := range diff.Changes {
+		paths = append(paths, change.Path)
+	}
+	want := []string{"ports[0].name", "ports[0].port", "ports[1].port"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("changed paths = %v, want %v", paths, want)
+	}
+}
+
+func TestBuildListsDriftedResources(t *testing.T) {
+	configMap := map[string]interface{}{"data": map[string]interface{}{"LOG_LEVEL": "info"}}
+	resources := []Resource{
+		{Kind: "Service", Name: "backoffice-core-bff", Live: configMap, Desired: configMap},
+		{Kind: "ConfigMap", Name: "backoffice-core-bff-old", Live: configMap},
+		{Kind: "ConfigMap", Name: "backoffice-core-bff", Desired: configMap},
+	}
+
+	report := Build("backoffice-core-bff-prod-sa-east-1", "default", resources, false)
+	if report.InSync || len(report.Resources) != 2 {
+		t.Fatalf("report = %+v", report)
+	}
+	if report.Resources[0].Name != "backoffice-core-bff" || report.Resources[0].Status != StatusMissing ||
+		report.Resources[1].Name != "backoffice-core-bff-old" || report.Resources[1].Status != StatusExtra {
+		t.Errorf("resources = %+v", report.Resources)
+	}
+
+	if all := Build("backoffice-core-bff-prod-sa-east-1", "default", resources, true); len(all.Resources) != 3 {
+		t.Errorf("all resources = %+v", all.Resources)
+	}
+}
+
+func TestParseManagedResourcesPrefersPredictedStates(t *testing.T) {
+	body := []byte(`{"items":[
+		{"kind":"Rollout","name":"backoffice-core-bff","liveState":"{\"spec\":{\"replicas\":3}}","targetState":"{\"spec\":{}}",
+		 "normalizedLiveState":"{\"spec\":{\"replicas\":3}}","predictedLiveState":"{\"spec\":{\"replicas\":2}}"},
+		{"kind":"ConfigMap","name":"backoffice-core-bff","liveState":"null","targetState":"{\"data\":{}}"},
+		{"kind":"Job","name":"migrate","hook":true,"liveState":"{}","targetState":"{}"}
+	]}`)
+
+	resources, err := ParseManagedResources(body)
+	if err != nil {
+		t.Fatalf("ParseManagedResources: %v", err)
+	}
+	if len(resources) != 2 {
+		t.Fatalf("resources = %+v, want the hook left out", resources)
+	}
+	rollout := resources[0]
+	if !rollout.Predicted || !reflect.DeepEqual(rollout.Desired, map[string]interface{}{"spec": map[string]interface{}{"replicas": 2.0}}) {
+		t.Errorf("rollout = %+v", rollout)
+	}
+	if resources[1].Live != nil || resources[1].Predicted {
+		t.Errorf("config map = %+v", resources[1])
+	}
+	if _, err := ParseManagedResources([]byte(`{"items":[{"kind":"Rollout","name":"x","liveState":"{broken"}]}`)); err == nil {
+		t.Error("broken live state parsed")
+	}
+}
@@ -0,0 +1,201 @@
+// pkg/events/broker.go
+package events
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	TypeRefreshStarted   = "refresh.started"
+	TypeRefreshProgress  = "refresh.progress"
+	TypeRefreshCompleted = "refresh.completed"
+	TypeRefreshFailed    = "refresh.failed"
+	TypeVersionChanged   = "version.changed"
+	TypeRolloutPaused    = "rollout.paused"
+	TypeRolloutResumed   = "rollout.resumed"
+	TypeRolloutAborted   = "rollout.aborted"
+	TypeHealthChanged    = "health.changed"
+	TypeHealthError      = "health.error"
+	TypeAppError         = "app.error"
+	TypeAppRecovered     = "app.recovered"
+	TypeAppAdded         = "app.added"
+	TypeAppRemoved       = "app.removed"
+)
+
+type Event struct {
+	ID   string `json:"id"`
+	Type string `json:"type"`
+	Repo string `json:"repo"`
+	App  string `json:"app,omitempty"`
+	// Env is the app's environment tier, as in summary.App.Env: prod,
+	// stag, ext, integration or dev
+	Env string `json:"env,omitempty"`
+	// Location is the account and region of the app, e.g. "prod-sa-east-1"
+	Location string                 `json:"location,omitempty"`
+	Message  string                 `json:"message"`
+	Data     map[string]interface{} `json:"data,omitempty"`
+	Time     time.Time              `json:"time"`
+}
+
+// Broker fans published events out to subscribers and keeps the most recent
+// ones so reconnecting clients can replay what they missed
+type Broker struct {
+	mu          sync.Mutex
+	nextID      int64
+	history     []Event
+	historySize int
+	subscribers map[chan Event]string
+	queues      map[*Queue]bool
+}
+
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		historySize: historySize,
+		subscribers: make(map[chan Event]string),
+		queues:      make(map[*Queue]bool),
+	}
+}
+
+// Publish stamps the event with an ID and time and delivers it to every
+// subscriber watching its repo. Slow subscribers miss events rather than
+// blocking the refresh; queues get every event.
+func (b *Broker) Publish(event Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.nextID++
+	event.ID = strconv.FormatInt(b.nextID, 10)
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+
+	b.history = append(b.history, event)
+	if len(b.history) > b.historySize {
+		b.history = b.history[len(b.history)-b.historySize:]
+	}
+
+	for ch, repo := range b.subscribers {
+		if repo != "" && repo != event.Repo {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	for queue := range b.queues {
+		queue.push(event)
+	}
+
+	return event
+}
+
+// Subscribe returns a channel of events for the repo ("" for every repo),
+// preceded by any history newer than lastEventID
+func (b *Broker) Subscribe(repo, lastEventID string) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if lastID, err := strconv.ParseInt(lastEventID, 10, 64); err == nil {
+		for _, event := range b.history {
+			id, _ := strconv.ParseInt(event.ID, 10, 64)
+			if id > lastID && (repo == "" || event.Repo == repo) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	ch := make(chan Event, 64+len(replay))
+	for _, event := range replay {
+		ch <- event
+	}
+	b.subscribers[ch] = repo
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return ch, unsubscribe
+}
+
+// Queue is an unbounded subscription for consumers that must not miss an
+// event, such as notifications
+type Queue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	events []Event
+	closed bool
+}
+
+// SubscribeQueue returns a queue of every event published from now on
+func (b *Broker) SubscribeQueue() (*Queue, func()) {
+	queue := &Queue{}
+	queue.cond = sync.NewCond(&queue.mu)
+
+	b.mu.Lock()
+	b.queues[queue] = true
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		delete(b.queues, queue)
+		b.mu.Unlock()
+		queue.close()
+	}
+	return queue, unsubscribe
+}
+
+func (q *Queue) push(event Event) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return
+	}
+	q.events = append(q.events, event)
+	q.cond.Signal()
+}
+
+func (q *Queue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// Next blocks until an event is queued and returns it. It returns false
+// once the queue is unsubscribed and drained.
+func (q *Queue) Next() (Event, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	for len(q.events) == 0 && !q.closed {
+		q.cond.Wait()
+	}
+	if len(q.events) == 0 {
+		return Event{}, false
+	}
+	event := q.events[0]
+	q.events[0] = Event{}
+	q.events = q.events[1:]
+	return event, true
+}
+
+// Recent returns up to limit of the latest events for the repo ("" for all)
+func (b *Broker) Recent(repo string, limit int) []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := []Event{}
+	for i := len(b.history) - 1; i >= 0 && len(events) < limit; i-- {
+		if repo == "" || b.history[i].Repo == repo {
+			events = append(events, b.history[i])
+		}
+	}
+	return events
+}
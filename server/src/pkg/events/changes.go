@@ -0,0 +1,134 @@
+// pkg/events/changes.go
+package events
+
+import (
+	"argocd/pkg/regions"
+	"argocd/pkg/summary"
+	"fmt"
+	"sort"
+)
+
+type appState struct {
+	Location regions.Location
+	Version  string
+	Health   string
+	Paused   bool
+	Aborted  bool
+	Errored  bool
+	TimedOut bool
+}
+
+// versionKnown reports whether the state's version can be trusted: an app
+// that errored or timed out has no version, or a stale one
+func (s appState) versionKnown() bool {
+	return s.Version != "" && !s.Errored && !s.TimedOut
+}
+
+// DetectChanges compares two summaries of the same repo and returns an event
+// for every app whose version, rollout, health or error state changed.
+// before may be nil on the first refresh, in which case nothing is reported.
+func DetectChanges(repo string, before, after *summary.Repo) []Event {
+	if before == nil || after == nil {
+		return nil
+	}
+
+	oldApps := appStates(before)
+	newApps := appStates(after)
+
+	names := make([]string, 0, len(newApps))
+	for name := range newApps {
+		names = append(names, name)
+	}
+	for name := range oldApps {
+		if _, ok := newApps[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var events []Event
+	for _, name := range names {
+		oldState, existed := oldApps[name]
+		newState, exists := newApps[name]
+
+		location := newState.Location
+		if !exists {
+			location = oldState.Location
+		}
+		where := location.Key()
+		event := func(eventType, message string, data map[string]interface{}) {
+			events = append(events, Event{Type: eventType, Repo: repo, App: name, Env: location.Env, Location: where, Message: message, Data: data})
+		}
+
+		switch {
+		case !existed:
+			event(TypeAppAdded, fmt.Sprintf("%s is now deployed in %s", repo, where), nil)
+			continue
+		case !exists:
+			event(TypeAppRemoved, fmt.Sprintf("%s is no longer deployed in %s", repo, where), nil)
+			continue
+		}
+
+		// An app recovering from an error or timeout gets its version back
+		// without having been deployed
+		if oldState.versionKnown() && newState.versionKnown() && oldState.Version != newState.Version {
+			event(TypeVersionChanged, fmt.Sprintf("version changed in %s: %s -> %s", where, oldState.Version, newState.Version),
+				map[string]interface{}{"from": oldState.Version, "to": newState.Version})
+		}
+		if !oldState.Paused && newState.Paused {
+			event(TypeRolloutPaused, fmt.Sprintf("rollout paused in %s", where), nil)
+		}
+		if oldState.Paused && !newState.Paused {
+			event(TypeRolloutResumed, fmt.Sprintf("rollout resumed in %s", where), nil)
+		}
+		if !oldState.Aborted && newState.Aborted {
+			event(TypeRolloutAborted, fmt.Sprintf("rollout aborted in %s", where), map[string]interface{}{"version": newState.Version})
+		}
+		if oldState.Health != newState.Health {
+			event(TypeHealthChanged, fmt.Sprintf("health in %s changed from %s to %s", where, displayHealth(oldState.Health), displayHealth(newState.Health)),
+				map[string]interface{}{"from": oldState.Health, "to": newState.Health})
+			if newState.Health == "Error" {
+				event(TypeHealthError, fmt.Sprintf("health.status is Error in %s", where), nil)
+			}
+		}
+		if !oldState.Errored && newState.Errored {
+			event(TypeAppError, fmt.Sprintf("%s is reporting errors", where), nil)
+		}
+		if oldState.Errored && !newState.Errored {
+			event(TypeAppRecovered, fmt.Sprintf("%s recovered", where), nil)
+		}
+	}
+
+	return events
+}
+
+func appStates(repo *summary.Repo) map[string]appState {
+	states := make(map[string]appState)
+
+	for _, app := range repo.Apps {
+		if app.AppName == "" {
+			continue
+		}
+		state := appState{
+			Location: app.Location(),
+			Version:  app.Version(),
+			Paused:   app.Paused(),
+			Errored:  app.HasErrors(),
+			TimedOut: app.TimedOut,
+		}
+		if app.ArgoCD != nil {
+			state.Health = app.ArgoCD.Health
+			state.Aborted = app.ArgoCD.Aborted
+		}
+		states[app.AppName] = state
+	}
+
+	return states
+}
+
+func displayHealth(health string) string {
+	if health == "" {
+		return "ok"
+	}
+	return health
+}
@@ -0,0 +1,83 @@
+package events
+
+import (
+	"argocd/pkg/analyzer"
+	"argocd/pkg/regions"
+	"argocd/pkg/summary"
+	"testing"
+)
+
+func running(version string) summary.App {
+	app := summary.NewApp("backoffice-core-bff-prod-sa-east-1", regions.NewLocation("prod", "sa-east-1"))
+	app.Deployment = &analyzer.DeploymentAnalysis{
+		Deployments: []analyzer.VersionDeployment{{Version: version, Type: "stable", PodCount: 3}},
+	}
+	return app
+}
+
+func types(events []Event) map[string]bool {
+	found := make(map[string]bool)
+	for _, event := range events {
+		found[event.Type] = true
+	}
+	return found
+}
+
+func TestDetectChangesReportsDeploys(t *testing.T) {
+	before := &summary.Repo{Apps: []summary.App{running("1.23.0")}}
+	after := &summary.Repo{Apps: []summary.App{running("1.24.0")}}
+
+	events := DetectChanges("backoffice-core-bff", before, after)
+	if len(events) != 1 || events[0].Type != TypeVersionChanged {
+		t.Fatalf("events = %+v, want one %s", events, TypeVersionChanged)
+	}
+	if events[0].Data["from"] != "1.23.0" || events[0].Data["to"] != "1.24.0" {
+		t.Errorf("data = %v", events[0].Data)
+	}
+	if events[0].Env != "prod" || events[0].Location != "prod-sa-east-1" {
+		t.Errorf("env = %q, location = %q, want prod and prod-sa-east-1", events[0].Env, events[0].Location)
+	}
+}
+
+func TestDetectChangesTakesLocationsFromTheApps(t *testing.T) {
+	// The account has dashes, so it cannot be cut out of the app name
+	failover := summary.NewApp("backoffice-core-bff-dev-ext-us-east-1", regions.Location{Account: "dev-ext", Region: "us-east-1", Env: "ext"})
+	before := &summary.Repo{Apps: []summary.App{failover}}
+
+	events := DetectChanges("backoffice-core-bff", before, &summary.Repo{})
+	if len(events) != 1 || events[0].Type != TypeAppRemoved {
+		t.Fatalf("events = %+v, want one %s", events, TypeAppRemoved)
+	}
+	if events[0].Env != "ext" || events[0].Location != "dev-ext-us-east-1" {
+		t.Errorf("env = %q, location = %q, want ext and dev-ext-us-east-1", events[0].Env, events[0].Location)
+	}
+}
+
+func TestDetectChangesIgnoresVersionsOfFailedRefreshes(t *testing.T) {
+	timedOut := running("")
+	timedOut.Deployment = nil
+	timedOut.TimedOut = true
+	errored := running("1.23.0")
+	errored.Error = []string{"resource-tree returned 503"}
+
+	cases := map[string]struct {
+		before, after summary.App
+		want          string
+	}{
+		"recovered from timeout": {before: timedOut, after: running("1.24.0")},
+		"recovered from error":   {before: errored, after: running("1.24.0"), want: TypeAppRecovered},
+		"timed out":              {before: running("1.24.0"), after: timedOut},
+	}
+	for name, c := range cases {
+		t.Run(name, func(t *testing.T) {
+			events := DetectChanges("backoffice-core-bff", &summary.Repo{Apps: []summary.App{c.before}}, &summary.Repo{Apps: []summary.App{c.after}})
+			found := types(events)
+			if found[TypeVersionChanged] {
+				t.Errorf("got a %s event: %+v", TypeVersionChanged, events)
+			}
+			if c.want != "" && !found[c.want] {
+				t.Errorf("no %s event: %+v", c.want, events)
+			}
+		})
+	}
+}
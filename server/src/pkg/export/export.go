@@ -0,0 +1,162 @@
+// pkg/export/export.go
+package export
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Format string
+
+const (
+	FormatJSON     Format = "json"
+	FormatCSV      Format = "csv"
+	FormatMarkdown Format = "md"
+	FormatXLSX     Format = "xlsx"
+)
+
+// Table is a named grid of string cells that every renderer understands
+type Table struct {
+	Name    string     `json:"name"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+}
+
+// ParseFormat maps a ?format= value or CLI flag onto a supported Format
+func ParseFormat(value string) (Format, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "md", "markdown":
+		return FormatMarkdown, nil
+	case "xlsx", "excel":
+		return FormatXLSX, nil
+	}
+	return "", fmt.Errorf("unsupported format %q, use json, csv, md or xlsx", value)
+}
+
+// ContentType returns the HTTP content type for the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatMarkdown:
+		return "text/markdown; charset=utf-8"
+	case FormatXLSX:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "application/json"
+}
+
+// Extension returns the file extension used for downloads
+func (f Format) Extension() string {
+	return string(f)
+}
+
+// Write renders the tables in the requested format. CSV only holds a single
+// table, so passing more than one is an error; the other formats write every
+// table (XLSX as one sheet each).
+func Write(w io.Writer, format Format, tables ...Table) error {
+	switch format {
+	case FormatJSON:
+		return WriteJSON(w, tables...)
+	case FormatCSV:
+		if len(tables) != 1 {
+			return fmt.Errorf("csv export holds exactly one table, got %d", len(tables))
+		}
+		return WriteCSV(w, tables[0])
+	case FormatMarkdown:
+		return WriteMarkdown(w, tables...)
+	case FormatXLSX:
+		return WriteXLSX(w, tables...)
+	}
+	return fmt.Errorf("unsupported format %q", format)
+}
+
+// WriteJSON writes a single table as an object and several as an array
+func WriteJSON(w io.Writer, tables ...Table) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if len(tables) == 1 {
+		return encoder.Encode(tables[0])
+	}
+	return encoder.Encode(tables)
+}
+
+// WriteCSV writes the table as RFC 4180 CSV: CRLF line endings, and fields
+// containing commas, quotes or line breaks are quoted
+func WriteCSV(w io.Writer, table Table) error {
+	writer := csv.NewWriter(w)
+	writer.UseCRLF = true
+
+	if err := writer.Write(table.Columns); err != nil {
+		return fmt.Errorf("error writing csv header: %v", err)
+	}
+	for _, row := range table.Rows {
+		if err := writer.Write(padRow(row, len(table.Columns))); err != nil {
+			return fmt.Errorf("error writing csv row: %v", err)
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteMarkdown writes each table as a GitHub-flavoured Markdown table,
+// headed by the table name when there is more than one
+func WriteMarkdown(w io.Writer, tables ...Table) error {
+	var sb strings.Builder
+
+	for i, table := range tables {
+		if i > 0 {
+			sb.WriteString("\n")
+		}
+		if len(tables) > 1 && table.Name != "" {
+			fmt.Fprintf(&sb, "## %s\n\n", escapeMarkdown(table.Name))
+		}
+
+		sb.WriteString(markdownRow(table.Columns))
+		separators := make([]string, len(table.Columns))
+		for j := range separators {
+			separators[j] = "---"
+		}
+		sb.WriteString("|" + strings.Join(separators, "|") + "|\n")
+
+		for _, row := range table.Rows {
+			sb.WriteString(markdownRow(padRow(row, len(table.Columns))))
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+func markdownRow(cells []string) string {
+	escaped := make([]string, len(cells))
+	for i, cell := range cells {
+		escaped[i] = escapeMarkdown(cell)
+	}
+	return "| " + strings.Join(escaped, " | ") + " |\n"
+}
+
+func escapeMarkdown(value string) string {
+	value = strings.ReplaceAll(value, "\\", "\\\\")
+	value = strings.ReplaceAll(value, "|", "\\|")
+	value = strings.ReplaceAll(value, "\r\n", "<br>")
+	return strings.ReplaceAll(value, "\n", "<br>")
+}
+
+// padRow makes sure every row has one cell per column
+func padRow(row []string, width int) []string {
+	if len(row) >= width {
+		return row
+	}
+	padded := make([]string, width)
+	copy(padded, row)
+	return padded
+}
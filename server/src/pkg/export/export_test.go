@@ -0,0 +1,67 @@
+package export
+
+import (
+	"bytes"
+	"encoding/csv"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var awkward = Table{
+	Name:    "fleet-overview",
+	Columns: []string{"repo", "versions", "note"},
+	Rows: [][]string{
+		{"backoffice-core-bff", "prod: 1.24.0, ext: 1.25.0", `says "hi"`},
+		{"psm-accounts", "dev | stag", "line one\nline two"},
+		{"short-row"},
+	},
+}
+
+func TestWriteCSVQuotesAwkwardCells(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, awkward); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.HasPrefix(out, "repo,versions,note\r\n") {
+		t.Errorf("header line = %q", strings.SplitN(out, "\n", 2)[0])
+	}
+	if !strings.Contains(out, `"prod: 1.24.0, ext: 1.25.0","says ""hi"""`) {
+		t.Errorf("commas and quotes not quoted: %q", out)
+	}
+
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatalf("reading the export back: %v", err)
+	}
+	want := [][]string{awkward.Columns, awkward.Rows[0], awkward.Rows[1], {"short-row", "", ""}}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
+
+func TestWriteMarkdownEscapesPipesAndLineBreaks(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, awkward); err != nil {
+		t.Fatalf("WriteMarkdown: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("got %d lines, want a header, a separator and 3 rows:\n%s", len(lines), buf.String())
+	}
+	if lines[3] != `| psm-accounts | dev \| stag | line one<br>line two |` {
+		t.Errorf("row = %q", lines[3])
+	}
+	if lines[4] != "| short-row |  |  |" {
+		t.Errorf("short row = %q", lines[4])
+	}
+}
+
+func TestWriteCSVHoldsOneTable(t *testing.T) {
+	if err := Write(&bytes.Buffer{}, FormatCSV, awkward, awkward); err == nil {
+		t.Error("csv export of two tables succeeded")
+	}
+}
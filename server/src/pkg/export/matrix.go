@@ -0,0 +1,164 @@
+// pkg/export/matrix.go
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// envPrefix marks the per-environment version keys on /list-repos entries
+const envPrefix = "env-"
+
+// Overview builds the fleet overview table from the /list-repos entries
+func Overview(repos []map[string]interface{}) Table {
+	envs := environments(repos)
+
+	table := Table{
+		Name:    "Fleet overview",
+		Columns: append([]string{"repository", "team", "description", "processed", "deployed"}, envs...),
+	}
+
+	for _, repo := range repos {
+		row := []string{
+			cell(repo["repository_name"]),
+			cell(repo["team"]),
+			cell(repo["description"]),
+			cell(repo["processed"]),
+			cell(repo["deployed"]),
+		}
+		for _, env := range envs {
+			row = append(row, cell(repo[envPrefix+env]))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table
+}
+
+// VersionMatrix lists the deployed version of every repo in every
+// environment, leaving out repos that are not deployed anywhere
+func VersionMatrix(repos []map[string]interface{}) Table {
+	envs := environments(repos)
+
+	table := Table{
+		Name:    "Version matrix",
+		Columns: append([]string{"repository", "team"}, envs...),
+	}
+
+	for _, repo := range repos {
+		versions := repoVersions(repo, envs)
+		if len(versions) == 0 {
+			continue
+		}
+
+		row := []string{cell(repo["repository_name"]), cell(repo["team"])}
+		for _, env := range envs {
+			row = append(row, versions[env])
+		}
+		table.Rows = append(table.Rows, row)
+	}
+
+	return table
+}
+
+// DriftMatrix compares every environment against the repo's reference
+// version (the one deployed to the most environments) and marks each cell
+// as "ok" or "drift (<version>)"
+func DriftMatrix(repos []map[string]interface{}) Table {
+	envs := environments(repos)
+
+	table := Table{
+		Name:    "Drift matrix",
+		Columns: append([]string{"repository", "team", "reference", "drifted"}, envs...),
+	}
+
+	for _, repo := range repos {
+		versions := repoVersions(repo, envs)
+		if len(versions) == 0 {
+			continue
+		}
+
+		reference := referenceVersion(versions)
+		drifted := 0
+		cells := make([]string, 0, len(envs))
+		for _, env := range envs {
+			version, ok := versions[env]
+			switch {
+			case !ok:
+				cells = append(cells, "")
+			case version == reference:
+				cells = append(cells, "ok")
+			default:
+				drifted++
+				cells = append(cells, fmt.Sprintf("drift (%s)", version))
+			}
+		}
+
+		row := []string{cell(repo["repository_name"]), cell(repo["team"]), reference, strconv.Itoa(drifted)}
+		table.Rows = append(table.Rows, append(row, cells...))
+	}
+
+	return table
+}
+
+// environments returns the sorted union of environment names across repos
+func environments(repos []map[string]interface{}) []string {
+	seen := make(map[string]bool)
+	for _, repo := range repos {
+		for key := range repo {
+			if strings.HasPrefix(key, envPrefix) {
+				seen[strings.TrimPrefix(key, envPrefix)] = true
+			}
+		}
+	}
+
+	envs := make([]string, 0, len(seen))
+	for env := range seen {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	return envs
+}
+
+func repoVersions(repo map[string]interface{}, envs []string) map[string]string {
+	versions := make(map[string]string)
+	for _, env := range envs {
+		if version := cell(repo[envPrefix+env]); version != "" {
+			versions[env] = version
+		}
+	}
+	return versions
+}
+
+// referenceVersion picks the most widely deployed version, breaking ties
+// with the lexically greatest so the result is stable
+func referenceVersion(versions map[string]string) string {
+	counts := make(map[string]int)
+	for _, version := range versions {
+		counts[version]++
+	}
+
+	reference := ""
+	for version, count := range counts {
+		if count > counts[reference] || (count == counts[reference] && version > reference) {
+			reference = version
+		}
+	}
+	return reference
+}
+
+func cell(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return fmt.Sprint(value)
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// readXLSX opens a workbook and returns its files by name
+func readXLSX(t *testing.T, data []byte) map[string]string {
+	t.Helper()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("not a zip archive: %v", err)
+	}
+	files := make(map[string]string)
+	for _, file := range archive.File {
+		f, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[file.Name] = string(content)
+	}
+	return files
+}
+
+// sheetCells parses a worksheet back into its rows of cell text
+func sheetCells(t *testing.T, sheet string) [][]string {
+	t.Helper()
+	var worksheet struct {
+		Rows []struct {
+			Cells []struct {
+				Ref  string `xml:"r,attr"`
+				Text string `xml:"is>t"`
+			} `xml:"c"`
+		} `xml:"sheetData>row"`
+	}
+	if err := xml.Unmarshal([]byte(sheet), &worksheet); err != nil {
+		t.Fatalf("worksheet is not valid XML: %v", err)
+	}
+	var rows [][]string
+	for _, row := range worksheet.Rows {
+		var cells []string
+		for _, cell := range row.Cells {
+			cells = append(cells, cell.Text)
+		}
+		rows = append(rows, cells)
+	}
+	return rows
+}
+
+func TestWriteXLSXEscapesCells(t *testing.T) {
+	table := Table{
+		Name:    "versions",
+		Columns: []string{"repo", "prod"},
+		Rows: [][]string{
+			{"<script>&", `1.24.0 "stable"`},
+			{"psm-accounts", "  padded\nand split  "},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, table); err != nil {
+		t.Fatalf("WriteXLSX: %v", err)
+	}
+
+	files := readXLSX(t, buf.Bytes())
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("workbook has no %s", name)
+		}
+	}
+	if strings.Contains(files["xl/worksheets/sheet1.xml"], "<script>") {
+		t.Error("cell markup written unescaped")
+	}
+
+	want := append([][]string{table.Columns}, table.Rows...)
+	if rows := sheetCells(t, files["xl/worksheets/sheet1.xml"]); !reflect.DeepEqual(rows, want) {
+		t.Errorf("cells = %q, want %q", rows, want)
+	}
+}
+
+func TestWriteXLSXNamesSheetsSafely(t *testing.T) {
+	long := "a table name far longer than excel allows"
+	var buf bytes.Buffer
+	if err := WriteXLSX(&buf, Table{Name: "drift: prod/ext"}, Table{Name: long}, Table{Name: long}, Table{}); err != nil {
+		t.Fatalf("WriteXLSX: %v", err)
+	}
+
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal([]byte(readXLSX(t, buf.Bytes())["xl/workbook.xml"]), &workbook); err != nil {
+		t.Fatalf("workbook is not valid XML: %v", err)
+	}
+	var names []string
+	for _, sheet := range workbook.Sheets {
+		names = append(names, sheet.Name)
+	}
+	want := []string{"drift_ prod_ext", long[:31], long[:27] + " (3)", "Sheet4"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("sheet names = %q, want %q", names, want)
+	}
+}
+
+func TestColumnName(t *testing.T) {
+	for index, want := range map[int]string{0: "A", 25: "Z", 26: "AA", 27: "AB", 701: "ZZ", 702: "AAA"} {
+		if got := columnName(index); got != want {
+			t.Errorf("columnName(%d) = %s, want %s", index, got, want)
+		}
+	}
+}
@@ -1,15 +1,30 @@
 package gitProcessor
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 	"time"
 	"unicode"
 )
 
+// Observe, when set, is called after every git command with the git
+// subcommand (log, show, for-each-ref...), how long it took and its error
+var Observe func(operation string, duration time.Duration, err error)
+
+func runGit(cmd *exec.Cmd) ([]byte, error) {
+	start := time.Now()
+	output, err := cmd.Output()
+	if Observe != nil && len(cmd.Args) > 1 {
+		Observe(cmd.Args[1], time.Since(start), err)
+	}
+	return output, err
+}
+
 type RepositoryModule struct {
 	options Options
 }
@@ -20,56 +35,53 @@ func NewRepositoryModule(opts Options) (*RepositoryModule, error) {
 	}, nil
 }
 
-func (m *RepositoryModule) Extract(repoPath string) ([]byte, error) {
-	// Save the current working directory
-	originalDir, err := os.Getwd()
-	if err != nil {
-		return nil, fmt.Errorf("failed to get current working directory: %v", err)
-	}
-	defer func() {
-		if err := os.Chdir(originalDir); err != nil {
-			fmt.Printf("Warning: failed to change back to original directory: %v\n", err)
-		}
-	}()
-
-	// Change to repository directory
-	if err := os.Chdir(repoPath); err != nil {
-		return nil, fmt.Errorf("failed to change to repository directory: %v", err)
+// Extract runs git in repoPath to collect the repo's commits, tags and
+// releases. Git commands are killed when ctx is cancelled or times out.
+// Every command and file read is rooted at repoPath, never at the process
+// working directory, so extractions can run alongside each other.
+func (m *RepositoryModule) Extract(ctx context.Context, repoPath string) ([]byte, error) {
+	if info, err := os.Stat(repoPath); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("repository directory %s not found", repoPath)
 	}
 
 	// Get repository info
 	repo := Repository{}
 
 	// Get remote URL
-	if url, err := m.getRemoteURL(); err == nil {
+	if url, err := m.getRemoteURL(ctx, repoPath); err == nil {
 		repo.URL = url
 	}
 
 	// Get current branch
-	if branch, err := m.getCurrentBranch(); err == nil {
+	if branch, err := m.getCurrentBranch(ctx, repoPath); err == nil {
 		repo.Branch = branch
 	}
 
 	// Get latest commit
-	if commit, err := m.getLatestCommit(); err == nil {
+	if commit, err := m.getLatestCommit(ctx, repoPath); err == nil {
 		repo.LastCommit = commit
 	}
 
 	// Get tags
-	if tags, err := m.getTags(); err == nil {
+	if tags, err := m.getTags(ctx, repoPath); err == nil {
 		repo.Tags = tags
 	}
 
 	// Get commit history
-	if commits, err := m.getCommitHistory(m.options.CommitHistoryMonths); err == nil {
+	if commits, err := m.getCommitHistory(ctx, repoPath, m.options.CommitHistoryMonths); err == nil {
 		repo.CommitHistory = commits
 	}
 
 	// Get release history
-	if releases, err := m.getReleaseHistory(m.options.ReleaseHistoryMonths); err == nil {
+	if releases, err := m.getReleaseHistory(ctx, repoPath, m.options.ReleaseHistoryMonths); err == nil {
 		repo.ReleaseHistory = releases
 	}
 
+	// A cancelled or timed out extraction would be silently incomplete
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("git extraction interrupted: %w", err)
+	}
+
 	// Create initial analysis result
 	result := AnalysisResult{
 		Repository: repo,
@@ -85,26 +97,42 @@ func (m *RepositoryModule) Extract(repoPath string) ([]byte, error) {
 	}
 
 	// Check for Dockerfile
-	if _, err := os.Stat("Dockerfile"); err == nil {
+	dockerfile := filepath.Join(repoPath, "Dockerfile")
+	if _, err := os.Stat(dockerfile); err == nil {
 		result.Build.Docker.Enabled = true
-		if ports, err := m.parseDockerPorts("Dockerfile"); err == nil {
+		if ports, err := m.parseDockerPorts(dockerfile); err == nil {
 			result.Build.Docker.Ports = ports
 		}
 	}
 
 	// Check for dependencies
-	if err := m.detectDependencies(&result); err != nil {
+	if err := m.detectDependencies(repoPath, &result); err != nil {
 		fmt.Printf("Warning: Failed to detect dependencies: %v\n", err)
 	}
 
 	// Check for documentation
-	if err := m.detectDocumentation(&result.Documentation); err != nil {
+	if err := m.detectDocumentation(repoPath, &result.Documentation); err != nil {
 		fmt.Printf("Warning: Failed to detect documentation: %v\n", err)
 	}
 
 	return json.Marshal(result)
 }
 
+// Revisions returns the commits of the last CommitHistoryMonths and the tags
+// of the repo at repoPath, with the commit each tag points to. Unlike Extract
+// it skips the repo's files.
+func (m *RepositoryModule) Revisions(ctx context.Context, repoPath string) ([]Commit, []Tag, error) {
+	tags, err := m.getTags(ctx, repoPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list tags: %v", err)
+	}
+	commits, err := m.getCommitHistory(ctx, repoPath, m.options.CommitHistoryMonths)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list commits: %v", err)
+	}
+	return commits, tags, nil
+}
+
 func (m *RepositoryModule) Validate(data []byte) error {
 	var result AnalysisResult
 	return json.Unmarshal(data, &result)
@@ -116,28 +144,31 @@ func (m *RepositoryModule) Transform(data []byte) ([]byte, error) {
 
 // Git Operations
 
-func (m *RepositoryModule) getRemoteURL() (string, error) {
-	cmd := exec.Command("git", "remote", "get-url", "origin")
-	output, err := cmd.Output()
+func (m *RepositoryModule) getRemoteURL(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "remote", "get-url", "origin")
+	cmd.Dir = dir
+	output, err := runGit(cmd)
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
-func (m *RepositoryModule) getCurrentBranch() (string, error) {
-	cmd := exec.Command("git", "rev-parse", "--abbrev-ref", "HEAD")
-	output, err := cmd.Output()
+func (m *RepositoryModule) getCurrentBranch(ctx context.Context, dir string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", "rev-parse", "--abbrev-ref", "HEAD")
+	cmd.Dir = dir
+	output, err := runGit(cmd)
 	if err != nil {
 		return "", err
 	}
 	return strings.TrimSpace(string(output)), nil
 }
 
-func (m *RepositoryModule) getLatestCommit() (Commit, error) {
+func (m *RepositoryModule) getLatestCommit(ctx context.Context, dir string) (Commit, error) {
 	format := "--format=%H%n%an%n%aI%n%s"
-	cmd := exec.Command("git", "log", "-1", format)
-	output, err := cmd.Output()
+	cmd := exec.CommandContext(ctx, "git", "log", "-1", format)
+	cmd.Dir = dir
+	output, err := runGit(cmd)
 	if err != nil {
 		return Commit{}, err
 	}
@@ -160,12 +191,14 @@ func (m *RepositoryModule) getLatestCommit() (Commit, error) {
 	}, nil
 }
 
-func (m *RepositoryModule) getTags() ([]Tag, error) {
-	cmd := exec.Command("git", "for-each-ref",
+// getTags lists the tags of the repo in dir. Annotated tags are peeled to the commit they point to.
+func (m *RepositoryModule) getTags(ctx context.Context, dir string) ([]Tag, error) {
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref",
 		"--sort=-creatordate",
-		"--format=%(refname:short)%09%(taggerdate:iso8601)%09%(taggername)",
+		"--format=%(refname:short)%09%(taggerdate:iso8601)%09%(taggername)%09%(if)%(*objectname)%(then)%(*objectname)%(else)%(objectname)%(end)",
 		"refs/tags")
-	output, err := cmd.Output()
+	cmd.Dir = dir
+	output, err := runGit(cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -178,11 +211,16 @@ func (m *RepositoryModule) getTags() ([]Tag, error) {
 
 	for _, line := range lines {
 		parts := strings.Split(line, "\t")
-		if len(parts) < 3 {
+		commit := ""
+		if len(parts) > 3 {
+			commit = strings.TrimSpace(parts[3])
+		}
+		if len(parts) < 3 || strings.TrimSpace(parts[1]) == "" {
 			// Try getting commit info for lightweight tags
 			tagName := strings.TrimSpace(parts[0])
-			tagCmd := exec.Command("git", "show", "-s", "--format=%aI%n%an", tagName)
-			tagInfo, err := tagCmd.Output()
+			tagCmd := exec.CommandContext(ctx, "git", "show", "-s", "--format=%aI%n%an", tagName)
+			tagCmd.Dir = dir
+			tagInfo, err := runGit(tagCmd)
 			if err != nil {
 				continue
 			}
@@ -196,6 +234,7 @@ func (m *RepositoryModule) getTags() ([]Tag, error) {
 					Name:   tagName,
 					Date:   date,
 					Author: tagLines[1],
+					Commit: commit,
 				})
 			}
 			continue
@@ -210,17 +249,20 @@ func (m *RepositoryModule) getTags() ([]Tag, error) {
 			Name:   strings.TrimSpace(parts[0]),
 			Date:   date,
 			Author: strings.TrimSpace(parts[2]),
+			Commit: commit,
 		})
 	}
 
 	return tags, nil
 }
 
-func (m *RepositoryModule) getCommitHistory(months int) ([]Commit, error) {
+// getCommitHistory lists the commits of the repo in dir made in the last months
+func (m *RepositoryModule) getCommitHistory(ctx context.Context, dir string, months int) ([]Commit, error) {
 	since := time.Now().AddDate(0, -months, 0).Format("2006-01-02")
 	format := "--format=%H%n%an%n%aI%n%s%n--COMMIT--"
-	cmd := exec.Command("git", "log", fmt.Sprintf("--since=%s", since), format)
-	output, err := cmd.Output()
+	cmd := exec.CommandContext(ctx, "git", "log", fmt.Sprintf("--since=%s", since), format)
+	cmd.Dir = dir
+	output, err := runGit(cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -254,15 +296,16 @@ func (m *RepositoryModule) getCommitHistory(months int) ([]Commit, error) {
 	return commits, nil
 }
 
-func (m *RepositoryModule) getReleaseHistory(months int) ([]Release, error) {
+func (m *RepositoryModule) getReleaseHistory(ctx context.Context, dir string, months int) ([]Release, error) {
 	since := time.Now().AddDate(0, -months, 0).Format("2006-01-02")
-	cmd := exec.Command("git", "for-each-ref",
+	cmd := exec.CommandContext(ctx, "git", "for-each-ref",
 		"--sort=-creatordate",
 		"--format=%(refname:short)%09%(creatordate:iso8601)%09%(subject)%09%(taggername)%(authorname)",
 		"refs/tags",
 		fmt.Sprintf("--since=%s", since))
+	cmd.Dir = dir
 
-	output, err := cmd.Output()
+	output, err := runGit(cmd)
 	if err != nil {
 		return nil, err
 	}
@@ -303,8 +346,9 @@ func (m *RepositoryModule) getReleaseHistory(months int) ([]Release, error) {
 		tagName := strings.TrimSpace(parts[0])
 
 		// Get detailed tag info using git show
-		tagCmd := exec.Command("git", "show", "--format=%aI%n%an", tagName)
-		tagInfo, err := tagCmd.Output()
+		tagCmd := exec.CommandContext(ctx, "git", "show", "--format=%aI%n%an", tagName)
+		tagCmd.Dir = dir
+		tagInfo, err := runGit(tagCmd)
 		if err == nil {
 			tagLines := strings.Split(strings.TrimSpace(string(tagInfo)), "\n")
 			if len(tagLines) >= 2 {
@@ -404,9 +448,9 @@ func (m *RepositoryModule) parseDockerPorts(dockerfilePath string) ([]string, er
 	return ports, nil
 }
 
-func (m *RepositoryModule) detectDependencies(result *AnalysisResult) error {
+func (m *RepositoryModule) detectDependencies(repoPath string, result *AnalysisResult) error {
 	// Check for go.mod
-	if data, err := os.ReadFile("go.mod"); err == nil {
+	if data, err := os.ReadFile(filepath.Join(repoPath, "go.mod")); err == nil {
 		result.Dependencies.Language = "Go"
 		lines := strings.Split(string(data), "\n")
 		if len(lines) > 0 {
@@ -418,7 +462,7 @@ func (m *RepositoryModule) detectDependencies(result *AnalysisResult) error {
 	}
 
 	// Check for package.json
-	if data, err := os.ReadFile("package.json"); err == nil {
+	if data, err := os.ReadFile(filepath.Join(repoPath, "package.json")); err == nil {
 		var pkg struct {
 			Dependencies    map[string]string `json:"dependencies"`
 			DevDependencies map[string]string `json:"devDependencies"`
@@ -436,13 +480,14 @@ func (m *RepositoryModule) detectDependencies(result *AnalysisResult) error {
 	return nil
 }
 
-func (m *RepositoryModule) detectDocumentation(result *DocumentationInfo) error {
+func (m *RepositoryModule) detectDocumentation(repoPath string, result *DocumentationInfo) error {
 	// Check for README files
 	readmePatterns := []string{"README.md", "README.txt", "README"}
 	for _, pattern := range readmePatterns {
-		if _, err := os.Stat(pattern); err == nil {
+		readme := filepath.Join(repoPath, pattern)
+		if _, err := os.Stat(readme); err == nil {
 			result.Available = true
-			if data, err := os.ReadFile(pattern); err == nil {
+			if data, err := os.ReadFile(readme); err == nil {
 				result.Summary = string(data)
 			}
 			break
@@ -455,7 +500,7 @@ func (m *RepositoryModule) detectDocumentation(result *DocumentationInfo) error
 		"swagger.json", "swagger.yaml", "openapi.json", "openapi.yaml",
 	}
 	for _, pattern := range apiDocPatterns {
-		if _, err := os.Stat(pattern); err == nil {
+		if _, err := os.Stat(filepath.Join(repoPath, pattern)); err == nil {
 			result.API = true
 			break
 		}
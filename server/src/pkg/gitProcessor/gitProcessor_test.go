@@ -0,0 +1,106 @@
+package gitProcessor
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+// newRepo commits files to a new git repo and returns its path
+func newRepo(t *testing.T, branch string, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{
+		{"init", "-q", "-b", branch},
+		{"add", "."},
+		{"-c", "user.name=Ana", "-c", "user.email=ana@pismo.io", "commit", "-q", "-m", "first"},
+		{"-c", "user.name=Ana", "-c", "user.email=ana@pismo.io", "tag", "-a", "1.0.0", "-m", "1.0.0"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, output)
+		}
+	}
+	return dir
+}
+
+func TestExtractReadsEachRepoWithoutChangingDirectory(t *testing.T) {
+	repos := map[string]string{
+		"go-service": newRepo(t, "main", map[string]string{
+			"go.mod":     "module example.com/go-service 1.22\n",
+			"Dockerfile": "FROM golang\nEXPOSE 8080\n",
+			"README.md":  "# go-service",
+		}),
+		"node-service": newRepo(t, "develop", map[string]string{
+			"package.json": `{"dependencies":{"express":"4.18.0"}}`,
+		}),
+	}
+	workingDir, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	module, _ := NewRepositoryModule(Options{CommitHistoryMonths: 1, ReleaseHistoryMonths: 1})
+	results := make(map[string]AnalysisResult)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		for name, path := range repos {
+			wg.Add(1)
+			go func(name, path string) {
+				defer wg.Done()
+				data, err := module.Extract(context.Background(), path)
+				if err != nil {
+					t.Errorf("Extract %s: %v", name, err)
+					return
+				}
+				var result AnalysisResult
+				if err := json.Unmarshal(data, &result); err != nil {
+					t.Errorf("decoding %s: %v", name, err)
+					return
+				}
+				mu.Lock()
+				results[name] = result
+				mu.Unlock()
+			}(name, path)
+		}
+	}
+	wg.Wait()
+
+	if dir, _ := os.Getwd(); dir != workingDir {
+		t.Errorf("working directory changed to %s", dir)
+	}
+
+	goService := results["go-service"]
+	if goService.Repository.Branch != "main" || len(goService.Repository.Tags) != 1 || len(goService.Repository.CommitHistory) != 1 {
+		t.Errorf("go-service repository = %+v", goService.Repository)
+	}
+	if goService.Dependencies.Language != "Go" || !goService.Build.Docker.Enabled || !goService.Documentation.Available {
+		t.Errorf("go-service = %+v", goService)
+	}
+
+	nodeService := results["node-service"]
+	if nodeService.Repository.Branch != "develop" || nodeService.Dependencies.Language != "JavaScript/Node.js" || nodeService.Build.Docker.Enabled {
+		t.Errorf("node-service = %+v", nodeService)
+	}
+}
+
+func TestExtractFailsOnMissingRepo(t *testing.T) {
+	module, _ := NewRepositoryModule(Options{})
+	if _, err := module.Extract(context.Background(), filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("Extract of a missing directory succeeded")
+	}
+}
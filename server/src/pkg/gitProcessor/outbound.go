@@ -0,0 +1,320 @@
+package gitProcessor
+
+import (
+	"bufio"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Outbound kinds
+const (
+	OutboundHTTP    = "http"
+	OutboundGraphQL = "graphql"
+	OutboundSNS     = "sns"
+	OutboundSQS     = "sqs"
+)
+
+// skippedDirs hold vendored, generated or test code, whose targets are not
+// the repo's own dependencies
+var skippedDirs = map[string]bool{
+	".git":         true,
+	"vendor":       true,
+	"node_modules": true,
+	"testdata":     true,
+	"mock":         true,
+	"mocks":        true,
+}
+
+// ignoredHosts are found in code without being called, such as the hosts
+// of doc links and XML namespaces
+var ignoredHosts = map[string]bool{
+	"localhost":           true,
+	"0.0.0.0":             true,
+	"github.com":          true,
+	"golang.org":          true,
+	"go.dev":              true,
+	"pkg.go.dev":          true,
+	"swagger.io":          true,
+	"www.w3.org":          true,
+	"json-schema.org":     true,
+	"opentelemetry.io":    true,
+	"schemas.xmlsoap.org": true,
+}
+
+var (
+	envVarPattern   = regexp.MustCompile(`^[A-Z][A-Z0-9_]*[A-Z0-9]$`)
+	sqsHostPattern  = regexp.MustCompile(`^sqs\.([a-z0-9-]+)\.amazonaws\.com$`)
+	configMapKind   = regexp.MustCompile(`(?m)^kind:\s*ConfigMap\s*$`)
+	configMapDatum  = regexp.MustCompile(`^([A-Za-z0-9_.-]+):\s*(.+)$`)
+	templatedTarget = regexp.MustCompile(`[{}$%<>]`)
+)
+
+// OutboundDependencies statically finds the HTTP hosts, GraphQL upstreams
+// and SNS topics and SQS queues the repo at repoPath depends on: in string
+// literals of its Go code, with the env var of constants such as
+// DefaultSnsConsoleAudit or of calls like env.Get("KEY", "https://..."),
+// and in the data of its Kubernetes and Helm ConfigMaps. Tests, mocks,
+// vendored and generated code are skipped.
+func (m *RepositoryModule) OutboundDependencies(repoPath string) ([]Outbound, error) {
+	var goFiles, yamlFiles []string
+	err := filepath.WalkDir(repoPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if path != repoPath && skippedDirs[entry.Name()] {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch name := entry.Name(); {
+		case strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go"):
+			goFiles = append(goFiles, path)
+		case strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml"):
+			yamlFiles = append(yamlFiles, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to walk repository: %v", err)
+	}
+
+	found := &outboundSet{byKey: make(map[string]*Outbound)}
+	scanGoFiles(repoPath, goFiles, found)
+	for _, path := range yamlFiles {
+		scanConfigMap(repoPath, path, found)
+	}
+	return found.list(), nil
+}
+
+// scanGoFiles parses every file first, as the env var of a default is often
+// a constant of another package
+func scanGoFiles(repoPath string, paths []string, found *outboundSet) {
+	fset := token.NewFileSet()
+	var files []*ast.File
+	envVars := make(map[string]string)
+	for _, path := range paths {
+		file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+		if err != nil || ast.IsGenerated(file) {
+			continue
+		}
+		files = append(files, file)
+		for _, spec := range valueSpecs(file) {
+			for i, name := range spec.Names {
+				if i < len(spec.Values) {
+					if value, ok := stringLiteral(spec.Values[i]); ok && envVarPattern.MatchString(value) {
+						envVars[name.Name] = value
+					}
+				}
+			}
+		}
+	}
+
+	for _, file := range files {
+		seen := make(map[*ast.BasicLit]bool)
+		record := func(lit *ast.BasicLit, envVar string) {
+			seen[lit] = true
+			value, _ := stringLiteral(lit)
+			position := fset.Position(lit.Pos())
+			found.add(value, envVar, source(repoPath, position.Filename, position.Line))
+		}
+		ast.Inspect(file, func(node ast.Node) bool {
+			switch n := node.(type) {
+			case *ast.ValueSpec:
+				// DefaultSnsConsoleAudit = "arn:..." is the default of SnsConsoleAudit = "SNS_CONSOLE_AUDIT"
+				for i, value := range n.Values {
+					if lit, ok := value.(*ast.BasicLit); ok && lit.Kind == token.STRING && i < len(n.Names) {
+						record(lit, envVars[strings.TrimPrefix(n.Names[i].Name, "Default")])
+					}
+				}
+			case *ast.CallExpr:
+				// env.Get("CONSOLE_AUDIT_API_URL", "https://...") and the like
+				if len(n.Args) == 2 {
+					if lit, ok := n.Args[1].(*ast.BasicLit); ok && lit.Kind == token.STRING {
+						record(lit, envVarOf(n.Args[0], envVars))
+					}
+				}
+			case *ast.BasicLit:
+				if n.Kind == token.STRING && !seen[n] {
+					record(n, "")
+				}
+			}
+			return true
+		})
+	}
+}
+
+func valueSpecs(file *ast.File) []*ast.ValueSpec {
+	var specs []*ast.ValueSpec
+	for _, decl := range file.Decls {
+		if gen, ok := decl.(*ast.GenDecl); ok {
+			for _, spec := range gen.Specs {
+				if value, ok := spec.(*ast.ValueSpec); ok {
+					specs = append(specs, value)
+				}
+			}
+		}
+	}
+	return specs
+}
+
+func stringLiteral(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	value, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return "", false
+	}
+	return value, true
+}
+
+// envVarOf returns the env var an expression names, as a literal or as a
+// constant such as env.DisputesApiURL
+func envVarOf(expr ast.Expr, envVars map[string]string) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return envVars[e.Name]
+	case *ast.SelectorExpr:
+		return envVars[e.Sel.Name]
+	}
+	if value, ok := stringLiteral(expr); ok && envVarPattern.MatchString(value) {
+		return value
+	}
+	return ""
+}
+
+// scanConfigMap reads the data of a ConfigMap manifest line by line, as Helm
+// templates are not YAML until rendered
+func scanConfigMap(repoPath, path string, found *outboundSet) {
+	data, err := os.ReadFile(path)
+	if err != nil || !configMapKind.Match(data) {
+		return
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(text, "#") {
+			continue
+		}
+		match := configMapDatum.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+		value := strings.Trim(strings.TrimSpace(match[2]), `"'`)
+		envVar := ""
+		if envVarPattern.MatchString(match[1]) {
+			envVar = match[1]
+		}
+		found.add(value, envVar, source(repoPath, path, line))
+	}
+}
+
+func source(repoPath, path string, line int) string {
+	if rel, err := filepath.Rel(repoPath, path); err == nil {
+		path = rel
+	}
+	return fmt.Sprintf("%s:%d", filepath.ToSlash(path), line)
+}
+
+// classify returns the dependency a string value points to, if any
+func classify(value string) (Outbound, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" || strings.ContainsAny(value, " \t\n") {
+		return Outbound{}, false
+	}
+
+	if strings.HasPrefix(value, "arn:aws:sns:") || strings.HasPrefix(value, "arn:aws:sqs:") {
+		// arn:aws:sns:<region>:<account>:<name>
+		parts := strings.Split(value, ":")
+		if len(parts) != 6 || parts[5] == "" || templatedTarget.MatchString(value) {
+			return Outbound{}, false
+		}
+		return Outbound{Kind: parts[2], Target: value, Name: parts[5]}, true
+	}
+
+	target, err := url.Parse(value)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return Outbound{}, false
+	}
+	host := strings.ToLower(target.Hostname())
+	if host == "" || ignoredHosts[host] || strings.HasPrefix(host, "127.") ||
+		strings.HasPrefix(host, "example.") || templatedTarget.MatchString(value) {
+		return Outbound{}, false
+	}
+
+	// https://sqs.<region>.amazonaws.com/<account>/<name>
+	if region := sqsHostPattern.FindStringSubmatch(host); region != nil {
+		parts := strings.Split(strings.Trim(target.Path, "/"), "/")
+		if len(parts) != 2 {
+			return Outbound{}, false
+		}
+		return Outbound{Kind: OutboundSQS, Target: "arn:aws:sqs:" + region[1] + ":" + parts[0] + ":" + parts[1], Name: parts[1]}, true
+	}
+
+	if strings.Contains(strings.ToLower(target.Path), "graphql") {
+		return Outbound{Kind: OutboundGraphQL, Target: target.Scheme + "://" + target.Host + target.Path, Name: host}, true
+	}
+	return Outbound{Kind: OutboundHTTP, Target: host, Name: host}, true
+}
+
+// outboundSet merges the places the same target is found
+type outboundSet struct {
+	byKey map[string]*Outbound
+}
+
+func (s *outboundSet) add(value, envVar, source string) {
+	outbound, ok := classify(value)
+	if !ok {
+		return
+	}
+	key := outbound.Kind + " " + outbound.Target
+	existing := s.byKey[key]
+	if existing == nil {
+		existing = &outbound
+		s.byKey[key] = existing
+	}
+	if envVar != "" && !contains(existing.EnvVars, envVar) {
+		existing.EnvVars = append(existing.EnvVars, envVar)
+	}
+	if !contains(existing.Sources, source) {
+		existing.Sources = append(existing.Sources, source)
+	}
+}
+
+func (s *outboundSet) list() []Outbound {
+	list := make([]Outbound, 0, len(s.byKey))
+	for _, outbound := range s.byKey {
+		sort.Strings(outbound.EnvVars)
+		sort.Strings(outbound.Sources)
+		list = append(list, *outbound)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].Target < list[j].Target
+	})
+	return list
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,104 @@
+package gitProcessor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	cases := []struct {
+		value string
+		want  Outbound
+		ok    bool
+	}{
+		{"https://console-audit-api.pismolabs.io/v1/audits", Outbound{Kind: OutboundHTTP, Target: "console-audit-api.pismolabs.io", Name: "console-audit-api.pismolabs.io"}, true},
+		{" http://Accounts-Ext.pismolabs.io:8080 ", Outbound{Kind: OutboundHTTP, Target: "accounts-ext.pismolabs.io", Name: "accounts-ext.pismolabs.io"}, true},
+		{"https://backoffice-core-bff.pismolabs.io/graphql?op=x", Outbound{Kind: OutboundGraphQL, Target: "https://backoffice-core-bff.pismolabs.io/graphql", Name: "backoffice-core-bff.pismolabs.io"}, true},
+		{"arn:aws:sns:sa-east-1:123456789012:console-audit", Outbound{Kind: OutboundSNS, Target: "arn:aws:sns:sa-east-1:123456789012:console-audit", Name: "console-audit"}, true},
+		{"https://sqs.sa-east-1.amazonaws.com/123456789012/disputes", Outbound{Kind: OutboundSQS, Target: "arn:aws:sqs:sa-east-1:123456789012:disputes", Name: "disputes"}, true},
+
+		// Not dependencies
+		{"", Outbound{}, false},
+		{"CONSOLE_AUDIT_API_URL", Outbound{}, false},
+		{"see https://pismolabs.io", Outbound{}, false},
+		{"ftp://files.pismolabs.io", Outbound{}, false},
+		{"https://github.com/pismo/backoffice-core-bff", Outbound{}, false},
+		{"http://localhost:8080", Outbound{}, false},
+		{"http://127.0.0.1:9000", Outbound{}, false},
+		{"https://example.com/api", Outbound{}, false},
+		{"https://%s.pismolabs.io", Outbound{}, false},
+		{"https://{{ .Values.host }}/api", Outbound{}, false},
+		{"arn:aws:sns:sa-east-1:${ACCOUNT}:console-audit", Outbound{}, false},
+		{"arn:aws:sns:sa-east-1:123456789012", Outbound{}, false},
+		{"https://sqs.sa-east-1.amazonaws.com/disputes", Outbound{}, false},
+	}
+	for _, c := range cases {
+		got, ok := classify(c.value)
+		if ok != c.ok || !reflect.DeepEqual(got, c.want) {
+			t.Errorf("classify(%q) = %+v, %v, want %+v, %v", c.value, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestOutboundDependencies(t *testing.T) {
+	repo := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(repo, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("internal/env/env.go", `package env
+
+const (
+	SnsConsoleAudit        = "SNS_CONSOLE_AUDIT"
+	DefaultSnsConsoleAudit = "arn:aws:sns:sa-east-1:123456789012:console-audit"
+	AccountsURL            = "ACCOUNTS_URL"
+)
+`)
+	write("internal/client/client.go", `package client
+
+var accounts = env.Get(env.AccountsURL, "https://accounts-ext.pismolabs.io/v1")
+`)
+	write("internal/client/client_test.go", `package client
+
+var stub = "https://stub.pismolabs.io"
+`)
+	write("vendor/lib/lib.go", `package lib
+
+var vendored = "https://vendored.pismolabs.io"
+`)
+	write("deploy/configmap.yaml", `kind: ConfigMap
+data:
+  # DISPUTES_QUEUE: https://sqs.sa-east-1.amazonaws.com/123456789012/old
+  DISPUTES_QUEUE: "https://sqs.sa-east-1.amazonaws.com/123456789012/disputes"
+  ACCOUNTS_URL: https://accounts-ext.pismolabs.io/v2
+`)
+
+	module, err := NewRepositoryModule(Options{})
+	if err != nil {
+		t.Fatalf("NewRepositoryModule: %v", err)
+	}
+	outbounds, err := module.OutboundDependencies(repo)
+	if err != nil {
+		t.Fatalf("OutboundDependencies: %v", err)
+	}
+
+	want := []Outbound{
+		{Kind: OutboundHTTP, Target: "accounts-ext.pismolabs.io", Name: "accounts-ext.pismolabs.io",
+			EnvVars: []string{"ACCOUNTS_URL"}, Sources: []string{"deploy/configmap.yaml:5", "internal/client/client.go:3"}},
+		{Kind: OutboundSNS, Target: "arn:aws:sns:sa-east-1:123456789012:console-audit", Name: "console-audit",
+			EnvVars: []string{"SNS_CONSOLE_AUDIT"}, Sources: []string{"internal/env/env.go:5"}},
+		{Kind: OutboundSQS, Target: "arn:aws:sqs:sa-east-1:123456789012:disputes", Name: "disputes",
+			EnvVars: []string{"DISPUTES_QUEUE"}, Sources: []string{"deploy/configmap.yaml:4"}},
+	}
+	if !reflect.DeepEqual(outbounds, want) {
+		t.Errorf("outbounds =\n%+v\nwant\n%+v", outbounds, want)
+	}
+}
@@ -26,6 +26,7 @@ type Tag struct {
 	Name   string    `json:"name"`
 	Date   time.Time `json:"date"`
 	Author string    `json:"author"`
+	Commit string    `json:"commit,omitempty"`
 }
 
 type Repository struct {
@@ -77,3 +78,18 @@ type AnalysisResult struct {
 	Timestamp     string            `json:"timestamp"`
 	Tag           string            `json:"tag"`
 }
+
+// Outbound is a service, topic or queue the repo's code or ConfigMaps point to
+type Outbound struct {
+	Kind string `json:"kind" enum:"http,graphql,sns,sqs"`
+	// Target is the host of an HTTP dependency, the URL of a GraphQL one and
+	// the ARN of a topic or queue
+	Target string `json:"target"`
+	// Name is the host, or the name of the topic or queue, which is the same
+	// in every account
+	Name string `json:"name"`
+	// EnvVars are the env vars the target is the default or ConfigMap value of
+	EnvVars []string `json:"envVars,omitempty"`
+	// Sources are where the target was found, as file:line
+	Sources []string `json:"sources"`
+}
@@ -0,0 +1,293 @@
+// pkg/grafana/client.go
+package grafana
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default PromQL and LogQL templates. $container, $env, $region, $namespace
+// and $version are replaced with the target's labels, like Grafana variables.
+var DefaultQueries = map[string]string{
+	"errorRate":  `sum(rate(http_server_requests_seconds_count{container="$container",env="$env",version="$version",status=~"5.."}[5m])) / sum(rate(http_server_requests_seconds_count{container="$container",env="$env",version="$version"}[5m]))`,
+	"latencyP95": `histogram_quantile(0.95, sum by (le) (rate(http_server_requests_seconds_bucket{container="$container",env="$env",version="$version"}[5m])))`,
+	"restarts":   `sum(increase(kube_pod_container_status_restarts_total{container="$container",namespace="$namespace"}[5m]))`,
+}
+
+const DefaultLogQuery = `{container="$container", env="$env", version="$version"} |~ "(?i)(error|panic|fatal)"`
+
+type Config struct {
+	// PrometheusURL and LokiURL are the base URLs of Prometheus and Loki
+	// compatible HTTP APIs (Grafana Cloud, Mimir, Thanos, or a stand-in server)
+	PrometheusURL string
+	LokiURL       string
+	// ExploreURL is the Grafana instance used for Explore links
+	ExploreURL       string
+	LokiDatasourceID string
+	// User and Token are sent as basic auth (Grafana Cloud); with no User the
+	// token is sent as a bearer token
+	User     string
+	Token    string
+	Queries  map[string]string
+	LogQuery string
+	Window   time.Duration
+	Step     time.Duration
+}
+
+type Client struct {
+	config     Config
+	httpClient *http.Client
+}
+
+func NewClient(config Config) *Client {
+	if config.Queries == nil {
+		config.Queries = DefaultQueries
+	}
+	if config.LogQuery == "" {
+		config.LogQuery = DefaultLogQuery
+	}
+	if config.Window == 0 {
+		config.Window = 24 * time.Hour
+	}
+	if config.Step == 0 {
+		config.Step = time.Hour
+	}
+	if config.LokiDatasourceID == "" {
+		config.LokiDatasourceID = "grafanacloud-logs"
+	}
+	config.PrometheusURL = strings.TrimRight(config.PrometheusURL, "/")
+	config.LokiURL = strings.TrimRight(config.LokiURL, "/")
+	config.ExploreURL = strings.TrimRight(config.ExploreURL, "/")
+
+	return &Client{
+		config:     config,
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// labelEscaper escapes a value for a quoted PromQL or LogQL label matcher
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// expand substitutes the target's labels into a query template. The values
+// are escaped, as the templates quote them inside label matchers.
+func expand(query string, target Target) string {
+	return strings.NewReplacer(
+		"$container", labelEscaper.Replace(target.Container),
+		"$env", labelEscaper.Replace(target.Env),
+		"$region", labelEscaper.Replace(target.Region),
+		"$namespace", labelEscaper.Replace(target.Namespace),
+		"$version", labelEscaper.Replace(target.Version),
+	).Replace(query)
+}
+
+func (c *Client) get(ctx context.Context, baseURL, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", baseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	if c.config.User != "" {
+		req.SetBasicAuth(c.config.User, c.config.Token)
+	} else if c.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("error: received status code %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error parsing response: %v", err)
+	}
+	return nil
+}
+
+// Sparklines runs every configured PromQL query over the window. A query
+// that fails is reported in AppMetrics.Errors without failing the others.
+func (c *Client) Sparklines(ctx context.Context, target Target) (*AppMetrics, error) {
+	if c.config.PrometheusURL == "" {
+		return nil, fmt.Errorf("no prometheus url configured")
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-c.config.Window)
+	metrics := &AppMetrics{
+		Target:     target,
+		From:       from,
+		To:         to,
+		Series:     []Series{},
+		ExploreURL: c.ExploreURL(target, strconv.FormatInt(from.UnixMilli(), 10), strconv.FormatInt(to.UnixMilli(), 10)),
+	}
+
+	names := make([]string, 0, len(c.config.Queries))
+	for name := range c.config.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		query := expand(c.config.Queries[name], target)
+		points, err := c.queryRange(ctx, query, from, to)
+		if err != nil {
+			metrics.Errors = append(metrics.Errors, fmt.Sprintf("%s: %v", name, err))
+			continue
+		}
+		metrics.Series = append(metrics.Series, Series{Name: name, Query: query, Points: points})
+	}
+
+	return metrics, nil
+}
+
+func (c *Client) queryRange(ctx context.Context, query string, from, to time.Time) ([]Point, error) {
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(from.Unix(), 10))
+	params.Set("end", strconv.FormatInt(to.Unix(), 10))
+	params.Set("step", strconv.Itoa(int(c.config.Step.Seconds())))
+
+	var response struct {
+		Status string `json:"status"`
+		Error  string `json:"error"`
+		Data   struct {
+			Result []struct {
+				Values [][2]interface{} `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := c.get(ctx, c.config.PrometheusURL, "/api/v1/query_range", params, &response); err != nil {
+		return nil, err
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("query failed: %s", response.Error)
+	}
+
+	// The queries aggregate to a single series; take the first one
+	points := []Point{}
+	if len(response.Data.Result) == 0 {
+		return points, nil
+	}
+	for _, value := range response.Data.Result[0].Values {
+		timestamp, ok := value[0].(float64)
+		if !ok {
+			continue
+		}
+		raw, ok := value[1].(string)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			continue
+		}
+		points = append(points, Point{
+			Time:  time.Unix(0, int64(timestamp*float64(time.Second))).UTC(),
+			Value: parsed,
+		})
+	}
+	return points, nil
+}
+
+// ErrorLogs returns the most recent error lines for the target, newest first,
+// along with the lines grouped by message
+func (c *Client) ErrorLogs(ctx context.Context, target Target, limit int) (*AppLogs, error) {
+	if c.config.LokiURL == "" {
+		return nil, fmt.Errorf("no loki url configured")
+	}
+
+	to := time.Now().UTC()
+	from := to.Add(-c.config.Window)
+	query := expand(c.config.LogQuery, target)
+
+	params := url.Values{}
+	params.Set("query", query)
+	params.Set("start", strconv.FormatInt(from.UnixNano(), 10))
+	params.Set("end", strconv.FormatInt(to.UnixNano(), 10))
+	params.Set("limit", strconv.Itoa(limit))
+	params.Set("direction", "backward")
+
+	var response struct {
+		Status string `json:"status"`
+		Data   struct {
+			Result []struct {
+				Stream map[string]string `json:"stream"`
+				Values [][2]string       `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := c.get(ctx, c.config.LokiURL, "/loki/api/v1/query_range", params, &response); err != nil {
+		return nil, err
+	}
+	if response.Status != "success" {
+		return nil, fmt.Errorf("log query failed with status %q", response.Status)
+	}
+
+	logs := &AppLogs{
+		Target:     target,
+		Lines:      []LogLine{},
+		Errors:     []ErrorSummary{},
+		ExploreURL: c.ExploreURL(target, strconv.FormatInt(from.UnixMilli(), 10), strconv.FormatInt(to.UnixMilli(), 10)),
+	}
+	for _, stream := range response.Data.Result {
+		for _, value := range stream.Values {
+			nanos, err := strconv.ParseInt(value[0], 10, 64)
+			if err != nil {
+				continue
+			}
+			logs.Lines = append(logs.Lines, LogLine{
+				Time:    time.Unix(0, nanos).UTC(),
+				Message: value[1],
+				Labels:  stream.Stream,
+			})
+		}
+	}
+
+	sort.Slice(logs.Lines, func(i, j int) bool { return logs.Lines[i].Time.After(logs.Lines[j].Time) })
+	if limit > 0 && len(logs.Lines) > limit {
+		logs.Lines = logs.Lines[:limit]
+	}
+	logs.Errors = summarise(logs.Lines)
+
+	return logs, nil
+}
+
+func summarise(lines []LogLine) []ErrorSummary {
+	byMessage := make(map[string]*ErrorSummary)
+	var order []string
+	for _, line := range lines {
+		message := strings.TrimSpace(line.Message)
+		summary, exists := byMessage[message]
+		if !exists {
+			summary = &ErrorSummary{Message: message}
+			byMessage[message] = summary
+			order = append(order, message)
+		}
+		summary.Count++
+		if line.Time.After(summary.LastOccurred) {
+			summary.LastOccurred = line.Time
+		}
+	}
+
+	summaries := make([]ErrorSummary, 0, len(order))
+	for _, message := range order {
+		summaries = append(summaries, *byMessage[message])
+	}
+	sort.SliceStable(summaries, func(i, j int) bool { return summaries[i].Count > summaries[j].Count })
+	return summaries
+}
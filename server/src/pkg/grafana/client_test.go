@@ -0,0 +1,119 @@
+package grafana
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// standIn serves the Prometheus and Loki query_range APIs
+func standIn(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		if strings.Contains(query, "broken") {
+			http.Error(w, "parse error", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprintf(w, `{"status":"success","data":{"result":[{"values":[[1700000000,"0.5"],[1700003600,"NaN-ish"],[1700007200,"1.25"]]}]}}`)
+	})
+	mux.HandleFunc("/loki/api/v1/query_range", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("limit"); got != "10" {
+			t.Errorf("limit = %q, want 10", got)
+		}
+		fmt.Fprint(w, `{"status":"success","data":{"result":[
+			{"stream":{"pod":"a"},"values":[["1700000000000000000","timeout calling disputes"],["1700000300000000000","panic: nil map"]]},
+			{"stream":{"pod":"b"},"values":[["1700000600000000000","timeout calling disputes"]]}
+		]}}`)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestSparklinesReportsFailedQueriesWithoutFailingOthers(t *testing.T) {
+	server := standIn(t)
+	client := NewClient(Config{
+		PrometheusURL: server.URL + "/",
+		Queries: map[string]string{
+			"errorRate": `rate(errors{container="$container",version="$version"}[5m])`,
+			"latency":   `broken(`,
+		},
+	})
+
+	metrics, err := client.Sparklines(context.Background(), Target{Container: "backoffice-core-bff", Env: "prod", Version: "1.24.0"})
+	if err != nil {
+		t.Fatalf("Sparklines: %v", err)
+	}
+
+	if len(metrics.Series) != 1 {
+		t.Fatalf("got %d series, want 1", len(metrics.Series))
+	}
+	series := metrics.Series[0]
+	if want := `rate(errors{container="backoffice-core-bff",version="1.24.0"}[5m])`; series.Query != want {
+		t.Errorf("query = %s, want %s", series.Query, want)
+	}
+	// The unparsable sample is skipped
+	if len(series.Points) != 2 || series.Points[0].Value != 0.5 || series.Points[1].Value != 1.25 {
+		t.Errorf("points = %+v", series.Points)
+	}
+	if !series.Points[0].Time.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("first point at %v", series.Points[0].Time)
+	}
+	if len(metrics.Errors) != 1 || !strings.HasPrefix(metrics.Errors[0], "latency: ") {
+		t.Errorf("errors = %v", metrics.Errors)
+	}
+}
+
+func TestErrorLogsSortsNewestFirstAndGroupsMessages(t *testing.T) {
+	server := standIn(t)
+	client := NewClient(Config{LokiURL: server.URL})
+
+	logs, err := client.ErrorLogs(context.Background(), Target{Container: "backoffice-core-bff", Env: "prod"}, 10)
+	if err != nil {
+		t.Fatalf("ErrorLogs: %v", err)
+	}
+
+	if len(logs.Lines) != 3 {
+		t.Fatalf("got %d lines, want 3", len(logs.Lines))
+	}
+	for i := 1; i < len(logs.Lines); i++ {
+		if logs.Lines[i].Time.After(logs.Lines[i-1].Time) {
+			t.Errorf("lines not newest first: %+v", logs.Lines)
+		}
+	}
+	if logs.Lines[0].Labels["pod"] != "b" {
+		t.Errorf("newest line from pod %q, want b", logs.Lines[0].Labels["pod"])
+	}
+
+	if len(logs.Errors) != 2 {
+		t.Fatalf("got %d error groups, want 2", len(logs.Errors))
+	}
+	top := logs.Errors[0]
+	if top.Message != "timeout calling disputes" || top.Count != 2 || !top.LastOccurred.Equal(time.Unix(1700000600, 0)) {
+		t.Errorf("top error = %+v", top)
+	}
+}
+
+func TestClientsWithoutURLsFail(t *testing.T) {
+	client := NewClient(Config{})
+	if _, err := client.Sparklines(context.Background(), Target{}); err == nil {
+		t.Error("Sparklines without a Prometheus URL succeeded")
+	}
+	if _, err := client.ErrorLogs(context.Background(), Target{}, 10); err == nil {
+		t.Error("ErrorLogs without a Loki URL succeeded")
+	}
+}
+
+func TestExpandEscapesLabelValues(t *testing.T) {
+	query := expand(DefaultLogQuery, Target{Container: "backoffice-core-bff", Env: `prod"} or {container=~".+`, Version: `1.24.0\`})
+	want := `{container="backoffice-core-bff", env="prod\"} or {container=~\".+", version="1.24.0\\"} |~ "(?i)(error|panic|fatal)"`
+	if query != want {
+		t.Errorf("query = %s, want %s", query, want)
+	}
+}
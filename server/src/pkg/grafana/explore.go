@@ -0,0 +1,51 @@
+// pkg/grafana/explore.go
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ExploreURL links to Grafana Explore showing the target's logs between from
+// and to, which are Grafana time expressions ("now-7d") or epoch milliseconds
+func (c *Client) ExploreURL(target Target, from, to string) string {
+	panes := map[string]interface{}{
+		"jqe": map[string]interface{}{
+			"datasource": c.config.LokiDatasourceID,
+			"queries": []map[string]interface{}{
+				{
+					"refId":      "A",
+					"expr":       StreamSelector(target),
+					"queryType":  "range",
+					"datasource": map[string]string{"type": "loki", "uid": c.config.LokiDatasourceID},
+					"editorMode": "code",
+				},
+			},
+			"range": map[string]string{"from": from, "to": to},
+		},
+	}
+
+	panesJSON, err := json.Marshal(panes)
+	if err != nil {
+		return c.config.ExploreURL + "/explore"
+	}
+
+	return fmt.Sprintf("%s/explore?schemaVersion=1&panes=%s&orgId=1", c.config.ExploreURL, url.QueryEscape(string(panesJSON)))
+}
+
+// StreamSelector builds the LogQL selector for the target, leaving out labels we don't know
+func StreamSelector(target Target) string {
+	var labels []string
+	for _, label := range []struct{ name, value string }{
+		{"container", target.Container},
+		{"env", target.Env},
+		{"version", target.Version},
+	} {
+		if label.value != "" {
+			labels = append(labels, fmt.Sprintf("%s=%q", label.name, label.value))
+		}
+	}
+	return "{" + strings.Join(labels, ", ") + "}"
+}
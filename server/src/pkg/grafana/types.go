@@ -0,0 +1,53 @@
+// pkg/grafana/types.go
+package grafana
+
+import "time"
+
+// Target identifies one deployed app in the metrics and log labels
+type Target struct {
+	Container string `json:"container"`
+	Env       string `json:"env"`
+	Region    string `json:"region,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	Version   string `json:"version,omitempty"`
+}
+
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+type Series struct {
+	Name   string  `json:"name"`
+	Query  string  `json:"query"`
+	Points []Point `json:"points"`
+}
+
+type LogLine struct {
+	Time    time.Time         `json:"time"`
+	Message string            `json:"message"`
+	Labels  map[string]string `json:"labels,omitempty"`
+}
+
+// ErrorSummary groups identical error log lines, as shown on the error-logs card
+type ErrorSummary struct {
+	Message      string    `json:"message"`
+	Count        int       `json:"count"`
+	LastOccurred time.Time `json:"lastOccurred"`
+}
+
+type AppMetrics struct {
+	Target     Target    `json:"target"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Series     []Series  `json:"series"`
+	ExploreURL string    `json:"exploreUrl"`
+	Errors     []string  `json:"errors,omitempty"`
+}
+
+type AppLogs struct {
+	Target     Target         `json:"target"`
+	Lines      []LogLine      `json:"lines"`
+	Errors     []ErrorSummary `json:"errors"`
+	ExploreURL string         `json:"exploreUrl"`
+}
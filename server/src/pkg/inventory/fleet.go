@@ -0,0 +1,127 @@
+// pkg/inventory/fleet.go
+package inventory
+
+import (
+	"argocd/pkg/registry"
+	"argocd/pkg/version"
+	"sort"
+)
+
+// Usage is one app running an image
+type Usage struct {
+	Repo      string `json:"repo"`
+	App       string `json:"app"`
+	Container string `json:"container,omitempty"`
+}
+
+// ImageVersions is every version of one image repository running in the
+// fleet. Repositories are compared without the registry host, so the regional
+// replicas of an ECR repository count as one.
+type ImageVersions struct {
+	Repository string `json:"repository"`
+	// Latest is the newest release version running anywhere; it is empty when
+	// no tag of the repository is a release version
+	Latest   string         `json:"latest,omitempty"`
+	Versions []VersionUsage `json:"versions"`
+	// Outdated lists the repos running a release older than Latest
+	Outdated []string `json:"outdated,omitempty"`
+}
+
+// VersionUsage is one tag of an image repository and the apps running it
+type VersionUsage struct {
+	Version string `json:"version"`
+	// Outdated is set for release versions older than the repository's
+	// Latest; tags such as "latest" or commit hashes are never outdated
+	Outdated bool    `json:"outdated"`
+	Apps     []Usage `json:"apps"`
+}
+
+// Fleet collects the containers of a role across apps and groups them by
+// image repository and version
+type Fleet struct {
+	role   string
+	byRepo map[string]map[string][]Usage
+}
+
+func NewFleet(role string) *Fleet {
+	return &Fleet{role: role, byRepo: make(map[string]map[string][]Usage)}
+}
+
+// Add records the containers of the role run by one app
+func (f *Fleet) Add(repo, app string, containers []Container) {
+	for _, container := range containers {
+		if container.Role != f.role {
+			continue
+		}
+		ref, err := registry.ParseReference(container.Image)
+		if err != nil {
+			continue
+		}
+		tag := ref.Tag
+		if tag == "" {
+			tag = ref.Digest
+		}
+		if f.byRepo[ref.Repository] == nil {
+			f.byRepo[ref.Repository] = make(map[string][]Usage)
+		}
+		f.byRepo[ref.Repository][tag] = append(f.byRepo[ref.Repository][tag], Usage{Repo: repo, App: app, Container: container.Name})
+	}
+}
+
+// Images returns the image repositories, those with outdated versions first
+func (f *Fleet) Images() []ImageVersions {
+	images := make([]ImageVersions, 0, len(f.byRepo))
+	for repository, byTag := range f.byRepo {
+		image := ImageVersions{Repository: repository}
+
+		var latest version.Version
+		hasLatest := false
+		for tag := range byTag {
+			if v, ok := version.Parse(tag); ok && (!hasLatest || version.Compare(v, latest) > 0) {
+				latest, hasLatest = v, true
+				image.Latest = tag
+			}
+		}
+
+		outdatedRepos := make(map[string]bool)
+		for tag, usages := range byTag {
+			usage := VersionUsage{Version: tag, Apps: usages}
+			if v, ok := version.Parse(tag); ok && hasLatest && version.Compare(v, latest) < 0 {
+				usage.Outdated = true
+				for _, app := range usages {
+					outdatedRepos[app.Repo] = true
+				}
+			}
+			sort.Slice(usage.Apps, func(i, j int) bool { return usage.Apps[i].App < usage.Apps[j].App })
+			image.Versions = append(image.Versions, usage)
+		}
+		sort.Slice(image.Versions, func(i, j int) bool { return olderTag(image.Versions[i].Version, image.Versions[j].Version) })
+
+		for repo := range outdatedRepos {
+			image.Outdated = append(image.Outdated, repo)
+		}
+		sort.Strings(image.Outdated)
+		images = append(images, image)
+	}
+
+	sort.Slice(images, func(i, j int) bool {
+		if (len(images[i].Outdated) > 0) != (len(images[j].Outdated) > 0) {
+			return len(images[i].Outdated) > 0
+		}
+		return images[i].Repository < images[j].Repository
+	})
+	return images
+}
+
+// olderTag orders release versions oldest first, then the other tags by name
+func olderTag(a, b string) bool {
+	va, okA := version.Parse(a)
+	vb, okB := version.Parse(b)
+	switch {
+	case okA && okB:
+		return version.Compare(va, vb) < 0
+	case okA != okB:
+		return okA
+	}
+	return a < b
+}
@@ -0,0 +1,60 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFleetImages(t *testing.T) {
+	fleet := NewFleet(RoleSidecar)
+	fleet.Add("crm-core", "crm-core-prod-sa-east-1", []Container{
+		{Name: "crm-core", Image: "crm-core:1.25.0", Role: RoleMain},
+		{Name: "datadog", Image: "datadog/agent:7.50.0", Role: RoleSidecar},
+		{Name: "vault", Image: "hashicorp/vault:1.15.0", Role: RoleSidecar},
+	})
+	// The regional replica of the ECR repository counts as the same one
+	fleet.Add("crm-core", "crm-core-prod-us-east-1", []Container{
+		{Name: "datadog", Image: "public.ecr.aws/datadog/agent:7.48.1", Role: RoleSidecar},
+	})
+	fleet.Add("psm-accounts", "psm-accounts-prod-sa-east-1", []Container{
+		{Name: "datadog", Image: "datadog/agent:7.50.0", Role: RoleSidecar},
+		{Name: "istio", Image: "istio/proxyv2:latest", Role: RoleSidecar},
+	})
+	fleet.Add("console-audit-bff", "console-audit-bff-dev-ext-sa-east-1", []Container{
+		{Name: "datadog", Image: "datadog/agent:7.49.0-rc.1", Role: RoleSidecar},
+		{Name: "vault", Image: "hashicorp/vault@sha256:0123abcd", Role: RoleSidecar},
+	})
+
+	want := []ImageVersions{
+		{
+			Repository: "datadog/agent",
+			Latest:     "7.50.0",
+			Versions: []VersionUsage{
+				{Version: "7.48.1", Outdated: true, Apps: []Usage{{Repo: "crm-core", App: "crm-core-prod-us-east-1", Container: "datadog"}}},
+				{Version: "7.49.0-rc.1", Outdated: true, Apps: []Usage{{Repo: "console-audit-bff", App: "console-audit-bff-dev-ext-sa-east-1", Container: "datadog"}}},
+				{Version: "7.50.0", Apps: []Usage{
+					{Repo: "crm-core", App: "crm-core-prod-sa-east-1", Container: "datadog"},
+					{Repo: "psm-accounts", App: "psm-accounts-prod-sa-east-1", Container: "datadog"},
+				}},
+			},
+			Outdated: []string{"console-audit-bff", "crm-core"},
+		},
+		// A digest is never outdated
+		{
+			Repository: "hashicorp/vault",
+			Latest:     "1.15.0",
+			Versions: []VersionUsage{
+				{Version: "1.15.0", Apps: []Usage{{Repo: "crm-core", App: "crm-core-prod-sa-east-1", Container: "vault"}}},
+				{Version: "sha256:0123abcd", Apps: []Usage{{Repo: "console-audit-bff", App: "console-audit-bff-dev-ext-sa-east-1", Container: "vault"}}},
+			},
+		},
+		// Without a release version there is no latest to be behind
+		{
+			Repository: "istio/proxyv2",
+			Versions:   []VersionUsage{{Version: "latest", Apps: []Usage{{Repo: "psm-accounts", App: "psm-accounts-prod-sa-east-1", Container: "istio"}}}},
+		},
+	}
+	if got := fleet.Images(); !reflect.DeepEqual(got, want) {
+		t.Errorf("images = %+v\nwant %+v", got, want)
+	}
+}
@@ -0,0 +1,106 @@
+// pkg/inventory/inventory.go
+package inventory
+
+import (
+	"strings"
+
+	"github.com/tidwall/gjson"
+)
+
+// Container roles
+const (
+	RoleMain    = "main"
+	RoleSidecar = "sidecar"
+	RoleInit    = "init"
+)
+
+// Container sources
+const (
+	SourceRollout = "rollout"
+	SourcePod     = "pod"
+)
+
+// Container is one container image an app runs
+type Container struct {
+	// Name is the container name in the rollout's pod template; containers
+	// only seen on running pods have none
+	Name  string `json:"name,omitempty"`
+	Image string `json:"image"`
+	Role  string `json:"role" enum:"main,sidecar,init"`
+	// Source is rollout for containers of the pod template and pod for images
+	// only found on running pods, e.g. sidecars injected by a webhook or the
+	// previous version while a canary is in progress
+	Source string `json:"source" enum:"rollout,pod"`
+}
+
+// FromArgoCD lists the containers of an app from its ArgoCD resource tree
+// and the manifest of its Rollout. Containers whose image contains the repo
+// name are the main ones, the other regular containers are sidecars.
+func FromArgoCD(repoName, resourceTree, rolloutManifest string) []Container {
+	// The same image may run in several roles, e.g. migrations run as an init
+	// container of the app's main image, so the rollout's containers are told
+	// apart by role and image. Pod images come without their role and are
+	// only added when the rollout does not run them at all.
+	var containers []Container
+	seen := make(map[[2]string]bool)
+	seenImages := make(map[string]bool)
+	add := func(container Container) {
+		key := [2]string{container.Role, container.Image}
+		if container.Image == "" || seen[key] || container.Source == SourcePod && seenImages[container.Image] {
+			return
+		}
+		seen[key], seenImages[container.Image] = true, true
+		containers = append(containers, container)
+	}
+
+	podSpec := gjson.Get(rolloutManifest, "spec.template.spec")
+	for _, field := range []string{"initContainers", "containers"} {
+		for _, spec := range podSpec.Get(field).Array() {
+			container := Container{
+				Name:   spec.Get("name").String(),
+				Image:  spec.Get("image").String(),
+				Source: SourceRollout,
+			}
+			container.Role = role(repoName, container.Image, field == "initContainers")
+			add(container)
+		}
+	}
+
+	for _, node := range gjson.Get(resourceTree, "nodes").Array() {
+		if node.Get("kind").String() != "Pod" {
+			continue
+		}
+		for _, image := range node.Get("images").Array() {
+			add(Container{
+				Image:  image.String(),
+				Role:   role(repoName, image.String(), false),
+				Source: SourcePod,
+			})
+		}
+	}
+
+	return containers
+}
+
+// role tells main from sidecar containers. The resource tree does not say
+// which pod images come from init containers, so those count as sidecars.
+func role(repoName, image string, init bool) string {
+	switch {
+	case init:
+		return RoleInit
+	case strings.Contains(image, repoName):
+		return RoleMain
+	}
+	return RoleSidecar
+}
+
+// Images returns the images of the containers with the given role and source
+func Images(containers []Container, role, source string) []string {
+	var images []string
+	for _, container := range containers {
+		if container.Role == role && container.Source == source {
+			images = append(images, container.Image)
+		}
+	}
+	return images
+}
@@ -0,0 +1,51 @@
+package inventory
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFromArgoCD(t *testing.T) {
+	rollout := `{"spec":{"template":{"spec":{
+		"initContainers":[
+			{"name":"migrate","image":"123456789012.dkr.ecr.sa-east-1.amazonaws.com/crm-core:1.25.0"},
+			{"name":"vault-init","image":"hashicorp/vault:1.15"}
+		],
+		"containers":[
+			{"name":"crm-core","image":"123456789012.dkr.ecr.sa-east-1.amazonaws.com/crm-core:1.25.0"},
+			{"name":"datadog","image":"datadog/agent:7"},
+			{"name":"no-image"}
+		]}}}}`
+	tree := `{"nodes":[
+		{"kind":"Rollout","images":["ignored:1"]},
+		{"kind":"Pod","images":[
+			"123456789012.dkr.ecr.sa-east-1.amazonaws.com/crm-core:1.25.0",
+			"hashicorp/vault:1.15",
+			"123456789012.dkr.ecr.sa-east-1.amazonaws.com/crm-core:1.24.0",
+			"istio/proxyv2:1.20"
+		]},
+		{"kind":"Pod","images":["istio/proxyv2:1.20"]}
+	]}`
+
+	want := []Container{
+		// Migrations run the main image, and keep their own entry
+		{Name: "migrate", Image: "123456789012.dkr.ecr.sa-east-1.amazonaws.com/crm-core:1.25.0", Role: RoleInit, Source: SourceRollout},
+		{Name: "vault-init", Image: "hashicorp/vault:1.15", Role: RoleInit, Source: SourceRollout},
+		{Name: "crm-core", Image: "123456789012.dkr.ecr.sa-east-1.amazonaws.com/crm-core:1.25.0", Role: RoleMain, Source: SourceRollout},
+		{Name: "datadog", Image: "datadog/agent:7", Role: RoleSidecar, Source: SourceRollout},
+		// The previous version still running mid-canary, and an injected sidecar
+		{Image: "123456789012.dkr.ecr.sa-east-1.amazonaws.com/crm-core:1.24.0", Role: RoleMain, Source: SourcePod},
+		{Image: "istio/proxyv2:1.20", Role: RoleSidecar, Source: SourcePod},
+	}
+	containers := FromArgoCD("crm-core", tree, rollout)
+	if !reflect.DeepEqual(containers, want) {
+		t.Errorf("containers = %+v, want %+v", containers, want)
+	}
+
+	if images := Images(containers, RoleMain, SourceRollout); !reflect.DeepEqual(images, []string{"123456789012.dkr.ecr.sa-east-1.amazonaws.com/crm-core:1.25.0"}) {
+		t.Errorf("main rollout images = %v", images)
+	}
+	if images := Images(containers, RoleInit, SourceRollout); len(images) != 2 {
+		t.Errorf("init images = %v", images)
+	}
+}
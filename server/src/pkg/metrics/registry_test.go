@@ -0,0 +1,123 @@
+package metrics
+
+import (
+	"bytes"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("lighthouse_requests_total", "Requests by path.\nCounted on arrival.", "path")
+	duration := registry.NewHistogramVec("lighthouse_duration_seconds", `Durations, C:\ style`, []float64{0.1, 1, 10}, "repo")
+	inFlight := registry.NewGaugeVec("lighthouse_inflight", "Fetches in flight.")
+	registry.NewGaugeFunc("go_goroutines", "Number of goroutines.", func() float64 { return 7 })
+
+	requests.Inc(`/repos/"quoted"`)
+	requests.Add(2.5, "/")
+	requests.Inc("back\\slash\nnewline")
+	duration.Observe(0.05, "crm-core")
+	duration.Observe(0.5, "crm-core")
+	duration.Observe(0.5, "crm-core")
+	duration.Observe(60, "crm-core")
+	duration.Observe(1, "backoffice-core-bff")
+	inFlight.Inc()
+	inFlight.Inc()
+	inFlight.Dec()
+
+	var buf bytes.Buffer
+	registry.WriteText(&buf)
+
+	want := `# HELP go_goroutines Number of goroutines.
+# TYPE go_goroutines gauge
+go_goroutines 7
+# HELP lighthouse_duration_seconds Durations, C:\\ style
+# TYPE lighthouse_duration_seconds histogram
+lighthouse_duration_seconds_bucket{repo="backoffice-core-bff",le="0.1"} 0
+lighthouse_duration_seconds_bucket{repo="backoffice-core-bff",le="1"} 1
+lighthouse_duration_seconds_bucket{repo="backoffice-core-bff",le="10"} 1
+lighthouse_duration_seconds_bucket{repo="backoffice-core-bff",le="+Inf"} 1
+lighthouse_duration_seconds_sum{repo="backoffice-core-bff"} 1
+lighthouse_duration_seconds_count{repo="backoffice-core-bff"} 1
+lighthouse_duration_seconds_bucket{repo="crm-core",le="0.1"} 1
+lighthouse_duration_seconds_bucket{repo="crm-core",le="1"} 3
+lighthouse_duration_seconds_bucket{repo="crm-core",le="10"} 3
+lighthouse_duration_seconds_bucket{repo="crm-core",le="+Inf"} 4
+lighthouse_duration_seconds_sum{repo="crm-core"} 61.05
+lighthouse_duration_seconds_count{repo="crm-core"} 4
+# HELP lighthouse_inflight Fetches in flight.
+# TYPE lighthouse_inflight gauge
+lighthouse_inflight 1
+# HELP lighthouse_requests_total Requests by path.\nCounted on arrival.
+# TYPE lighthouse_requests_total counter
+lighthouse_requests_total{path="/"} 2.5
+lighthouse_requests_total{path="/repos/\"quoted\""} 1
+lighthouse_requests_total{path="back\\slash\nnewline"} 1
+`
+	if got := buf.String(); got != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestUnlabeledMetricsAreExposedBeforeTheirFirstUpdate(t *testing.T) {
+	registry := NewRegistry()
+	registry.NewGaugeVec("lighthouse_fetch_slots", "Capacity of the fetch semaphore.")
+	registry.NewCounterVec("lighthouse_refreshes_total", "Refreshes.")
+	registry.NewHistogramVec("lighthouse_wait_seconds", "Waits.", []float64{1})
+	registry.NewCounterVec("lighthouse_errors_total", "Errors by reason.", "reason")
+
+	recorder := httptest.NewRecorder()
+	registry.Handler()(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	want := `# HELP lighthouse_errors_total Errors by reason.
+# TYPE lighthouse_errors_total counter
+# HELP lighthouse_fetch_slots Capacity of the fetch semaphore.
+# TYPE lighthouse_fetch_slots gauge
+lighthouse_fetch_slots 0
+# HELP lighthouse_refreshes_total Refreshes.
+# TYPE lighthouse_refreshes_total counter
+lighthouse_refreshes_total 0
+# HELP lighthouse_wait_seconds Waits.
+# TYPE lighthouse_wait_seconds histogram
+lighthouse_wait_seconds_bucket{le="1"} 0
+lighthouse_wait_seconds_bucket{le="+Inf"} 0
+lighthouse_wait_seconds_sum 0
+lighthouse_wait_seconds_count 0
+`
+	if got := recorder.Body.String(); got != want {
+		t.Errorf("exposition =\n%s\nwant\n%s", got, want)
+	}
+	if contentType := recorder.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %s", contentType)
+	}
+}
+
+func TestRegistryRejectsMisuse(t *testing.T) {
+	registry := NewRegistry()
+	gauge := registry.NewGaugeVec("lighthouse_up", "Up.", "instance")
+
+	for name, misuse := range map[string]func(){
+		"duplicate name":       func() { registry.NewCounterVec("lighthouse_up", "Again.") },
+		"missing label values": func() { gauge.Set(1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s did not panic", name)
+				}
+			}()
+			misuse()
+		}()
+	}
+}
+
+func TestFormatFloat(t *testing.T) {
+	for value, want := range map[float64]string{0: "0", 2.5: "2.5", 1e21: "1e+21", math.Inf(1): "+Inf", math.Inf(-1): "-Inf"} {
+		if got := formatFloat(value); got != want {
+			t.Errorf("formatFloat(%v) = %s, want %s", value, got, want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// pkg/metrics/transport.go
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Transport records latency and errors per upstream host for every request
+// made through it
+type Transport struct {
+	Base     http.RoundTripper
+	duration *HistogramVec
+	errors   *CounterVec
+}
+
+func NewTransport(registry *Registry, base http.RoundTripper) *Transport {
+	return &Transport{
+		Base: base,
+		duration: registry.NewHistogramVec("lighthouse_upstream_request_duration_seconds",
+			"Latency of requests to upstream APIs such as ArgoCD, Jira and Grafana.", nil, "host", "method", "code"),
+		errors: registry.NewCounterVec("lighthouse_upstream_request_errors_total",
+			"Upstream requests that failed, by transport error or non-2xx status.", "host", "reason"),
+	}
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	elapsed := time.Since(start).Seconds()
+
+	host := req.URL.Host
+	if err != nil {
+		t.duration.Observe(elapsed, host, req.Method, "error")
+		t.errors.Inc(host, "transport")
+		return nil, err
+	}
+
+	t.duration.Observe(elapsed, host, req.Method, strconv.Itoa(resp.StatusCode))
+	if resp.StatusCode >= 400 {
+		t.errors.Inc(host, strconv.Itoa(resp.StatusCode))
+	}
+	return resp, nil
+}
+
+// Wrap instruments another base transport with the same metrics, for
+// clients that need transport settings of their own such as TLS
+func (t *Transport) Wrap(base http.RoundTripper) http.RoundTripper {
+	return &Transport{Base: base, duration: t.duration, errors: t.errors}
+}
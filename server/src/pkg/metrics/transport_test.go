@@ -0,0 +1,46 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTransportRecordsUpstreamRequests(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+		}
+	}))
+	defer upstream.Close()
+
+	registry := NewRegistry()
+	client := &http.Client{Transport: NewTransport(registry, http.DefaultTransport)}
+	for _, path := range []string{"/", "/missing"} {
+		resp, err := client.Get(upstream.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	if _, err := client.Get("http://127.0.0.1:1/"); err == nil {
+		t.Fatal("request to a closed port succeeded")
+	}
+
+	var buf bytes.Buffer
+	registry.WriteText(&buf)
+	host := strings.TrimPrefix(upstream.URL, "http://")
+	for _, line := range []string{
+		`lighthouse_upstream_request_duration_seconds_count{host="` + host + `",method="GET",code="200"} 1`,
+		`lighthouse_upstream_request_duration_seconds_count{host="` + host + `",method="GET",code="404"} 1`,
+		`lighthouse_upstream_request_duration_seconds_count{host="127.0.0.1:1",method="GET",code="error"} 1`,
+		`lighthouse_upstream_request_errors_total{host="` + host + `",reason="404"} 1`,
+		`lighthouse_upstream_request_errors_total{host="127.0.0.1:1",reason="transport"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("exposition has no %s:\n%s", line, buf.String())
+		}
+	}
+}
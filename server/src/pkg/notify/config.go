@@ -0,0 +1,110 @@
+// pkg/notify/config.go
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"time"
+)
+
+// DefaultEvents are delivered when a subscription does not list event types
+var DefaultEvents = []string{"version.changed", "rollout.aborted", "health.error"}
+
+type SinkConfig struct {
+	// Type is webhook, slack or file
+	Type    string            `json:"type"`
+	URL     string            `json:"url,omitempty"`
+	Path    string            `json:"path,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// Subscription matches events by repo, squad, environment tier, location and
+// type. Empty lists match everything; entries may use path.Match globs such
+// as "*-bff" or "prod-*".
+type Subscription struct {
+	Name   string   `json:"name"`
+	Repos  []string `json:"repos,omitempty"`
+	Squads []string `json:"squads,omitempty"`
+	// Envs match the environment tier: prod, stag, ext, integration or dev
+	Envs []string `json:"envs,omitempty"`
+	// Locations match the account and region, e.g. "prod-sa-east-1"
+	Locations []string   `json:"locations,omitempty"`
+	Events    []string   `json:"events,omitempty"`
+	Sink      SinkConfig `json:"sink"`
+}
+
+type Config struct {
+	Subscriptions []Subscription `json:"subscriptions"`
+	// DedupWindow suppresses identical notifications to the same sink, e.g. "1h"
+	DedupWindow string `json:"dedupWindow,omitempty"`
+	// MaxAttempts and InitialBackoff control delivery retries, e.g. 5 and "2s"
+	MaxAttempts    int    `json:"maxAttempts,omitempty"`
+	InitialBackoff string `json:"initialBackoff,omitempty"`
+}
+
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading notifications config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing notifications config: %v", err)
+	}
+
+	for i, subscription := range config.Subscriptions {
+		if _, err := newSink(subscription.Sink); err != nil {
+			return nil, fmt.Errorf("subscription %d (%s): %v", i, subscription.Name, err)
+		}
+		patterns := append(append(append([]string{}, subscription.Repos...), subscription.Squads...), subscription.Envs...)
+		for _, pattern := range append(patterns, subscription.Locations...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return nil, fmt.Errorf("subscription %d (%s): invalid pattern %q", i, subscription.Name, pattern)
+			}
+		}
+	}
+	for _, value := range []string{config.DedupWindow, config.InitialBackoff} {
+		if value == "" {
+			continue
+		}
+		if _, err := time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("invalid duration %q in notifications config", value)
+		}
+	}
+
+	return &config, nil
+}
+
+func (s Subscription) matches(eventType, repo, squad, env, location string) bool {
+	events := s.Events
+	if len(events) == 0 {
+		events = DefaultEvents
+	}
+	return matchAny(events, eventType) &&
+		matchAny(s.Repos, repo) &&
+		matchAny(s.Squads, squad) &&
+		matchAny(s.Envs, env) &&
+		matchAny(s.Locations, location)
+}
+
+func matchAny(patterns []string, value string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func durationOr(value string, fallback time.Duration) time.Duration {
+	if parsed, err := time.ParseDuration(value); err == nil && value != "" {
+		return parsed
+	}
+	return fallback
+}
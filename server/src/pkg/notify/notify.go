@@ -0,0 +1,181 @@
+// pkg/notify/notify.go
+package notify
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"sync"
+	"time"
+
+	"argocd/pkg/events"
+)
+
+type Notification struct {
+	Subscription string                 `json:"subscription"`
+	Type         string                 `json:"type"`
+	Repo         string                 `json:"repo"`
+	Squad        string                 `json:"squad,omitempty"`
+	App          string                 `json:"app,omitempty"`
+	Env          string                 `json:"env,omitempty"`
+	Location     string                 `json:"location,omitempty"`
+	Message      string                 `json:"message"`
+	Link         string                 `json:"link,omitempty"`
+	Data         map[string]interface{} `json:"data,omitempty"`
+	Time         time.Time              `json:"time"`
+}
+
+type route struct {
+	subscription Subscription
+	sink         Sink
+}
+
+// Notifier turns change events into notifications for every matching
+// subscription, de-duplicating repeats and retrying failed deliveries
+type Notifier struct {
+	routes         []route
+	squadOf        func(repo string) string
+	linkOf         func(repo, app string) string
+	dedupWindow    time.Duration
+	maxAttempts    int
+	initialBackoff time.Duration
+
+	mu   sync.Mutex
+	sent map[string]time.Time
+	// pending holds the notifications being delivered, so that a repeat
+	// arriving meanwhile is not sent twice
+	pending map[string]bool
+}
+
+// NewNotifier builds the sinks for every subscription. squadOf resolves the
+// owning squad of a repo for squad-scoped subscriptions; linkOf may be nil.
+func NewNotifier(config *Config, squadOf func(repo string) string, linkOf func(repo, app string) string) (*Notifier, error) {
+	notifier := &Notifier{
+		squadOf:        squadOf,
+		linkOf:         linkOf,
+		dedupWindow:    durationOr(config.DedupWindow, time.Hour),
+		maxAttempts:    config.MaxAttempts,
+		initialBackoff: durationOr(config.InitialBackoff, 2*time.Second),
+		sent:           make(map[string]time.Time),
+		pending:        make(map[string]bool),
+	}
+	if notifier.maxAttempts <= 0 {
+		notifier.maxAttempts = 5
+	}
+
+	for _, subscription := range config.Subscriptions {
+		sink, err := newSink(subscription.Sink)
+		if err != nil {
+			return nil, err
+		}
+		notifier.routes = append(notifier.routes, route{subscription: subscription, sink: sink})
+	}
+	return notifier, nil
+}
+
+// Run delivers notifications for events from the broker. It reads from a
+// queue rather than a stream, which drops events when it falls behind.
+func (n *Notifier) Run(broker *events.Broker) {
+	queue, unsubscribe := broker.SubscribeQueue()
+	defer unsubscribe()
+
+	for {
+		event, ok := queue.Next()
+		if !ok {
+			return
+		}
+		n.Handle(event)
+	}
+}
+
+// Handle routes one event to its matching subscriptions. Deliveries run in the
+// background so a slow sink never holds up the others.
+func (n *Notifier) Handle(event events.Event) {
+	squad := ""
+	if n.squadOf != nil {
+		squad = n.squadOf(event.Repo)
+	}
+
+	for _, r := range n.routes {
+		if !r.subscription.matches(event.Type, event.Repo, squad, event.Env, event.Location) {
+			continue
+		}
+
+		notification := Notification{
+			Subscription: r.subscription.Name,
+			Type:         event.Type,
+			Repo:         event.Repo,
+			Squad:        squad,
+			App:          event.App,
+			Env:          event.Env,
+			Location:     event.Location,
+			Message:      event.Message,
+			Data:         event.Data,
+			Time:         event.Time,
+		}
+		if n.linkOf != nil && event.App != "" {
+			notification.Link = n.linkOf(event.Repo, event.App)
+		}
+
+		key := dedupKey(r.sink, notification)
+		if !n.claim(key) {
+			continue
+		}
+		go n.deliver(r.sink, notification, key)
+	}
+}
+
+func dedupKey(sink Sink, notification Notification) string {
+	sum := sha256.Sum256([]byte(sink.Name() + "\x00" + notification.Type + "\x00" + notification.Repo + "\x00" + notification.App + "\x00" + notification.Message))
+	return hex.EncodeToString(sum[:])
+}
+
+// claim reports whether the notification is to be delivered: it is not being
+// delivered already, nor was it sent within the dedup window
+func (n *Notifier) claim(key string) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	now := time.Now()
+	for k, sentAt := range n.sent {
+		if now.Sub(sentAt) > n.dedupWindow {
+			delete(n.sent, k)
+		}
+	}
+	if _, seen := n.sent[key]; seen || n.pending[key] {
+		return false
+	}
+	n.pending[key] = true
+	return true
+}
+
+// settle ends a delivery; only a delivered notification counts for the
+// dedup window, so a failed one is sent again when the event repeats
+func (n *Notifier) settle(key string, delivered bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.pending, key)
+	if delivered {
+		n.sent[key] = time.Now()
+	}
+}
+
+// deliver sends with exponential backoff between attempts
+func (n *Notifier) deliver(sink Sink, notification Notification, key string) {
+	backoff := n.initialBackoff
+	for attempt := 1; attempt <= n.maxAttempts; attempt++ {
+		err := sink.Send(notification)
+		if err == nil {
+			n.settle(key, true)
+			return
+		}
+		if attempt == n.maxAttempts {
+			n.settle(key, false)
+			slog.Error("Giving up on notification", "type", notification.Type, "sink", sink.Name(), "attempts", attempt, "error", err)
+			return
+		}
+		slog.Warn("Notification failed, retrying", "sink", sink.Name(), "attempt", attempt, "maxAttempts", n.maxAttempts, "backoff", backoff, "error", err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"argocd/pkg/events"
+)
+
+// recordingSink fails the first failures sends and records the others
+type recordingSink struct {
+	mu       sync.Mutex
+	failures int
+	sent     []Notification
+	attempts chan struct{}
+}
+
+func (s *recordingSink) Name() string { return "recording" }
+
+func (s *recordingSink) Send(notification Notification) error {
+	defer func() { s.attempts <- struct{}{} }()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("webhook returned 502")
+	}
+	s.sent = append(s.sent, notification)
+	return nil
+}
+
+func (s *recordingSink) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sent)
+}
+
+func newTestNotifier(sink Sink) *Notifier {
+	return &Notifier{
+		routes:         []route{{subscription: Subscription{Name: "all"}, sink: sink}},
+		dedupWindow:    time.Hour,
+		maxAttempts:    1,
+		initialBackoff: time.Millisecond,
+		sent:           make(map[string]time.Time),
+		pending:        make(map[string]bool),
+	}
+}
+
+// waitFor waits for a delivery attempt and for the notifier to settle it
+func waitFor(t *testing.T, notifier *Notifier, attempts chan struct{}) {
+	t.Helper()
+	select {
+	case <-attempts:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no delivery attempt")
+	}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		notifier.mu.Lock()
+		pending := len(notifier.pending)
+		notifier.mu.Unlock()
+		if pending == 0 {
+			return
+		}
+	}
+	t.Fatal("delivery never settled")
+}
+
+var deploy = events.Event{Type: events.TypeVersionChanged, Repo: "backoffice-core-bff", App: "backoffice-core-bff-prod-sa-east-1", Env: "prod", Location: "prod-sa-east-1", Message: "version changed in prod-sa-east-1: 1.23.0 -> 1.24.0"}
+
+func TestFailedDeliveryDoesNotSuppressTheRepeat(t *testing.T) {
+	sink := &recordingSink{failures: 1, attempts: make(chan struct{}, 10)}
+	notifier := newTestNotifier(sink)
+
+	notifier.Handle(deploy)
+	waitFor(t, notifier, sink.attempts)
+	notifier.Handle(deploy)
+	waitFor(t, notifier, sink.attempts)
+
+	if sink.count() != 1 {
+		t.Errorf("delivered %d notifications, want the repeat delivered", sink.count())
+	}
+}
+
+func TestDeliveredNotificationIsDeduplicated(t *testing.T) {
+	sink := &recordingSink{attempts: make(chan struct{}, 10)}
+	notifier := newTestNotifier(sink)
+
+	notifier.Handle(deploy)
+	waitFor(t, notifier, sink.attempts)
+	notifier.Handle(deploy)
+
+	select {
+	case <-sink.attempts:
+		t.Error("repeat within the dedup window was sent again")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestRunReceivesEveryEventOfABurst(t *testing.T) {
+	broker := events.NewBroker(10)
+	queue, unsubscribe := broker.SubscribeQueue()
+
+	// Far more than a stream subscriber buffers, published before anything reads
+	const burst = 500
+	for i := 0; i < burst; i++ {
+		broker.Publish(events.Event{Type: events.TypeRefreshProgress, Repo: "backoffice-core-bff"})
+	}
+	unsubscribe()
+
+	received := 0
+	for {
+		if _, ok := queue.Next(); !ok {
+			break
+		}
+		received++
+	}
+	if received != burst {
+		t.Errorf("received %d events, want %d", received, burst)
+	}
+}
+
+func TestSubscriptionsMatchEnvAndLocationSeparately(t *testing.T) {
+	cases := []struct {
+		subscription Subscription
+		want         bool
+	}{
+		{Subscription{Envs: []string{"prod"}}, true},
+		{Subscription{Envs: []string{"prod-sa-east-1"}}, false},
+		{Subscription{Locations: []string{"prod-sa-*"}}, true},
+		{Subscription{Envs: []string{"prod"}, Locations: []string{"*-us-east-1"}}, false},
+	}
+	for _, c := range cases {
+		if got := c.subscription.matches(deploy.Type, deploy.Repo, "psm-console", deploy.Env, deploy.Location); got != c.want {
+			t.Errorf("%+v matches = %v, want %v", c.subscription, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+// pkg/notify/sinks.go
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// Sink delivers a notification somewhere. Send is retried by the notifier,
+// so a Sink should return an error for anything worth retrying.
+type Sink interface {
+	Name() string
+	Send(notification Notification) error
+}
+
+func newSink(config SinkConfig) (Sink, error) {
+	switch config.Type {
+	case "webhook":
+		if config.URL == "" {
+			return nil, fmt.Errorf("webhook sink needs a url")
+		}
+		return &WebhookSink{URL: config.URL, Headers: config.Headers, client: defaultHTTPClient}, nil
+	case "slack":
+		if config.URL == "" {
+			return nil, fmt.Errorf("slack sink needs a url")
+		}
+		return &SlackSink{URL: config.URL, client: defaultHTTPClient}, nil
+	case "file":
+		if config.Path == "" {
+			return nil, fmt.Errorf("file sink needs a path")
+		}
+		return &FileSink{Path: config.Path}, nil
+	}
+	return nil, fmt.Errorf("unknown sink type %q, use webhook, slack or file", config.Type)
+}
+
+var defaultHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookSink posts the notification as JSON
+type WebhookSink struct {
+	URL     string
+	Headers map[string]string
+	client  *http.Client
+}
+
+func (s *WebhookSink) Name() string {
+	return "webhook:" + s.URL
+}
+
+func (s *WebhookSink) Send(notification Notification) error {
+	return postJSON(s.client, s.URL, s.Headers, notification)
+}
+
+// SlackSink posts a Slack incoming-webhook payload
+type SlackSink struct {
+	URL    string
+	client *http.Client
+}
+
+func (s *SlackSink) Name() string {
+	return "slack:" + s.URL
+}
+
+func (s *SlackSink) Send(notification Notification) error {
+	text := fmt.Sprintf("*%s* %s", notification.Repo, notification.Message)
+
+	var context []string
+	if notification.Squad != "" {
+		context = append(context, "squad "+notification.Squad)
+	}
+	if notification.Location != "" {
+		context = append(context, "in "+notification.Location)
+	} else if notification.Env != "" {
+		context = append(context, "env "+notification.Env)
+	}
+	if notification.Link != "" {
+		context = append(context, fmt.Sprintf("<%s|ArgoCD>", notification.Link))
+	}
+
+	blocks := []map[string]interface{}{
+		{
+			"type": "section",
+			"text": map[string]string{"type": "mrkdwn", "text": text},
+		},
+	}
+	if len(context) > 0 {
+		elements := make([]map[string]string, 0, len(context))
+		for _, item := range context {
+			elements = append(elements, map[string]string{"type": "mrkdwn", "text": item})
+		}
+		blocks = append(blocks, map[string]interface{}{"type": "context", "elements": elements})
+	}
+
+	payload := map[string]interface{}{
+		// text is the fallback shown in notifications
+		"text":   fmt.Sprintf("%s: %s", notification.Repo, notification.Message),
+		"blocks": blocks,
+	}
+	return postJSON(s.client, s.URL, nil, payload)
+}
+
+// FileSink appends one JSON line per notification, for local testing
+type FileSink struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (s *FileSink) Name() string {
+	return "file:" + s.Path
+}
+
+func (s *FileSink) Send(notification Notification) error {
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return fmt.Errorf("error marshalling notification: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error opening notification file: %v", err)
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	return err
+}
+
+func postJSON(client *http.Client, url string, headers map[string]string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("error marshalling payload: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error: received status code %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+// pkg/policy/engine.go
+package policy
+
+import (
+	"argocd/pkg/summary"
+	"argocd/pkg/version"
+	"fmt"
+	"time"
+)
+
+// Engine checks repo summaries against the configured rules
+type Engine struct {
+	rules   []Rule
+	squadOf func(repo string) string
+	now     func() time.Time
+}
+
+// NewEngine checks summaries against the rules of config. squadOf resolves
+// the squad owning a repo, which squad-scoped rules and the repo.squad
+// variable go by.
+func NewEngine(config Config, squadOf func(repo string) string) *Engine {
+	return &Engine{rules: config.Rules, squadOf: squadOf, now: time.Now}
+}
+
+// Rules returns the configured rules
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Evaluate sets the violations of every app in the repo. repoDir is the
+// repo's checkout, where the helm values are read from. Rules that fail to
+// evaluate for an app are returned as errors and count as met.
+func (e *Engine) Evaluate(repo *summary.Repo, repoDir string) []error {
+	var errs []error
+	squad := e.squadOf(repo.RepoName)
+	repoVars := repoVariables(repo, squad)
+	for i := range repo.Apps {
+		app := &repo.Apps[i]
+		app.Violations = nil
+		vars := map[string]interface{}{
+			"repo":   repoVars,
+			"app":    e.appVariables(*app),
+			"values": HelmValues(repoDir, app.Account, app.Region),
+		}
+		for _, rule := range e.rules {
+			if !rule.appliesTo(squad) {
+				continue
+			}
+			violation, err := rule.check(vars)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("rule %s on %s: %v", rule.Name, app.AppName, err))
+				continue
+			}
+			if violation != nil {
+				app.Violations = append(app.Violations, *violation)
+			}
+		}
+	}
+	return errs
+}
+
+// repoVariables describes the repo owned by squad to rules. versions maps
+// each environment to the newest release version running in it.
+func repoVariables(repo *summary.Repo, squad string) map[string]interface{} {
+	versions := make(map[string]interface{})
+	newest := make(map[string]version.Version)
+	for _, app := range repo.Apps {
+		v, ok := version.Parse(app.Version())
+		if !ok {
+			continue
+		}
+		if current, seen := newest[app.Env]; !seen || version.Compare(v, current) > 0 {
+			newest[app.Env] = v
+			versions[app.Env] = app.Version()
+		}
+	}
+	return map[string]interface{}{
+		"name":      repo.RepoName,
+		"squad":     squad,
+		"namespace": repo.RepoNamespace,
+		"versions":  versions,
+	}
+}
+
+// appVariables describes an app to rules
+func (e *Engine) appVariables(app summary.App) map[string]interface{} {
+	containers := make([]interface{}, 0, len(app.Containers))
+	for _, container := range app.Containers {
+		containers = append(containers, map[string]interface{}{
+			"name":   container.Name,
+			"image":  container.Image,
+			"role":   container.Role,
+			"source": container.Source,
+		})
+	}
+
+	replicas := 0
+	if app.Deployment != nil {
+		replicas = app.Deployment.TotalPods
+	}
+
+	health, aborted := "", false
+	pausedFor := time.Duration(0)
+	if app.ArgoCD != nil {
+		health, aborted = app.ArgoCD.Health, app.ArgoCD.Aborted
+		if app.Paused() && app.ArgoCD.PausedSince != nil {
+			pausedFor = e.now().Sub(*app.ArgoCD.PausedSince)
+		}
+	}
+
+	return map[string]interface{}{
+		"name":       app.AppName,
+		"type":       app.Type,
+		"account":    app.Account,
+		"region":     app.Region,
+		"env":        app.Env,
+		"version":    app.Version(),
+		"images":     stringList(app.Images),
+		"containers": containers,
+		"errors":     stringList(app.Error),
+		"warnings":   stringList(app.Warning),
+		"replicas":   float64(replicas),
+		"health":     health,
+		"aborted":    aborted,
+		"paused":     app.Paused(),
+		"pausedFor":  pausedFor,
+		"timedOut":   app.TimedOut,
+	}
+}
+
+func stringList(values []string) []interface{} {
+	list := make([]interface{}, 0, len(values))
+	for _, value := range values {
+		list = append(list, value)
+	}
+	return list
+}
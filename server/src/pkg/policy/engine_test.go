@@ -0,0 +1,97 @@
+package policy
+
+import (
+	"argocd/pkg/inventory"
+	"argocd/pkg/regions"
+	"argocd/pkg/summary"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testPolicies = `{"rules": [
+	{"name": "no-pr-images-in-prod", "severity": "error",
+	 "when": "app.env == 'prod'",
+	 "expr": "!app.containers.exists(c, c.image.contains(':PR-'))",
+	 "message": "a PR image is deployed to production"},
+	{"name": "console-squad-only", "squads": ["psm-console"],
+	 "expr": "repo.squad != 'psm-console'"}
+]}`
+
+func loadTestEngine(t *testing.T, owners map[string]string) *Engine {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "policies.json")
+	if err := os.WriteFile(path, []byte(testPolicies), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config, err := LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return NewEngine(*config, func(repo string) string { return owners[repo] })
+}
+
+func appWithImage(location regions.Location, image string) summary.App {
+	app := summary.NewApp("backoffice-core-bff"+location.Suffix(), location)
+	app.Containers = []inventory.Container{{Name: "app", Image: image}}
+	return app
+}
+
+func TestEvaluateFlagsViolations(t *testing.T) {
+	engine := loadTestEngine(t, nil)
+	repo := &summary.Repo{RepoName: "backoffice-core-bff", Apps: []summary.App{
+		appWithImage(regions.NewLocation("prod", "sa-east-1"), "backoffice-core-bff:PR-12"),
+		appWithImage(regions.NewLocation("dev", "sa-east-1"), "backoffice-core-bff:PR-12"),
+		appWithImage(regions.NewLocation("prod", "us-east-1"), "backoffice-core-bff:1.24.0"),
+	}}
+
+	if errs := engine.Evaluate(repo, t.TempDir()); len(errs) != 0 {
+		t.Fatalf("errors = %v", errs)
+	}
+	if violations := repo.Apps[0].Violations; len(violations) != 1 || violations[0].Rule != "no-pr-images-in-prod" || violations[0].Severity != SeverityError {
+		t.Errorf("prod PR image violations = %+v", violations)
+	}
+	if len(repo.Apps[1].Violations) != 0 || len(repo.Apps[2].Violations) != 0 {
+		t.Errorf("violations outside the rule's scope: %+v, %+v", repo.Apps[1].Violations, repo.Apps[2].Violations)
+	}
+}
+
+func TestEvaluateScopesRulesByTheResolvedOwner(t *testing.T) {
+	// The catalog still names the old team; the reconciled owner decides
+	engine := loadTestEngine(t, map[string]string{"backoffice-core-bff": "psm-console"})
+	repo := &summary.Repo{RepoName: "backoffice-core-bff", RepoSquad: "psm-accounts", Apps: []summary.App{
+		appWithImage(regions.NewLocation("dev", "sa-east-1"), "backoffice-core-bff:1.24.0"),
+	}}
+
+	engine.Evaluate(repo, t.TempDir())
+	if violations := repo.Apps[0].Violations; len(violations) != 1 || violations[0].Rule != "console-squad-only" {
+		t.Errorf("violations = %+v, want console-squad-only", violations)
+	}
+
+	engine = loadTestEngine(t, map[string]string{"backoffice-core-bff": "psm-accounts"})
+	repo.RepoSquad = "psm-console"
+	engine.Evaluate(repo, t.TempDir())
+	if len(repo.Apps[0].Violations) != 0 {
+		t.Errorf("rule of another squad applied: %+v", repo.Apps[0].Violations)
+	}
+}
+
+func TestLoadConfigRejectsBadRules(t *testing.T) {
+	cases := map[string]string{
+		"no name":          `{"rules": [{"expr": "true"}]}`,
+		"duplicate":        `{"rules": [{"name": "a", "expr": "true"}, {"name": "a", "expr": "true"}]}`,
+		"unknown severity": `{"rules": [{"name": "a", "severity": "fatal", "expr": "true"}]}`,
+		"no expr":          `{"rules": [{"name": "a"}]}`,
+		"bad expr":         `{"rules": [{"name": "a", "expr": "app.env =="}]}`,
+		"bad when":         `{"rules": [{"name": "a", "when": "(", "expr": "true"}]}`,
+	}
+	for name, config := range cases {
+		path := filepath.Join(t.TempDir(), "policies.json")
+		if err := os.WriteFile(path, []byte(config), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := LoadConfig(path); err == nil {
+			t.Errorf("%s: config accepted", name)
+		}
+	}
+}
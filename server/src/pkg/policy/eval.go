@@ -0,0 +1,466 @@
+// pkg/policy/eval.go
+package policy
+
+import (
+	"argocd/pkg/version"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Expression is a compiled expression
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses an expression
+func Compile(source string) (*Expression, error) {
+	root, err := parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("error compiling %q: %v", source, err)
+	}
+	return &Expression{source: source, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression with the given variables. Values are nil,
+// bool, float64, string, time.Duration, version.Version, []interface{} and
+// map[string]interface{}.
+func (e *Expression) Eval(vars map[string]interface{}) (interface{}, error) {
+	return eval(e.root, vars)
+}
+
+// EvalBool evaluates an expression that must be true or false
+func (e *Expression) EvalBool(vars map[string]interface{}) (bool, error) {
+	value, err := e.Eval(vars)
+	if err != nil {
+		return false, err
+	}
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("%q is %s, not bool", e.source, typeName(value))
+	}
+	return result, nil
+}
+
+func eval(n node, vars map[string]interface{}) (interface{}, error) {
+	switch n := n.(type) {
+	case literalNode:
+		return n.value, nil
+	case identNode:
+		value, ok := vars[n.name]
+		if !ok {
+			return nil, fmt.Errorf("undeclared reference to %q", n.name)
+		}
+		return value, nil
+	case listNode:
+		items := make([]interface{}, 0, len(n.items))
+		for _, item := range n.items {
+			value, err := eval(item, vars)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case unaryNode:
+		operand, err := eval(n.operand, vars)
+		if err != nil {
+			return nil, err
+		}
+		return unary(n.op, operand)
+	case binaryNode:
+		return evalBinary(n, vars)
+	case memberNode:
+		target, err := eval(n.target, vars)
+		if err != nil {
+			return nil, err
+		}
+		return index(target, n.field)
+	case indexNode:
+		target, err := eval(n.target, vars)
+		if err != nil {
+			return nil, err
+		}
+		key, err := eval(n.index, vars)
+		if err != nil {
+			return nil, err
+		}
+		return index(target, key)
+	case callNode:
+		return evalCall(n, vars)
+	}
+	return nil, fmt.Errorf("unknown expression %T", n)
+}
+
+func unary(op string, operand interface{}) (interface{}, error) {
+	switch value := operand.(type) {
+	case bool:
+		if op == "!" {
+			return !value, nil
+		}
+	case float64:
+		if op == "-" {
+			return -value, nil
+		}
+	case time.Duration:
+		if op == "-" {
+			return -value, nil
+		}
+	}
+	return nil, fmt.Errorf("no such operator: %s%s", op, typeName(operand))
+}
+
+func evalBinary(n binaryNode, vars map[string]interface{}) (interface{}, error) {
+	left, err := eval(n.left, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	// && and || stop at the first operand that decides the result
+	if n.op == "&&" || n.op == "||" {
+		l, ok := left.(bool)
+		if !ok {
+			return nil, fmt.Errorf("no such operator: %s %s _", typeName(left), n.op)
+		}
+		if (n.op == "&&" && !l) || (n.op == "||" && l) {
+			return l, nil
+		}
+		right, err := eval(n.right, vars)
+		if err != nil {
+			return nil, err
+		}
+		if r, ok := right.(bool); ok {
+			return r, nil
+		}
+		return nil, fmt.Errorf("no such operator: bool %s %s", n.op, typeName(right))
+	}
+
+	right, err := eval(n.right, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "in":
+		switch container := right.(type) {
+		case []interface{}:
+			for _, item := range container {
+				if equal(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := left.(string)
+			if !ok {
+				return false, nil
+			}
+			_, found := container[key]
+			return found, nil
+		}
+	case "<", "<=", ">", ">=":
+		cmp, err := compare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return cmp < 0, nil
+		case "<=":
+			return cmp <= 0, nil
+		case ">":
+			return cmp > 0, nil
+		}
+		return cmp >= 0, nil
+	case "+":
+		switch l := left.(type) {
+		case float64:
+			if r, ok := right.(float64); ok {
+				return l + r, nil
+			}
+		case string:
+			if r, ok := right.(string); ok {
+				return l + r, nil
+			}
+		case time.Duration:
+			if r, ok := right.(time.Duration); ok {
+				return l + r, nil
+			}
+		case []interface{}:
+			if r, ok := right.([]interface{}); ok {
+				return append(append([]interface{}{}, l...), r...), nil
+			}
+		}
+	case "-":
+		switch l := left.(type) {
+		case float64:
+			if r, ok := right.(float64); ok {
+				return l - r, nil
+			}
+		case time.Duration:
+			if r, ok := right.(time.Duration); ok {
+				return l - r, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no such operator: %s %s %s", typeName(left), n.op, typeName(right))
+}
+
+func equal(a, b interface{}) bool {
+	if va, ok := a.(version.Version); ok {
+		if vb, ok := b.(version.Version); ok {
+			return version.Compare(va, vb) == 0
+		}
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func compare(a, b interface{}) (int, error) {
+	switch l := a.(type) {
+	case float64:
+		if r, ok := b.(float64); ok {
+			return compareFloat(l, r), nil
+		}
+	case string:
+		if r, ok := b.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	case time.Duration:
+		if r, ok := b.(time.Duration); ok {
+			return compareFloat(float64(l), float64(r)), nil
+		}
+	case version.Version:
+		if r, ok := b.(version.Version); ok {
+			return version.Compare(l, r), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", typeName(a), typeName(b))
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func index(target, key interface{}) (interface{}, error) {
+	switch container := target.(type) {
+	case map[string]interface{}:
+		name, ok := key.(string)
+		if !ok {
+			return nil, fmt.Errorf("map key must be string, not %s", typeName(key))
+		}
+		value, found := container[name]
+		if !found {
+			return nil, fmt.Errorf("no such key: %s", name)
+		}
+		return value, nil
+	case []interface{}:
+		i, ok := key.(float64)
+		if !ok || i != float64(int(i)) {
+			return nil, fmt.Errorf("list index must be an integer, not %v", key)
+		}
+		if int(i) < 0 || int(i) >= len(container) {
+			return nil, fmt.Errorf("index %d out of range", int(i))
+		}
+		return container[int(i)], nil
+	}
+	return nil, fmt.Errorf("cannot index %s", typeName(target))
+}
+
+func evalCall(n callNode, vars map[string]interface{}) (interface{}, error) {
+	// Macros take their arguments unevaluated
+	switch {
+	case n.target == nil && n.name == "has":
+		if len(n.args) != 1 {
+			return nil, fmt.Errorf("has takes one field selection")
+		}
+		member, ok := n.args[0].(memberNode)
+		if !ok {
+			return nil, fmt.Errorf("has takes a field selection such as has(values.minReplicas)")
+		}
+		target, err := eval(member.target, vars)
+		if err != nil {
+			return nil, err
+		}
+		fields, ok := target.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("has needs a map, not %s", typeName(target))
+		}
+		value, found := fields[member.field]
+		return found && value != nil, nil
+	case n.target != nil && (n.name == "exists" || n.name == "all"):
+		return evalQuantifier(n, vars)
+	}
+
+	args := make([]interface{}, 0, len(n.args)+1)
+	if n.target != nil {
+		target, err := eval(n.target, vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, target)
+	}
+	for _, arg := range n.args {
+		value, err := eval(arg, vars)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+
+	function, ok := functions[n.name]
+	if !ok {
+		return nil, fmt.Errorf("undeclared function %q", n.name)
+	}
+	return function(args)
+}
+
+// evalQuantifier evaluates list.exists(x, pred) and list.all(x, pred)
+func evalQuantifier(n callNode, vars map[string]interface{}) (interface{}, error) {
+	if len(n.args) != 2 {
+		return nil, fmt.Errorf("%s takes a variable and a predicate", n.name)
+	}
+	variable, ok := n.args[0].(identNode)
+	if !ok {
+		return nil, fmt.Errorf("the first argument of %s must be a variable name", n.name)
+	}
+	target, err := eval(n.target, vars)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := target.([]interface{})
+	if target != nil && !ok {
+		return nil, fmt.Errorf("%s needs a list, not %s", n.name, typeName(target))
+	}
+
+	scope := make(map[string]interface{}, len(vars)+1)
+	for name, value := range vars {
+		scope[name] = value
+	}
+	for _, item := range items {
+		scope[variable.name] = item
+		value, err := eval(n.args[1], scope)
+		if err != nil {
+			return nil, err
+		}
+		result, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("the predicate of %s is %s, not bool", n.name, typeName(value))
+		}
+		if n.name == "exists" && result {
+			return true, nil
+		}
+		if n.name == "all" && !result {
+			return false, nil
+		}
+	}
+	return n.name == "all", nil
+}
+
+var functions = map[string]func(args []interface{}) (interface{}, error){
+	"size": func(args []interface{}) (interface{}, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("size takes one argument")
+		}
+		switch value := args[0].(type) {
+		case string:
+			return float64(len(value)), nil
+		case []interface{}:
+			return float64(len(value)), nil
+		case map[string]interface{}:
+			return float64(len(value)), nil
+		case nil:
+			return float64(0), nil
+		}
+		return nil, fmt.Errorf("size of %s", typeName(args[0]))
+	},
+	"contains":   stringPredicate("contains", strings.Contains),
+	"startsWith": stringPredicate("startsWith", strings.HasPrefix),
+	"endsWith":   stringPredicate("endsWith", strings.HasSuffix),
+	"matches": stringPredicate("matches", func(s, pattern string) bool {
+		matched, err := regexp.MatchString(pattern, s)
+		return err == nil && matched
+	}),
+	"duration": func(args []interface{}) (interface{}, error) {
+		text, ok := singleString(args)
+		if !ok {
+			return nil, fmt.Errorf("duration takes a string such as \"4h\"")
+		}
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration %q", text)
+		}
+		return d, nil
+	},
+	"version": func(args []interface{}) (interface{}, error) {
+		text, ok := singleString(args)
+		if !ok {
+			return nil, fmt.Errorf("version takes a string such as \"1.2.3\"")
+		}
+		v, ok := version.Parse(text)
+		if !ok {
+			return nil, fmt.Errorf("%q is not a release version", text)
+		}
+		return v, nil
+	},
+}
+
+func stringPredicate(name string, predicate func(s, t string) bool) func([]interface{}) (interface{}, error) {
+	return func(args []interface{}) (interface{}, error) {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s takes a string receiver and one argument", name)
+		}
+		s, ok1 := args[0].(string)
+		t, ok2 := args[1].(string)
+		if !ok1 || !ok2 {
+			return nil, fmt.Errorf("no such overload: %s.%s(%s)", typeName(args[0]), name, typeName(args[1]))
+		}
+		return predicate(s, t), nil
+	}
+}
+
+func singleString(args []interface{}) (string, bool) {
+	if len(args) != 1 {
+		return "", false
+	}
+	s, ok := args[0].(string)
+	return s, ok
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case time.Duration:
+		return "duration"
+	case version.Version:
+		return "version"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,105 @@
+package policy
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func testVars() map[string]interface{} {
+	return map[string]interface{}{
+		"app": map[string]interface{}{
+			"env":       "prod",
+			"version":   "1.24.0",
+			"replicas":  3.0,
+			"paused":    true,
+			"pausedFor": 5 * time.Hour,
+			"images":    []interface{}{"backoffice-core-bff:1.24.0", "istio-proxy:1.20"},
+			"containers": []interface{}{
+				map[string]interface{}{"name": "app", "image": "backoffice-core-bff:1.24.0"},
+				map[string]interface{}{"name": "sidecar", "image": "istio-proxy:1.20"},
+			},
+			"errors": []interface{}{},
+		},
+		"repo": map[string]interface{}{
+			"versions": map[string]interface{}{"ext": "1.25.0", "prod": "1.24.0"},
+		},
+		"values": map[string]interface{}{"minReplicas": 2.0, "maxReplicas": nil},
+	}
+}
+
+func TestEval(t *testing.T) {
+	cases := map[string]bool{
+		`app.env == 'prod' && app.replicas >= values.minReplicas`:                           true,
+		`app.env != "prod" || app.replicas > 3`:                                             false,
+		`app.replicas - 1 == 2 && -app.replicas < 0`:                                        true,
+		`app.pausedFor > duration('4h') && app.pausedFor < duration("4h") + duration("2h")`: true,
+		`version(app.version) < version(repo.versions['ext'])`:                              true,
+		`version('1.24.0') == version('v1.24.0')`:                                           true,
+		`'ext' in repo.versions && !('stag' in repo.versions)`:                              true,
+		`app.env in ['stag', 'prod']`:                                                       true,
+		`app.containers.exists(c, c.image.contains(':PR-'))`:                                false,
+		`app.containers.all(c, c.name.startsWith('app') || c.name == 'sidecar')`:            true,
+		`app.errors.all(e, e.contains('boom')) && !app.errors.exists(e, true)`:              true,
+		`size(app.images) == 2 && app.images.size() == 2 && size('abc') == 3`:               true,
+		`has(values.minReplicas) && !has(values.maxReplicas) && !has(values.other)`:         true,
+		`app.version.matches('^[0-9]+(\\.[0-9]+)*$') && app.version.endsWith('.0')`:         true,
+		`[1] + [2] == [1, 2] && 'a' + 'b' == 'ab'`:                                          true,
+		// The right operand is not evaluated once the left one decides
+		`app.env == 'stag' && app.missing`: false,
+		`app.env == 'prod' || app.missing`: true,
+	}
+	vars := testVars()
+	for src, want := range cases {
+		expression, err := Compile(src)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", src, err)
+			continue
+		}
+		got, err := expression.EvalBool(vars)
+		if err != nil {
+			t.Errorf("%s: %v", src, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s = %v, want %v", src, got, want)
+		}
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	cases := map[string]string{
+		`cluster.name == 'x'`:                 `undeclared reference to "cluster"`,
+		`app.owner == 'x'`:                    "no such key: owner",
+		`app.replicas > '2'`:                  "cannot compare number and string",
+		`app.env + 1 == 'x'`:                  "no such operator: string + number",
+		`!app.env`:                            "no such operator: !string",
+		`app.replicas && true`:                "no such operator: number && _",
+		`app.images[5] == 'x'`:                "index 5 out of range",
+		`app.images[0.5] == 'x'`:              "list index must be an integer",
+		`version(app.env) > version('1.0.0')`: `"prod" is not a release version`,
+		`duration('soon') > duration('1h')`:   `invalid duration "soon"`,
+		`app.replicas.contains('3')`:          "no such overload: number.contains(string)",
+		`app.containers.exists(c, c.name)`:    "the predicate of exists is string, not bool",
+		`app.containers.exists('c', true)`:    "must be a variable name",
+		`has(app)`:                            "has takes a field selection",
+		`lower(app.env) == 'prod'`:            `undeclared function "lower"`,
+		`app.env`:                             `is string, not bool`,
+	}
+	vars := testVars()
+	for src, want := range cases {
+		expression, err := Compile(src)
+		if err != nil {
+			t.Errorf("Compile(%q): %v", src, err)
+			continue
+		}
+		_, err = expression.EvalBool(vars)
+		if err == nil {
+			t.Errorf("%s evaluated without error", src)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: %v, want %q", src, err, want)
+		}
+	}
+}
@@ -0,0 +1,344 @@
+// pkg/policy/expr.go
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// The expression language is a small subset of CEL:
+//
+//	literals     1  2.5  "text"  'text'  true  false  null  [1, 2]
+//	operators    ||  &&  !  ==  !=  <  <=  >  >=  in  +  -
+//	access       app.env  repo.versions["ext"]
+//	functions    size(x)  has(a.b)  duration("4h")  version("1.2.3")
+//	methods      s.contains(t)  s.startsWith(t)  s.endsWith(t)  s.matches(re)
+//	             list.exists(x, pred)  list.all(x, pred)  x.size()
+//
+// Numbers are float64. Durations and versions compare with the usual operators.
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{}
+	pos   int
+}
+
+// operators are matched longest first
+var operators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", ".", ",", "(", ")", "[", "]"}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(src); {
+		c := rune(src[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c == '_' || unicode.IsLetter(c):
+			start := pos
+			for pos < len(src) && (src[pos] == '_' || unicode.IsLetter(rune(src[pos])) || unicode.IsDigit(rune(src[pos]))) {
+				pos++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: src[start:pos], pos: start})
+		case unicode.IsDigit(c):
+			start := pos
+			for pos < len(src) && (unicode.IsDigit(rune(src[pos])) || src[pos] == '.') {
+				pos++
+			}
+			number, err := strconv.ParseFloat(src[start:pos], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", src[start:pos], start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: src[start:pos], value: number, pos: start})
+		case c == '"' || c == '\'':
+			start := pos
+			pos++
+			var text strings.Builder
+			for pos < len(src) && rune(src[pos]) != c {
+				if src[pos] == '\\' && pos+1 < len(src) {
+					pos++
+				}
+				text.WriteByte(src[pos])
+				pos++
+			}
+			if pos >= len(src) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			pos++
+			tokens = append(tokens, token{kind: tokenString, text: src[start:pos], value: text.String(), pos: start})
+		default:
+			matched := ""
+			for _, op := range operators {
+				if strings.HasPrefix(src[pos:], op) {
+					matched = op
+					break
+				}
+			}
+			if matched == "" {
+				return nil, fmt.Errorf("unexpected %q at %d", c, pos)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: matched, pos: pos})
+			pos += len(matched)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// node is a parsed expression
+type node interface{}
+
+type (
+	literalNode struct{ value interface{} }
+	identNode   struct{ name string }
+	listNode    struct{ items []node }
+	unaryNode   struct {
+		op      string
+		operand node
+	}
+	binaryNode struct {
+		op          string
+		left, right node
+	}
+	memberNode struct {
+		target node
+		field  string
+	}
+	indexNode struct {
+		target, index node
+	}
+	// callNode is a function call, or a method call when target is set
+	callNode struct {
+		target node
+		name   string
+		args   []node
+	}
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// parse compiles an expression into its syntax tree
+func parse(src string) (node, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	expr, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if next := p.peek(); next.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", next.text, next.pos)
+	}
+	return expr, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the operators or keywords
+func (p *parser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokenOperator && t.kind != tokenIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		t := p.peek()
+		if t.kind == tokenEOF {
+			return fmt.Errorf("expected %q at end of expression", text)
+		}
+		return fmt.Errorf("expected %q at %d, found %q", text, t.pos, t.text)
+	}
+	return nil
+}
+
+func (p *parser) or() (node, error) {
+	return p.binary(p.and, "||")
+}
+
+func (p *parser) and() (node, error) {
+	return p.binary(p.comparison, "&&")
+}
+
+func (p *parser) comparison() (node, error) {
+	left, err := p.additive()
+	if err != nil {
+		return nil, err
+	}
+	if op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "in"); ok {
+		right, err := p.additive()
+		if err != nil {
+			return nil, err
+		}
+		return binaryNode{op: op, left: left, right: right}, nil
+	}
+	return left, nil
+}
+
+func (p *parser) additive() (node, error) {
+	return p.binary(p.unary, "+", "-")
+}
+
+// binary parses a left associative chain of operands joined by ops
+func (p *parser) binary(operand func() (node, error), ops ...string) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) unary() (node, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryNode{op: op, operand: operand}, nil
+	}
+	return p.postfix()
+}
+
+func (p *parser) postfix() (node, error) {
+	expr, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.peek().text == "." && p.peek().kind == tokenOperator:
+			p.next()
+			name := p.next()
+			if name.kind != tokenIdent {
+				return nil, fmt.Errorf("expected a field name at %d", name.pos)
+			}
+			if _, ok := p.accept("("); ok {
+				args, err := p.arguments(")")
+				if err != nil {
+					return nil, err
+				}
+				expr = callNode{target: expr, name: name.text, args: args}
+			} else {
+				expr = memberNode{target: expr, field: name.text}
+			}
+		case p.peek().text == "[" && p.peek().kind == tokenOperator:
+			p.next()
+			index, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			expr = indexNode{target: expr, index: index}
+		default:
+			return expr, nil
+		}
+	}
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber, tokenString:
+		return literalNode{value: t.value}, nil
+	case tokenIdent:
+		switch t.text {
+		case "true":
+			return literalNode{value: true}, nil
+		case "false":
+			return literalNode{value: false}, nil
+		case "null":
+			return literalNode{value: nil}, nil
+		}
+		if _, ok := p.accept("("); ok {
+			args, err := p.arguments(")")
+			if err != nil {
+				return nil, err
+			}
+			return callNode{name: t.text, args: args}, nil
+		}
+		return identNode{name: t.text}, nil
+	case tokenOperator:
+		switch t.text {
+		case "(":
+			expr, err := p.or()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		case "[":
+			items, err := p.arguments("]")
+			if err != nil {
+				return nil, err
+			}
+			return listNode{items: items}, nil
+		}
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	}
+	return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// arguments parses a comma separated list up to the closing token
+func (p *parser) arguments(closing string) ([]node, error) {
+	var args []node
+	if _, ok := p.accept(closing); ok {
+		return args, nil
+	}
+	for {
+		arg, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if _, ok := p.accept(","); ok {
+			continue
+		}
+		return args, p.expect(closing)
+	}
+}
@@ -0,0 +1,113 @@
+package policy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, err := tokenize(`app.replicas >= 2.5 && 'it\'s' != "x" || !paused`)
+	if err != nil {
+		t.Fatalf("tokenize: %v", err)
+	}
+	var texts []string
+	for _, token := range tokens {
+		texts = append(texts, token.text)
+	}
+	want := []string{"app", ".", "replicas", ">=", "2.5", "&&", `'it\'s'`, "!=", `"x"`, "||", "!", "paused", ""}
+	if !reflect.DeepEqual(texts, want) {
+		t.Errorf("tokens = %q, want %q", texts, want)
+	}
+	if tokens[4].value != 2.5 || tokens[6].value != "it's" {
+		t.Errorf("literal values = %v, %v", tokens[4].value, tokens[6].value)
+	}
+	if tokens[len(tokens)-1].kind != tokenEOF {
+		t.Error("tokens do not end with EOF")
+	}
+}
+
+func TestTokenizeRejectsBadInput(t *testing.T) {
+	for _, src := range []string{`"unterminated`, `1.2.3`, `app.env = 'prod'`, `a # b`} {
+		if _, err := tokenize(src); err == nil {
+			t.Errorf("tokenize(%q) succeeded", src)
+		}
+	}
+}
+
+func TestParsePrecedence(t *testing.T) {
+	root, err := parse(`a || b && !c == d`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	// || binds loosest, then &&, then comparison, then unary
+	want := binaryNode{op: "||",
+		left: identNode{"a"},
+		right: binaryNode{op: "&&",
+			left: identNode{"b"},
+			right: binaryNode{op: "==",
+				left:  unaryNode{op: "!", operand: identNode{"c"}},
+				right: identNode{"d"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(root, want) {
+		t.Errorf("tree = %#v", root)
+	}
+
+	root, err = parse(`1 - 2 - 3`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	leftAssociative := binaryNode{op: "-",
+		left:  binaryNode{op: "-", left: literalNode{1.0}, right: literalNode{2.0}},
+		right: literalNode{3.0},
+	}
+	if !reflect.DeepEqual(root, leftAssociative) {
+		t.Errorf("tree = %#v", root)
+	}
+}
+
+func TestParseAccessAndCalls(t *testing.T) {
+	root, err := parse(`app.containers.exists(c, c.image.contains(':PR-')) && repo.versions['ext'] in [1, 2]`)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	and := root.(binaryNode)
+	exists, ok := and.left.(callNode)
+	if !ok || exists.name != "exists" || len(exists.args) != 2 {
+		t.Fatalf("left = %#v", and.left)
+	}
+	if target := (memberNode{target: identNode{"app"}, field: "containers"}); !reflect.DeepEqual(exists.target, target) {
+		t.Errorf("exists target = %#v", exists.target)
+	}
+	in := and.right.(binaryNode)
+	if in.op != "in" || !reflect.DeepEqual(in.left, indexNode{target: memberNode{target: identNode{"repo"}, field: "versions"}, index: literalNode{"ext"}}) {
+		t.Errorf("right = %#v", in)
+	}
+	if list, ok := in.right.(listNode); !ok || len(list.items) != 2 {
+		t.Errorf("list = %#v", in.right)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		`app.env ==`:        "unexpected end of expression",
+		`(app.paused`:       `expected ")" at end of expression`,
+		`size(app.images`:   `expected ")" at end of expression`,
+		`app. 1`:            "expected a field name",
+		`app.paused true`:   `unexpected "true"`,
+		`repo.versions[1`:   `expected "]"`,
+		`app.env == == 'x'`: `unexpected "=="`,
+	}
+	for src, want := range cases {
+		_, err := Compile(src)
+		if err == nil {
+			t.Errorf("Compile(%q) succeeded", src)
+			continue
+		}
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Compile(%q) = %v, want %q", src, err, want)
+		}
+	}
+}
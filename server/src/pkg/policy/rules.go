@@ -0,0 +1,123 @@
+// pkg/policy/rules.go
+package policy
+
+import (
+	"argocd/pkg/summary"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Severities, from least to most severe
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// Rule is a condition every app it applies to must meet
+type Rule struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Severity    string `json:"severity"`
+	// Squads limits the rule to the repos of these squads; empty means all
+	Squads []string `json:"squads,omitempty"`
+	// When selects the apps the rule applies to; empty means all
+	When string `json:"when,omitempty"`
+	// Expr must hold for every app the rule applies to
+	Expr string `json:"expr"`
+	// Message describes a violation; it defaults to the description
+	Message string `json:"message,omitempty"`
+
+	when *Expression
+	expr *Expression
+}
+
+type Config struct {
+	Rules []Rule `json:"rules"`
+}
+
+// LoadConfig reads the rules and compiles their expressions, so a typo in
+// a rule stops the server instead of silently never matching
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading policies config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing policies config: %v", err)
+	}
+
+	names := make(map[string]bool)
+	for i := range config.Rules {
+		rule := &config.Rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("rule %d has no name", i)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("rule %s is declared twice", rule.Name)
+		}
+		names[rule.Name] = true
+
+		switch rule.Severity {
+		case SeverityInfo, SeverityWarning, SeverityError:
+		case "":
+			rule.Severity = SeverityWarning
+		default:
+			return nil, fmt.Errorf("rule %s has unknown severity %q", rule.Name, rule.Severity)
+		}
+
+		if rule.Expr == "" {
+			return nil, fmt.Errorf("rule %s has no expr", rule.Name)
+		}
+		if rule.expr, err = Compile(rule.Expr); err != nil {
+			return nil, fmt.Errorf("rule %s: %v", rule.Name, err)
+		}
+		if rule.When != "" {
+			if rule.when, err = Compile(rule.When); err != nil {
+				return nil, fmt.Errorf("rule %s: %v", rule.Name, err)
+			}
+		}
+	}
+	return &config, nil
+}
+
+// appliesTo reports whether the rule covers the repos of squad
+func (r Rule) appliesTo(squad string) bool {
+	if len(r.Squads) == 0 {
+		return true
+	}
+	for _, candidate := range r.Squads {
+		if candidate == squad {
+			return true
+		}
+	}
+	return false
+}
+
+// check evaluates the rule against one app. It returns nil when the app is
+// out of the rule's scope or meets it.
+func (r Rule) check(vars map[string]interface{}) (*summary.Violation, error) {
+	if r.when != nil {
+		applies, err := r.when.EvalBool(vars)
+		if err != nil || !applies {
+			return nil, err
+		}
+	}
+
+	ok, err := r.expr.EvalBool(vars)
+	if err != nil || ok {
+		return nil, err
+	}
+
+	message := r.Message
+	if message == "" {
+		message = r.Description
+	}
+	if message == "" {
+		message = "violates " + r.Expr
+	}
+	return &summary.Violation{Rule: r.Name, Severity: r.Severity, Message: message}, nil
+}
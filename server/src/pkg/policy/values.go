@@ -0,0 +1,40 @@
+// pkg/policy/values.go
+package policy
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// valuesKeyRe matches the replica settings of a helm values.yaml
+var valuesKeyRe = regexp.MustCompile(`^\s*(replicas|minReplicas|maxReplicas):\s*(\d+)\s*(#.*)?$`)
+
+// HelmValues reads the replica settings from the values.yaml of an account
+// and region under scripts/helm. Keys are only present when set; the first
+// occurrence wins, which is the rollout's own before any subchart's.
+func HelmValues(repoDir, account, region string) map[string]interface{} {
+	values := make(map[string]interface{})
+	file, err := os.Open(filepath.Join(repoDir, "scripts/helm", account, region, "values.yaml"))
+	if err != nil {
+		return values
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		match := valuesKeyRe.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		if _, seen := values[match[1]]; seen {
+			continue
+		}
+		if number, err := strconv.Atoi(match[2]); err == nil {
+			values[match[1]] = float64(number)
+		}
+	}
+	return values
+}
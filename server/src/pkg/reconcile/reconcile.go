@@ -0,0 +1,226 @@
+// pkg/reconcile/reconcile.go
+package reconcile
+
+import (
+	"argocd/pkg/regions"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Application is the part of an ArgoCD application the report needs
+type Application struct {
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	Namespace string            `json:"namespace"`
+	Server    string            `json:"server,omitempty"`
+	// Instance is the ArgoCD instance the application was listed from
+	Instance string `json:"instance,omitempty"`
+}
+
+// ParseApplications reads the response of an ArgoCD instance's /api/v1/applications
+func ParseApplications(instance string, body []byte) ([]Application, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Spec struct {
+				Destination struct {
+					Namespace string `json:"namespace"`
+					Server    string `json:"server"`
+					Name      string `json:"name"`
+				} `json:"destination"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, fmt.Errorf("error parsing ArgoCD applications: %v", err)
+	}
+
+	apps := make([]Application, 0, len(list.Items))
+	for _, item := range list.Items {
+		server := item.Spec.Destination.Server
+		if server == "" {
+			server = item.Spec.Destination.Name
+		}
+		apps = append(apps, Application{
+			Name:      item.Metadata.Name,
+			Labels:    item.Metadata.Labels,
+			Namespace: item.Spec.Destination.Namespace,
+			Server:    server,
+			Instance:  instance,
+		})
+	}
+	return apps, nil
+}
+
+// Declared is a location the repo's terraform or DR pairs deploy to, with
+// the namespace its terraform names and the ArgoCD instance it is routed to
+type Declared struct {
+	regions.Location
+	Namespace string `json:"namespace,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+}
+
+// Deployed is an ArgoCD application of the repo
+type Deployed struct {
+	App       string `json:"app"`
+	Account   string `json:"account,omitempty"`
+	Region    string `json:"region,omitempty"`
+	Namespace string `json:"namespace"`
+	Instance  string `json:"instance,omitempty"`
+}
+
+// NamespaceMismatch is an app deployed to another namespace than declared
+type NamespaceMismatch struct {
+	App      string `json:"app"`
+	Declared string `json:"declared"`
+	Deployed string `json:"deployed"`
+}
+
+// InstanceMismatch is an app found on another ArgoCD instance than the one
+// its location is routed to, so Lighthouse reads it from the wrong place
+type InstanceMismatch struct {
+	App      string `json:"app"`
+	Routed   string `json:"routed"`
+	Deployed string `json:"deployed"`
+}
+
+// Report compares where a repo is declared to be deployed with the ArgoCD
+// applications that exist for it
+type Report struct {
+	Repo string `json:"repo"`
+	// Selector is how the repo's applications were found: a label selector,
+	// or "prefix" for apps named <repo>-
+	Selector string `json:"selector"`
+	// InSync is set when every declared location has an app in its namespace
+	// on its instance, every app is declared and every instance answered
+	InSync              bool                `json:"inSync"`
+	Matched             []Deployed          `json:"matched"`
+	NotDeployed         []Declared          `json:"notDeployed"`
+	Undeclared          []Deployed          `json:"undeclared"`
+	NamespaceMismatches []NamespaceMismatch `json:"namespaceMismatches"`
+	InstanceMismatches  []InstanceMismatch  `json:"instanceMismatches"`
+	// Unreachable lists the instances that could not be listed, whose apps
+	// may show up as not deployed
+	Unreachable []string `json:"unreachable,omitempty"`
+}
+
+// Select picks the applications of repo out of a list. With a label, the list
+// is assumed to come from a label selector already and is kept whole. Without
+// one, apps are those named <repo>-<suffix>, except the ones that belong to a
+// longer repo name that shares the prefix, e.g. crm-core-bff for crm-core.
+func Select(repo string, apps []Application, label string, repoNames []string) []Application {
+	if label != "" {
+		return apps
+	}
+
+	var longer []string
+	for _, name := range repoNames {
+		if name != repo && strings.HasPrefix(name, repo+"-") {
+			longer = append(longer, name+"-")
+		}
+	}
+
+	var selected []Application
+	for _, app := range apps {
+		if !strings.HasPrefix(app.Name, repo+"-") {
+			continue
+		}
+		owned := true
+		for _, prefix := range longer {
+			if strings.HasPrefix(app.Name, prefix) {
+				owned = false
+				break
+			}
+		}
+		if owned {
+			selected = append(selected, app)
+		}
+	}
+	return selected
+}
+
+// Build matches the declared locations to the repo's applications by app name
+func Build(repo, selector string, declared []Declared, apps []Application, unreachable []string) Report {
+	report := Report{
+		Repo:                repo,
+		Selector:            selector,
+		Matched:             []Deployed{},
+		NotDeployed:         []Declared{},
+		Undeclared:          []Deployed{},
+		NamespaceMismatches: []NamespaceMismatch{},
+		InstanceMismatches:  []InstanceMismatch{},
+		Unreachable:         unreachable,
+	}
+
+	byName := make(map[string][]Application)
+	for _, app := range apps {
+		byName[app.Name] = append(byName[app.Name], app)
+	}
+
+	declaredNames := make(map[string]bool)
+	for _, location := range declared {
+		name := location.AppName(repo)
+		declaredNames[name] = true
+
+		candidates := byName[name]
+		if len(candidates) == 0 {
+			report.NotDeployed = append(report.NotDeployed, location)
+			continue
+		}
+		// An app on several instances is matched on the one it is routed to
+		app := candidates[0]
+		for _, candidate := range candidates {
+			if candidate.Instance == location.Instance {
+				app = candidate
+			}
+		}
+		report.Matched = append(report.Matched, deployed(repo, app))
+		if location.Namespace != "" && app.Namespace != location.Namespace {
+			report.NamespaceMismatches = append(report.NamespaceMismatches, NamespaceMismatch{
+				App:      name,
+				Declared: location.Namespace,
+				Deployed: app.Namespace,
+			})
+		}
+		if location.Instance != "" && app.Instance != "" && app.Instance != location.Instance {
+			report.InstanceMismatches = append(report.InstanceMismatches, InstanceMismatch{
+				App:      name,
+				Routed:   location.Instance,
+				Deployed: app.Instance,
+			})
+		}
+	}
+
+	for _, app := range apps {
+		if !declaredNames[app.Name] {
+			report.Undeclared = append(report.Undeclared, deployed(repo, app))
+		}
+	}
+
+	sort.Slice(report.Matched, func(i, j int) bool { return report.Matched[i].App < report.Matched[j].App })
+	sort.Slice(report.NotDeployed, func(i, j int) bool { return report.NotDeployed[i].Key() < report.NotDeployed[j].Key() })
+	sort.Slice(report.Undeclared, func(i, j int) bool { return report.Undeclared[i].App < report.Undeclared[j].App })
+	sort.Slice(report.NamespaceMismatches, func(i, j int) bool {
+		return report.NamespaceMismatches[i].App < report.NamespaceMismatches[j].App
+	})
+	sort.Slice(report.InstanceMismatches, func(i, j int) bool {
+		return report.InstanceMismatches[i].App < report.InstanceMismatches[j].App
+	})
+
+	report.InSync = len(report.NotDeployed) == 0 && len(report.Undeclared) == 0 &&
+		len(report.NamespaceMismatches) == 0 && len(report.InstanceMismatches) == 0 && len(unreachable) == 0
+	return report
+}
+
+func deployed(repo string, app Application) Deployed {
+	result := Deployed{App: app.Name, Namespace: app.Namespace, Instance: app.Instance}
+	if location, ok := regions.ParseAppName(repo, app.Name); ok {
+		result.Account, result.Region = location.Account, location.Region
+	}
+	return result
+}
@@ -0,0 +1,173 @@
+package reconcile
+
+import (
+	"argocd/pkg/regions"
+	"reflect"
+	"testing"
+)
+
+func names(apps []Application) []string {
+	var result []string
+	for _, app := range apps {
+		result = append(result, app.Name)
+	}
+	return result
+}
+
+func TestSelect(t *testing.T) {
+	apps := []Application{
+		{Name: "crm-core-prod-sa-east-1"},
+		{Name: "crm-core-bff-prod-sa-east-1"},
+		{Name: "crm-core-bff-ext-sa-east-1"},
+		{Name: "crm-core-worker-prod-sa-east-1"},
+		{Name: "crm-corezilla-prod-sa-east-1"},
+		{Name: "psm-accounts-prod-sa-east-1"},
+	}
+	repoNames := []string{"crm-core", "crm-core-bff", "crm-corezilla", "psm-accounts"}
+
+	cases := []struct {
+		name  string
+		repo  string
+		label string
+		want  []string
+	}{
+		// crm-core-worker is not a catalog repo, so its apps are crm-core's
+		{"longer repo names keep their apps", "crm-core", "", []string{"crm-core-prod-sa-east-1", "crm-core-worker-prod-sa-east-1"}},
+		{"the longer repo", "crm-core-bff", "", []string{"crm-core-bff-prod-sa-east-1", "crm-core-bff-ext-sa-east-1"}},
+		{"no dash, no prefix", "crm-corezilla", "", []string{"crm-corezilla-prod-sa-east-1"}},
+		{"no apps", "console-audit-bff", "", nil},
+		{"a label selector is trusted whole", "crm-core", "lighthouse.pismo.io/repo", names(apps)},
+	}
+	for _, c := range cases {
+		if got := names(Select(c.repo, apps, c.label, repoNames)); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%s: selected %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	prod := Declared{Location: regions.NewLocation("prod", "sa-east-1"), Namespace: "crm", Instance: "default"}
+	ext := Declared{Location: regions.NewLocation("ext", "sa-east-1"), Namespace: "crm", Instance: "default"}
+	gov := Declared{Location: regions.NewLocation("prod", "us-gov-west-1"), Namespace: "crm", Instance: "gov"}
+
+	cases := []struct {
+		name        string
+		declared    []Declared
+		apps        []Application
+		unreachable []string
+		check       func(t *testing.T, report Report)
+	}{
+		{
+			name:     "in sync",
+			declared: []Declared{prod, gov},
+			apps: []Application{
+				{Name: "crm-core-prod-sa-east-1", Namespace: "crm", Instance: "default"},
+				{Name: "crm-core-prod-us-gov-west-1", Namespace: "crm", Instance: "gov"},
+			},
+			check: func(t *testing.T, report Report) {
+				want := []Deployed{
+					{App: "crm-core-prod-sa-east-1", Account: "prod", Region: "sa-east-1", Namespace: "crm", Instance: "default"},
+					{App: "crm-core-prod-us-gov-west-1", Account: "prod", Region: "us-gov-west-1", Namespace: "crm", Instance: "gov"},
+				}
+				if !report.InSync || !reflect.DeepEqual(report.Matched, want) {
+					t.Errorf("report = %+v", report)
+				}
+			},
+		},
+		{
+			name:     "not deployed and undeclared",
+			declared: []Declared{prod, ext},
+			apps: []Application{
+				{Name: "crm-core-prod-sa-east-1", Namespace: "crm", Instance: "default"},
+				{Name: "crm-core-sandbox-us-east-1", Namespace: "crm", Instance: "default"},
+			},
+			check: func(t *testing.T, report Report) {
+				if report.InSync || len(report.NotDeployed) != 1 || report.NotDeployed[0].Key() != "ext-sa-east-1" {
+					t.Errorf("not deployed = %+v", report.NotDeployed)
+				}
+				if len(report.Undeclared) != 1 || report.Undeclared[0].Account != "sandbox" || report.Undeclared[0].Region != "us-east-1" {
+					t.Errorf("undeclared = %+v", report.Undeclared)
+				}
+			},
+		},
+		{
+			name:     "namespace and instance mismatches",
+			declared: []Declared{prod, gov},
+			apps: []Application{
+				{Name: "crm-core-prod-sa-east-1", Namespace: "default", Instance: "default"},
+				{Name: "crm-core-prod-us-gov-west-1", Namespace: "crm", Instance: "default"},
+			},
+			check: func(t *testing.T, report Report) {
+				if report.InSync || len(report.Matched) != 2 {
+					t.Errorf("report = %+v", report)
+				}
+				if !reflect.DeepEqual(report.NamespaceMismatches, []NamespaceMismatch{{App: "crm-core-prod-sa-east-1", Declared: "crm", Deployed: "default"}}) {
+					t.Errorf("namespace mismatches = %+v", report.NamespaceMismatches)
+				}
+				if !reflect.DeepEqual(report.InstanceMismatches, []InstanceMismatch{{App: "crm-core-prod-us-gov-west-1", Routed: "gov", Deployed: "default"}}) {
+					t.Errorf("instance mismatches = %+v", report.InstanceMismatches)
+				}
+			},
+		},
+		{
+			// The copy on the routed instance is the match
+			name:     "app on several instances",
+			declared: []Declared{gov},
+			apps: []Application{
+				{Name: "crm-core-prod-us-gov-west-1", Namespace: "old", Instance: "default"},
+				{Name: "crm-core-prod-us-gov-west-1", Namespace: "crm", Instance: "gov"},
+			},
+			check: func(t *testing.T, report Report) {
+				if !report.InSync || len(report.Matched) != 1 || report.Matched[0].Instance != "gov" {
+					t.Errorf("report = %+v", report)
+				}
+			},
+		},
+		{
+			name:        "an unreachable instance is never in sync",
+			declared:    []Declared{prod},
+			apps:        []Application{{Name: "crm-core-prod-sa-east-1", Namespace: "crm", Instance: "default"}},
+			unreachable: []string{"gov"},
+			check: func(t *testing.T, report Report) {
+				if report.InSync || !reflect.DeepEqual(report.Unreachable, []string{"gov"}) {
+					t.Errorf("report = %+v", report)
+				}
+			},
+		},
+		{
+			name: "nothing declared or deployed",
+			check: func(t *testing.T, report Report) {
+				if !report.InSync || report.Matched == nil || report.NotDeployed == nil || report.Undeclared == nil {
+					t.Errorf("report = %+v, want empty lists", report)
+				}
+			},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			report := Build("crm-core", "prefix", c.declared, c.apps, c.unreachable)
+			if report.Repo != "crm-core" || report.Selector != "prefix" {
+				t.Errorf("repo = %s, selector = %s", report.Repo, report.Selector)
+			}
+			c.check(t, report)
+		})
+	}
+}
+
+func TestParseApplications(t *testing.T) {
+	apps, err := ParseApplications("gov", []byte(`{"items":[
+		{"metadata":{"name":"crm-core-prod-us-gov-west-1","labels":{"team":"crm"}},
+		 "spec":{"destination":{"namespace":"crm","server":"https://kubernetes.default.svc"}}},
+		{"metadata":{"name":"crm-core-dev-us-gov-west-1"},"spec":{"destination":{"namespace":"crm","name":"eks-dev"}}}
+	]}`))
+	if err != nil {
+		t.Fatalf("ParseApplications: %v", err)
+	}
+	want := []Application{
+		{Name: "crm-core-prod-us-gov-west-1", Labels: map[string]string{"team": "crm"}, Namespace: "crm", Server: "https://kubernetes.default.svc", Instance: "gov"},
+		{Name: "crm-core-dev-us-gov-west-1", Namespace: "crm", Server: "eks-dev", Instance: "gov"},
+	}
+	if !reflect.DeepEqual(apps, want) {
+		t.Errorf("apps = %+v, want %+v", apps, want)
+	}
+}
@@ -0,0 +1,89 @@
+// pkg/regions/location.go
+package regions
+
+import (
+	"regexp"
+	"strings"
+)
+
+// awsRegionRe matches the region at the end of an app name suffix, e.g. "-prod-sa-east-1"
+var awsRegionRe = regexp.MustCompile(`-([a-z]{2}(?:-gov)?-[a-z]+-\d+)$`)
+
+// Location is one account and region a repo is deployed to. Its ArgoCD app is
+// named <repo>-<account>-<region>.
+type Location struct {
+	Account string `json:"account"`
+	Region  string `json:"region"`
+	// Env is the tier of the account: prod, stag, ext, integration or dev,
+	// or the account itself when it does not name one
+	Env     string `json:"env"`
+	Primary bool   `json:"primary"`
+}
+
+// NewLocation builds the primary location of an account and region
+func NewLocation(account, region string) Location {
+	return Location{Account: account, Region: region, Env: EnvironmentOf(account), Primary: true}
+}
+
+// Suffix is what the repo name is followed by in the ArgoCD app name
+func (l Location) Suffix() string {
+	return "-" + l.Account + "-" + l.Region
+}
+
+// Key identifies the location on its own, e.g. "dev-ext-sa-east-1"
+func (l Location) Key() string {
+	return l.Account + "-" + l.Region
+}
+
+func (l Location) AppName(repo string) string {
+	return repo + l.Suffix()
+}
+
+// ParseSuffix reads a location back from an app name suffix such as
+// "-dev-ext-sa-east-1". The region is whatever AWS region ends the suffix, so
+// accounts may contain any number of dashes. The bool is false when the
+// suffix does not end in a region; the whole suffix is then the account.
+func ParseSuffix(suffix string) (Location, bool) {
+	match := awsRegionRe.FindStringSubmatchIndex(suffix)
+	if match == nil {
+		return NewLocation(strings.TrimPrefix(suffix, "-"), ""), false
+	}
+	return NewLocation(strings.TrimPrefix(suffix[:match[0]], "-"), suffix[match[2]:match[3]]), true
+}
+
+// ParseAppName reads the location of one of repo's ArgoCD apps from its name
+func ParseAppName(repo, appName string) (Location, bool) {
+	if !strings.HasPrefix(appName, repo+"-") {
+		return Location{}, false
+	}
+	return ParseSuffix(strings.TrimPrefix(appName, repo))
+}
+
+// envTiers maps the words found in account names to their tier, in the order
+// they are looked for; "dev-ext" is ext, not dev
+var envTiers = []struct {
+	tier  string
+	words []string
+}{
+	{"prod", []string{"prod", "production", "prd"}},
+	{"stag", []string{"stag", "staging", "stg"}},
+	{"ext", []string{"ext"}},
+	{"integration", []string{"integration", "int"}},
+	{"dev", []string{"dev", "development"}},
+}
+
+// EnvironmentOf returns the tier an account belongs to, e.g. "prod" for
+// "aus-prod" and "ext" for "dev-ext"
+func EnvironmentOf(account string) string {
+	words := strings.Split(account, "-")
+	for _, tier := range envTiers {
+		for _, word := range words {
+			for _, candidate := range tier.words {
+				if word == candidate {
+					return tier.tier
+				}
+			}
+		}
+	}
+	return account
+}
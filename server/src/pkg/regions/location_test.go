@@ -0,0 +1,68 @@
+package regions
+
+import "testing"
+
+func TestParseAppName(t *testing.T) {
+	cases := []struct {
+		repo, app string
+		want      Location
+		ok        bool
+	}{
+		{"crm-core-bff", "crm-core-bff-prod-sa-east-1", Location{Account: "prod", Region: "sa-east-1", Env: "prod", Primary: true}, true},
+		{"console-audit-bff", "console-audit-bff-dev-ext-sa-east-1", Location{Account: "dev-ext", Region: "sa-east-1", Env: "ext", Primary: true}, true},
+		{"console-audit-bff", "console-audit-bff-prod-us-gov-west-1", Location{Account: "prod", Region: "us-gov-west-1", Env: "prod", Primary: true}, true},
+		{"crm-core", "crm-core-aus-prod-ap-southeast-2", Location{Account: "aus-prod", Region: "ap-southeast-2", Env: "prod", Primary: true}, true},
+		// Without a region the whole suffix is the account
+		{"crm-core", "crm-core-sandbox", Location{Account: "sandbox", Env: "sandbox", Primary: true}, false},
+		{"crm-core", "crm-core-bff-prod", Location{Account: "bff-prod", Env: "prod", Primary: true}, false},
+		// Not the repo's
+		{"crm-core", "crm-corezilla-prod-sa-east-1", Location{}, false},
+		{"crm-core", "crm-core", Location{}, false},
+	}
+	for _, c := range cases {
+		got, ok := ParseAppName(c.repo, c.app)
+		if got != c.want || ok != c.ok {
+			t.Errorf("ParseAppName(%q, %q) = %+v, %v, want %+v, %v", c.repo, c.app, got, ok, c.want, c.ok)
+		}
+	}
+}
+
+func TestParseSuffixRoundTrips(t *testing.T) {
+	for _, location := range []Location{
+		NewLocation("prod", "sa-east-1"),
+		NewLocation("dev-ext", "sa-east-1"),
+		NewLocation("prod", "us-gov-west-1"),
+	} {
+		got, ok := ParseSuffix(location.Suffix())
+		if !ok || got != location {
+			t.Errorf("ParseSuffix(%q) = %+v, %v, want %+v", location.Suffix(), got, ok, location)
+		}
+		if location.AppName("crm-core-bff") != "crm-core-bff"+location.Suffix() || location.Key() != location.Account+"-"+location.Region {
+			t.Errorf("app name = %s, key = %s", location.AppName("crm-core-bff"), location.Key())
+		}
+	}
+}
+
+func TestEnvironmentOf(t *testing.T) {
+	for account, want := range map[string]string{
+		"prod":          "prod",
+		"aus-prod":      "prod",
+		"production":    "prod",
+		"prd-latam":     "prod",
+		"staging":       "stag",
+		"stg":           "stag",
+		"dev-ext":       "ext",
+		"ext":           "ext",
+		"int":           "integration",
+		"dev":           "dev",
+		"development":   "dev",
+		"sandbox":       "sandbox",
+		"products":      "products",
+		"prod-dev-test": "prod",
+		"":              "",
+	} {
+		if got := EnvironmentOf(account); got != want {
+			t.Errorf("EnvironmentOf(%q) = %q, want %q", account, got, want)
+		}
+	}
+}
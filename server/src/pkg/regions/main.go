@@ -13,6 +13,9 @@ type RegionDetails struct {
 	RegionDefault  string `json:"region_default"`
 	AccountDefault string `json:"account_default"`
 	Namespace      string `json:"namespace"`
+	// FailoverRegion is the DR region of this account and region, declared
+	// with a failover_region variable
+	FailoverRegion string `json:"failover_region,omitempty"`
 }
 
 // ParseRegions returns the region configuration for a given repository
@@ -52,6 +55,7 @@ func ParseRegions(baseRepoName string) ([]RegionDetails, error) {
 		// Extract values using regex
 		config.RegionDefault = extractValue(string(varsContent), "variable \"region\"", "default")
 		config.AccountDefault = extractValue(string(varsContent), "variable \"account\"", "default")
+		config.FailoverRegion = extractValue(string(varsContent), "variable \"failover_region\"", "default")
 
 		// Try to get namespace from providers.tf if it exists
 		providersPath := filepath.Join(path, "providers.tf")
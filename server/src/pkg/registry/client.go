@@ -0,0 +1,232 @@
+// pkg/registry/client.go
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ImageInfo is what the registry knows about a deployed image
+type ImageInfo struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest,omitempty"`
+	// Pushed is the push date recorded by ECR; other registries do not record
+	// one, so it is the build date from the image config instead
+	Pushed *time.Time   `json:"pushed,omitempty"`
+	Size   int64        `json:"size,omitempty"`
+	Scan   *ScanSummary `json:"scan,omitempty"`
+	Error  string       `json:"error,omitempty"`
+}
+
+// ScanSummary counts the vulnerability findings of an image by severity
+type ScanSummary struct {
+	Status    string         `json:"status"`
+	Findings  map[string]int `json:"findings,omitempty"`
+	Completed *time.Time     `json:"completed,omitempty"`
+}
+
+// RegistryConfig overrides how one registry host is reached
+type RegistryConfig struct {
+	Host string `json:"host"`
+	// Type is ecr or distribution; by default hosts named like
+	// <account>.dkr.ecr.<region>.amazonaws.com are ecr
+	Type string `json:"type,omitempty"`
+	// Endpoint replaces https://<host>, e.g. to send an ECR host to a local
+	// registry:2 stand-in of type distribution
+	Endpoint string `json:"endpoint,omitempty"`
+	// Insecure talks plain http, e.g. to a local registry:2 on localhost:5000
+	Insecure bool `json:"insecure,omitempty"`
+	// Username and the password read from PasswordFile answer auth challenges
+	Username     string `json:"username,omitempty"`
+	PasswordFile string `json:"passwordFile,omitempty"`
+	// ECREndpoint replaces https://api.ecr.<region>.amazonaws.com for ECR hosts
+	ECREndpoint string `json:"ecrEndpoint,omitempty"`
+}
+
+type Config struct {
+	Registries []RegistryConfig `json:"registries,omitempty"`
+	// CacheTTL is how long a tag's digest is trusted, e.g. "5m"
+	CacheTTL string `json:"cacheTTL,omitempty"`
+}
+
+func LoadConfig(configPath string) (*Config, error) {
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading registries config: %v", err)
+	}
+
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("error parsing registries config: %v", err)
+	}
+	for i, registry := range config.Registries {
+		if registry.Host == "" {
+			return nil, fmt.Errorf("registry %d has no host", i)
+		}
+		if registry.Type != "" && registry.Type != "ecr" && registry.Type != "distribution" {
+			return nil, fmt.Errorf("registry %s has unknown type %q", registry.Host, registry.Type)
+		}
+	}
+	if config.CacheTTL != "" {
+		if _, err := time.ParseDuration(config.CacheTTL); err != nil {
+			return nil, fmt.Errorf("invalid cacheTTL %q in registries config", config.CacheTTL)
+		}
+	}
+	return &config, nil
+}
+
+// Client resolves image references against their registries. ECR hosts are
+// recognised by name and use the AWS credentials for both the ECR API and
+// the dkr.ecr endpoint; every other host is a plain distribution registry.
+type Client struct {
+	config      Config
+	credentials AWSCredentials
+	httpClient  *http.Client
+	cacheTTL    time.Duration
+
+	mu         sync.Mutex
+	registries map[string]*distribution
+	ecrAPIs    map[string]*ecrAPI
+	cache      map[string]cachedInfo
+}
+
+type cachedInfo struct {
+	info    ImageInfo
+	expires time.Time
+}
+
+func NewClient(config Config, credentials AWSCredentials) *Client {
+	cacheTTL := 5 * time.Minute
+	if parsed, err := time.ParseDuration(config.CacheTTL); err == nil && config.CacheTTL != "" {
+		cacheTTL = parsed
+	}
+	return &Client{
+		config:      config,
+		credentials: credentials,
+		httpClient:  &http.Client{Timeout: 30 * time.Second},
+		cacheTTL:    cacheTTL,
+		registries:  make(map[string]*distribution),
+		ecrAPIs:     make(map[string]*ecrAPI),
+		cache:       make(map[string]cachedInfo),
+	}
+}
+
+// Resolve looks an image up in its registry. The returned info always names
+// the image; err says why the rest is missing.
+func (c *Client) Resolve(ctx context.Context, image string) (ImageInfo, error) {
+	info := ImageInfo{Image: image}
+
+	c.mu.Lock()
+	cached, ok := c.cache[image]
+	c.mu.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return cached.info, nil
+	}
+
+	ref, err := ParseReference(image)
+	if err != nil {
+		return info, err
+	}
+
+	registry, ecr, err := c.registryFor(ref)
+	if err != nil {
+		return info, err
+	}
+
+	digest, size, created, err := registry.inspect(ctx, ref)
+	if err != nil {
+		return info, err
+	}
+	info.Digest, info.Size, info.Pushed = digest, size, created
+
+	if ecr != nil {
+		detail, err := ecr.describeImage(ctx, ref)
+		if err != nil {
+			return info, err
+		}
+		pushed := epoch(detail.ImagePushedAt)
+		info.Pushed = &pushed
+		if detail.ImageSizeInBytes > 0 {
+			info.Size = detail.ImageSizeInBytes
+		}
+		info.Scan = scanSummary(detail)
+	}
+
+	c.mu.Lock()
+	c.cache[image] = cachedInfo{info: info, expires: time.Now().Add(c.cacheTTL)}
+	c.mu.Unlock()
+	return info, nil
+}
+
+func scanSummary(detail *ecrImageDetail) *ScanSummary {
+	if detail.ImageScanStatus == nil && detail.ImageScanFindingsSummary == nil {
+		return nil
+	}
+	scan := &ScanSummary{Status: "COMPLETE"}
+	if detail.ImageScanStatus != nil {
+		scan.Status = detail.ImageScanStatus.Status
+	}
+	if summary := detail.ImageScanFindingsSummary; summary != nil {
+		scan.Findings = summary.FindingSeverityCounts
+		if summary.ImageScanCompletedAt > 0 {
+			completed := epoch(summary.ImageScanCompletedAt)
+			scan.Completed = &completed
+		}
+	}
+	return scan
+}
+
+// registryFor returns the distribution client of ref's host, and the ECR API
+// client when the host is an ECR registry
+func (c *Client) registryFor(ref Reference) (*distribution, *ecrAPI, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	registry, known := c.registries[ref.Host]
+	ecr := c.ecrAPIs[ref.Host]
+	if known {
+		return registry, ecr, nil
+	}
+
+	var config RegistryConfig
+	for _, candidate := range c.config.Registries {
+		if candidate.Host == ref.Host {
+			config = candidate
+		}
+	}
+
+	var credentials credentialsFunc
+	account, region, isECR := ref.ECR()
+	switch {
+	case config.Type == "ecr" && !isECR:
+		return nil, nil, fmt.Errorf("registry %s is not an ECR host", ref.Host)
+	case isECR && config.Type != "distribution":
+		ecr = newECRAPI(config.ECREndpoint, account, region, c.credentials, c.httpClient)
+		credentials = ecr.registryCredentials
+	case config.Username != "":
+		password := ""
+		if config.PasswordFile != "" {
+			data, err := os.ReadFile(config.PasswordFile)
+			if err != nil {
+				return nil, nil, fmt.Errorf("error reading password file of registry %s: %v", ref.Host, err)
+			}
+			password = strings.TrimSpace(string(data))
+		}
+		credentials = func(context.Context) (string, string, error) {
+			return config.Username, password, nil
+		}
+	}
+
+	registry = newDistribution(ref.Host, config.Endpoint, config.Insecure, c.httpClient, credentials)
+	c.registries[ref.Host] = registry
+	if ecr != nil {
+		c.ecrAPIs[ref.Host] = ecr
+	}
+	return registry, ecr, nil
+}
@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const (
+	indexDigest  = "sha256:1111111111111111111111111111111111111111111111111111111111111111"
+	amd64Digest  = "sha256:2222222222222222222222222222222222222222222222222222222222222222"
+	arm64Digest  = "sha256:3333333333333333333333333333333333333333333333333333333333333333"
+	configDigest = "sha256:4444444444444444444444444444444444444444444444444444444444444444"
+)
+
+// registryStandIn serves backoffice-core-bff:1.24.0 as an image index the way
+// a registry:2 behind a token service does. With username set it answers
+// with a basic challenge instead.
+type registryStandIn struct {
+	*httptest.Server
+	username, password string
+
+	mu            sync.Mutex
+	tokenRequests int
+	tokenQuery    string
+}
+
+func newRegistryStandIn(t *testing.T, username, password string) *registryStandIn {
+	t.Helper()
+	standIn := &registryStandIn{username: username, password: password}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		standIn.mu.Lock()
+		standIn.tokenRequests++
+		standIn.tokenQuery = r.URL.RawQuery
+		standIn.mu.Unlock()
+		fmt.Fprint(w, `{"token":"pull-token","expires_in":300}`)
+	})
+	mux.HandleFunc("/v2/", func(w http.ResponseWriter, r *http.Request) {
+		if !standIn.authorized(r) {
+			if standIn.username != "" {
+				w.Header().Set("WWW-Authenticate", `Basic realm="registry"`)
+			} else {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.local",scope="repository:backoffice-core-bff:pull"`, standIn.URL))
+			}
+			http.Error(w, `{"errors":[{"code":"UNAUTHORIZED"}]}`, http.StatusUnauthorized)
+			return
+		}
+
+		switch strings.TrimPrefix(r.URL.Path, "/v2/backoffice-core-bff") {
+		case "/manifests/1.24.0", "/manifests/" + indexDigest:
+			if !strings.Contains(r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json") {
+				http.Error(w, "index not accepted", http.StatusNotAcceptable)
+				return
+			}
+			w.Header().Set("Docker-Content-Digest", indexDigest)
+			fmt.Fprintf(w, `{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[
+				{"digest":"%s","platform":{"architecture":"arm64","os":"linux"}},
+				{"digest":"%s","platform":{"architecture":"amd64","os":"linux"}}
+			]}`, arm64Digest, amd64Digest)
+		case "/manifests/" + amd64Digest:
+			fmt.Fprintf(w, `{"mediaType":"application/vnd.oci.image.manifest.v1+json",
+				"config":{"digest":"%s","size":100},"layers":[{"size":1000},{"size":2000}]}`, configDigest)
+		case "/blobs/" + configDigest:
+			fmt.Fprint(w, `{"created":"2026-10-18T21:04:00Z","architecture":"amd64"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+	standIn.Server = httptest.NewServer(mux)
+	t.Cleanup(standIn.Close)
+	return standIn
+}
+
+func (s *registryStandIn) authorized(r *http.Request) bool {
+	if s.username != "" {
+		username, password, ok := r.BasicAuth()
+		return ok && username == s.username && password == s.password
+	}
+	return r.Header.Get("Authorization") == "Bearer pull-token"
+}
+
+func TestResolveAnswersABearerChallengeAndPicksTheAMD64Manifest(t *testing.T) {
+	standIn := newRegistryStandIn(t, "", "")
+	client := NewClient(Config{Registries: []RegistryConfig{{Host: "registry.local:5000", Endpoint: standIn.URL}}}, AWSCredentials{})
+
+	info, err := client.Resolve(context.Background(), "registry.local:5000/backoffice-core-bff:1.24.0")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	// The digest is the index's, as docker pull reports it, and the size that of linux/amd64
+	if info.Digest != indexDigest || info.Size != 3100 {
+		t.Errorf("info = %+v", info)
+	}
+	if info.Pushed == nil || !info.Pushed.Equal(time.Date(2026, 10, 18, 21, 4, 0, 0, time.UTC)) {
+		t.Errorf("pushed = %v, want the config's creation time", info.Pushed)
+	}
+	if standIn.tokenQuery != "scope=repository%3Abackoffice-core-bff%3Apull&service=registry.local" {
+		t.Errorf("token query = %s", standIn.tokenQuery)
+	}
+
+	// The token is reused for the repository until it expires
+	if _, err := client.Resolve(context.Background(), "registry.local:5000/backoffice-core-bff@"+indexDigest); err != nil {
+		t.Fatalf("Resolve by digest: %v", err)
+	}
+	if standIn.tokenRequests != 1 {
+		t.Errorf("%d token requests, want 1", standIn.tokenRequests)
+	}
+
+	if _, err := client.Resolve(context.Background(), "registry.local:5000/backoffice-core-bff:9.9.9"); err == nil {
+		t.Error("unknown tag resolved")
+	}
+}
+
+func TestResolveAnswersABasicChallenge(t *testing.T) {
+	standIn := newRegistryStandIn(t, "lighthouse", "s3cret")
+	passwordFile := filepath.Join(t.TempDir(), "password")
+	if err := os.WriteFile(passwordFile, []byte("s3cret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient(Config{Registries: []RegistryConfig{{Host: "registry.local:5000", Endpoint: standIn.URL, Username: "lighthouse", PasswordFile: passwordFile}}}, AWSCredentials{})
+	if info, err := client.Resolve(context.Background(), "registry.local:5000/backoffice-core-bff:1.24.0"); err != nil || info.Digest != indexDigest {
+		t.Errorf("info = %+v, err = %v", info, err)
+	}
+
+	anonymous := NewClient(Config{Registries: []RegistryConfig{{Host: "registry.local:5000", Endpoint: standIn.URL}}}, AWSCredentials{})
+	if _, err := anonymous.Resolve(context.Background(), "registry.local:5000/backoffice-core-bff:1.24.0"); err == nil || !strings.Contains(err.Error(), "requires credentials") {
+		t.Errorf("err = %v, want credentials required", err)
+	}
+}
+
+func TestResolveReadsECRThroughItsAPI(t *testing.T) {
+	standIn := newRegistryStandIn(t, "AWS", "ecr-password")
+
+	var actions []string
+	var mu sync.Mutex
+	ecrAPI := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/") {
+			http.Error(w, `{"__type":"UnrecognizedClientException","message":"unsigned"}`, http.StatusForbidden)
+			return
+		}
+		action := strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "AmazonEC2ContainerRegistry_V20150921.")
+		mu.Lock()
+		actions = append(actions, action)
+		mu.Unlock()
+
+		body, _ := io.ReadAll(r.Body)
+		var request map[string]interface{}
+		json.Unmarshal(body, &request)
+		switch action {
+		case "GetAuthorizationToken":
+			token := base64.StdEncoding.EncodeToString([]byte("AWS:ecr-password"))
+			fmt.Fprintf(w, `{"authorizationData":[{"authorizationToken":"%s","expiresAt":%d}]}`, token, time.Now().Add(12*time.Hour).Unix())
+		case "DescribeImages":
+			if request["repositoryName"] != "backoffice-core-bff" || request["registryId"] != "123456789012" {
+				http.Error(w, `{"__type":"RepositoryNotFoundException"}`, http.StatusBadRequest)
+				return
+			}
+			fmt.Fprint(w, `{"imageDetails":[{"imageDigest":"`+indexDigest+`","imageSizeInBytes":4096,"imagePushedAt":1760821440,
+				"imageScanStatus":{"status":"COMPLETE"},
+				"imageScanFindingsSummary":{"imageScanCompletedAt":1760821500,"findingSeverityCounts":{"HIGH":2}}}]}`)
+		default:
+			http.Error(w, `{"__type":"UnknownOperationException"}`, http.StatusBadRequest)
+		}
+	}))
+	t.Cleanup(ecrAPI.Close)
+
+	host := "123456789012.dkr.ecr.sa-east-1.amazonaws.com"
+	client := NewClient(Config{Registries: []RegistryConfig{{Host: host, Endpoint: standIn.URL, ECREndpoint: ecrAPI.URL}}}, exampleCredentials)
+	info, err := client.Resolve(context.Background(), host+"/backoffice-core-bff:1.24.0")
+	if err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+
+	// ECR's push date and size replace the ones read from the image
+	if info.Digest != indexDigest || info.Size != 4096 || info.Pushed == nil || info.Pushed.Unix() != 1760821440 {
+		t.Errorf("info = %+v", info)
+	}
+	if info.Scan == nil || info.Scan.Status != "COMPLETE" || info.Scan.Findings["HIGH"] != 2 || info.Scan.Completed == nil {
+		t.Errorf("scan = %+v", info.Scan)
+	}
+	if strings.Join(actions, ",") != "GetAuthorizationToken,DescribeImages" {
+		t.Errorf("ECR actions = %v", actions)
+	}
+
+	unsigned := NewClient(Config{Registries: []RegistryConfig{{Host: host, Endpoint: standIn.URL, ECREndpoint: ecrAPI.URL}}}, AWSCredentials{})
+	if _, err := unsigned.Resolve(context.Background(), host+"/backoffice-core-bff:1.24.0"); err == nil || !strings.Contains(err.Error(), "no AWS credentials") {
+		t.Errorf("err = %v, want missing AWS credentials", err)
+	}
+}
@@ -0,0 +1,297 @@
+// pkg/registry/distribution.go
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// manifestMediaTypes are the manifests asked for, indexes first so the
+// digest is the one `docker pull` reports for multi-platform images
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+type descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	Platform  *struct {
+		Architecture string `json:"architecture"`
+		OS           string `json:"os"`
+	} `json:"platform,omitempty"`
+}
+
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+func (m manifest) isIndex() bool {
+	return len(m.Manifests) > 0
+}
+
+// credentialsFunc returns the basic auth credentials of a registry, if any
+type credentialsFunc func(ctx context.Context) (username, password string, err error)
+
+// distribution talks the OCI distribution API to one registry host. It
+// answers basic and bearer token challenges, so the same code serves a local
+// registry:2, Docker Hub, and ECR with the credentials of its authorization token.
+type distribution struct {
+	baseURL     string
+	httpClient  *http.Client
+	credentials credentialsFunc
+
+	mu     sync.Mutex
+	tokens map[string]bearerToken // by scope
+}
+
+type bearerToken struct {
+	token   string
+	expires time.Time
+}
+
+func newDistribution(host, endpoint string, insecure bool, httpClient *http.Client, credentials credentialsFunc) *distribution {
+	if endpoint == "" {
+		scheme := "https"
+		if insecure {
+			scheme = "http"
+		}
+		if host == dockerHub {
+			host = "registry-1.docker.io"
+		}
+		endpoint = scheme + "://" + host
+	}
+	return &distribution{
+		baseURL:     strings.TrimSuffix(endpoint, "/"),
+		httpClient:  httpClient,
+		credentials: credentials,
+		tokens:      make(map[string]bearerToken),
+	}
+}
+
+// inspect resolves ref to its digest and adds up the size of the image. For
+// multi-platform images the size is that of linux/amd64, or the first platform.
+// created is the image config's creation time, when it has one.
+func (d *distribution) inspect(ctx context.Context, ref Reference) (digest string, size int64, created *time.Time, err error) {
+	m, digest, err := d.manifest(ctx, ref.Repository, ref.manifestRef(), manifestMediaTypes)
+	if err != nil {
+		return "", 0, nil, err
+	}
+
+	image := m
+	if m.isIndex() {
+		platform := m.Manifests[0]
+		for _, candidate := range m.Manifests {
+			if candidate.Platform != nil && candidate.Platform.OS == "linux" && candidate.Platform.Architecture == "amd64" {
+				platform = candidate
+				break
+			}
+		}
+		if image, _, err = d.manifest(ctx, ref.Repository, platform.Digest, manifestMediaTypes[2:]); err != nil {
+			return "", 0, nil, err
+		}
+	}
+
+	size = image.Config.Size
+	for _, layer := range image.Layers {
+		size += layer.Size
+	}
+
+	if image.Config.Digest != "" {
+		var config struct {
+			Created *time.Time `json:"created"`
+		}
+		if body, err := d.get(ctx, ref.Repository, "/blobs/"+image.Config.Digest, nil); err == nil {
+			if json.Unmarshal(body, &config) == nil && config.Created != nil && !config.Created.IsZero() {
+				created = config.Created
+			}
+		}
+	}
+
+	return digest, size, created, nil
+}
+
+func (d *distribution) manifest(ctx context.Context, repository, reference string, accept []string) (manifest, string, error) {
+	var m manifest
+	resp, err := d.do(ctx, repository, "/manifests/"+reference, accept)
+	if err != nil {
+		return m, "", err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return m, "", fmt.Errorf("error reading manifest: %w", err)
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return m, "", fmt.Errorf("error parsing manifest: %w", err)
+	}
+
+	digest := resp.Header.Get("Docker-Content-Digest")
+	if digest == "" {
+		sum := sha256.Sum256(body)
+		digest = "sha256:" + hex.EncodeToString(sum[:])
+	}
+	return m, digest, nil
+}
+
+func (d *distribution) get(ctx context.Context, repository, path string, accept []string) ([]byte, error) {
+	resp, err := d.do(ctx, repository, path, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return io.ReadAll(resp.Body)
+}
+
+// do sends a GET for /v2/<repository><path>, answering one auth challenge
+func (d *distribution) do(ctx context.Context, repository, path string, accept []string) (*http.Response, error) {
+	endpoint := d.baseURL + "/v2/" + repository + path
+	scope := "repository:" + repository + ":pull"
+
+	send := func(authorization string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", endpoint, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error creating request: %w", err)
+		}
+		if len(accept) > 0 {
+			req.Header.Set("Accept", strings.Join(accept, ", "))
+		}
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return d.httpClient.Do(req)
+	}
+
+	resp, err := send(d.cachedAuthorization(scope))
+	if err != nil {
+		return nil, fmt.Errorf("error making request: %w", err)
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		authorization, err := d.answer(ctx, challenge, scope)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = send(authorization); err != nil {
+			return nil, fmt.Errorf("error making request: %w", err)
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry returned status %d for %s", resp.StatusCode, endpoint)
+	}
+	return resp, nil
+}
+
+func (d *distribution) cachedAuthorization(scope string) string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if token, ok := d.tokens[scope]; ok && time.Now().Before(token.expires) {
+		return token.token
+	}
+	return ""
+}
+
+var challengeParamRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// answer turns a WWW-Authenticate challenge into an Authorization header
+func (d *distribution) answer(ctx context.Context, challenge, scope string) (string, error) {
+	scheme, params, _ := strings.Cut(challenge, " ")
+	username, password := "", ""
+	if d.credentials != nil {
+		var err error
+		if username, password, err = d.credentials(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return "", fmt.Errorf("registry %s requires credentials", d.baseURL)
+		}
+		req, _ := http.NewRequest("GET", d.baseURL, nil)
+		req.SetBasicAuth(username, password)
+		authorization := req.Header.Get("Authorization")
+		d.remember(scope, authorization, time.Hour)
+		return authorization, nil
+	case "bearer":
+		values := map[string]string{}
+		for _, match := range challengeParamRe.FindAllStringSubmatch(params, -1) {
+			values[match[1]] = match[2]
+		}
+		return d.fetchToken(ctx, values["realm"], values["service"], scope, username, password)
+	}
+	return "", fmt.Errorf("unsupported registry auth challenge %q", challenge)
+}
+
+// fetchToken gets a bearer token from the registry's token service
+func (d *distribution) fetchToken(ctx context.Context, realm, service, scope, username, password string) (string, error) {
+	if realm == "" {
+		return "", fmt.Errorf("registry bearer challenge has no realm")
+	}
+	query := url.Values{"scope": {scope}}
+	if service != "" {
+		query.Set("service", service)
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", realm+"?"+query.Encode(), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating token request: %w", err)
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error fetching registry token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("registry token service returned status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("error parsing registry token: %w", err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	if token.ExpiresIn <= 0 {
+		token.ExpiresIn = 60
+	}
+
+	authorization := "Bearer " + token.Token
+	d.remember(scope, authorization, time.Duration(token.ExpiresIn)*time.Second)
+	return authorization, nil
+}
+
+func (d *distribution) remember(scope, authorization string, ttl time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	// Renew a little early so a token never expires mid-request
+	d.tokens[scope] = bearerToken{token: authorization, expires: time.Now().Add(ttl - ttl/10)}
+}
@@ -0,0 +1,252 @@
+// pkg/registry/ecr.go
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWSCredentials sign ECR API calls
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSCredentialsFromEnv reads the standard AWS_* environment variables
+func AWSCredentialsFromEnv() AWSCredentials {
+	return AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+}
+
+func (c AWSCredentials) empty() bool {
+	return c.AccessKeyID == "" || c.SecretAccessKey == ""
+}
+
+// ecrAPI calls the ECR control plane of one account and region: the
+// authorization token used against the dkr.ecr endpoint, and the push date,
+// size and scan findings the distribution API does not carry
+type ecrAPI struct {
+	endpoint    string
+	region      string
+	registryID  string
+	credentials AWSCredentials
+	httpClient  *http.Client
+
+	mu       sync.Mutex
+	username string
+	password string
+	expires  time.Time
+}
+
+func newECRAPI(endpoint, account, region string, credentials AWSCredentials, httpClient *http.Client) *ecrAPI {
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://api.ecr.%s.amazonaws.com", region)
+	}
+	return &ecrAPI{
+		endpoint:    strings.TrimSuffix(endpoint, "/"),
+		region:      region,
+		registryID:  account,
+		credentials: credentials,
+		httpClient:  httpClient,
+	}
+}
+
+// registryCredentials returns the basic auth pair for the dkr.ecr endpoint,
+// fetching a new authorization token when the last one is about to expire
+func (e *ecrAPI) registryCredentials(ctx context.Context) (string, string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.password != "" && time.Until(e.expires) > 5*time.Minute {
+		return e.username, e.password, nil
+	}
+
+	var response struct {
+		AuthorizationData []struct {
+			AuthorizationToken string  `json:"authorizationToken"`
+			ExpiresAt          float64 `json:"expiresAt"`
+		} `json:"authorizationData"`
+	}
+	request := map[string]interface{}{"registryIds": []string{e.registryID}}
+	if err := e.call(ctx, "GetAuthorizationToken", request, &response); err != nil {
+		return "", "", err
+	}
+	if len(response.AuthorizationData) == 0 {
+		return "", "", fmt.Errorf("ECR returned no authorization token")
+	}
+
+	data := response.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(data.AuthorizationToken)
+	if err != nil {
+		return "", "", fmt.Errorf("error decoding ECR authorization token: %w", err)
+	}
+	username, password, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", "", fmt.Errorf("malformed ECR authorization token")
+	}
+	e.username, e.password = username, password
+	e.expires = epoch(data.ExpiresAt)
+	return username, password, nil
+}
+
+type ecrImageDetail struct {
+	ImageDigest      string  `json:"imageDigest"`
+	ImageSizeInBytes int64   `json:"imageSizeInBytes"`
+	ImagePushedAt    float64 `json:"imagePushedAt"`
+	ImageScanStatus  *struct {
+		Status string `json:"status"`
+	} `json:"imageScanStatus"`
+	ImageScanFindingsSummary *struct {
+		ImageScanCompletedAt  float64        `json:"imageScanCompletedAt"`
+		FindingSeverityCounts map[string]int `json:"findingSeverityCounts"`
+	} `json:"imageScanFindingsSummary"`
+}
+
+// describeImage looks an image up by tag, or by digest when ref is pinned
+func (e *ecrAPI) describeImage(ctx context.Context, ref Reference) (*ecrImageDetail, error) {
+	imageID := map[string]string{"imageTag": ref.Tag}
+	if ref.Digest != "" {
+		imageID = map[string]string{"imageDigest": ref.Digest}
+	}
+	request := map[string]interface{}{
+		"registryId":     e.registryID,
+		"repositoryName": ref.Repository,
+		"imageIds":       []map[string]string{imageID},
+	}
+
+	var response struct {
+		ImageDetails []ecrImageDetail `json:"imageDetails"`
+	}
+	if err := e.call(ctx, "DescribeImages", request, &response); err != nil {
+		return nil, err
+	}
+	if len(response.ImageDetails) == 0 {
+		return nil, fmt.Errorf("ECR has no image %s", ref.Name())
+	}
+	return &response.ImageDetails[0], nil
+}
+
+// call sends a SigV4 signed JSON request to the ECR API
+func (e *ecrAPI) call(ctx context.Context, action string, request, response interface{}) error {
+	if e.credentials.empty() {
+		return fmt.Errorf("no AWS credentials for ECR in %s; set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY", e.region)
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("error marshalling ECR request: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, "POST", e.endpoint+"/", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating ECR request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "AmazonEC2ContainerRegistry_V20150921."+action)
+	signV4(req, body, e.credentials, e.region, "ecr", time.Now().UTC())
+
+	resp, err := e.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error calling ECR %s: %w", action, err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading ECR %s response: %w", action, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		var apiError struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(respBody, &apiError)
+		return fmt.Errorf("ECR %s returned status %d: %s %s", action, resp.StatusCode, apiError.Type, apiError.Message)
+	}
+	if err := json.Unmarshal(respBody, response); err != nil {
+		return fmt.Errorf("error parsing ECR %s response: %w", action, err)
+	}
+	return nil
+}
+
+// signV4 adds an AWS Signature Version 4 Authorization header to req
+func signV4(req *http.Request, body []byte, credentials AWSCredentials, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	if credentials.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		req.URL.Query().Encode(),
+		canonicalHeaders.String(),
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("AWS4"+credentials.SecretAccessKey), date)
+	for _, part := range []string{region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		credentials.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// epoch converts the fractional epoch seconds the ECR API uses for dates
+func epoch(seconds float64) time.Time {
+	return time.Unix(0, int64(seconds*float64(time.Second))).UTC()
+}
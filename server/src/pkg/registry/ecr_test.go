@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The credentials and expected signatures are those of AWS's Signature
+// Version 4 test suite and documentation
+var exampleCredentials = AWSCredentials{
+	AccessKeyID:     "AKIDEXAMPLE",
+	SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+}
+
+func TestSignV4(t *testing.T) {
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	cases := []struct {
+		name, url, region, service string
+		headers                    map[string]string
+		want                       string
+	}{
+		{
+			name: "get-vanilla", url: "https://example.amazonaws.com/", region: "us-east-1", service: "service",
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, " +
+				"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name: "IAM ListUsers", url: "https://iam.amazonaws.com/?Action=ListUsers&Version=2010-05-08", region: "us-east-1", service: "iam",
+			headers: map[string]string{"Content-Type": "application/x-www-form-urlencoded; charset=utf-8"},
+			want: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/iam/aws4_request, SignedHeaders=content-type;host;x-amz-date, " +
+				"Signature=5d672d79c15b13162d9279b0855cfba6789a8edb4c82c400e06b5924a6f2b5d7",
+		},
+	}
+	for _, c := range cases {
+		req, err := http.NewRequest(http.MethodGet, c.url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for name, value := range c.headers {
+			req.Header.Set(name, value)
+		}
+		signV4(req, nil, exampleCredentials, c.region, c.service, now)
+		if got := req.Header.Get("Authorization"); got != c.want {
+			t.Errorf("%s: Authorization =\n%s\nwant\n%s", c.name, got, c.want)
+		}
+		if req.Header.Get("X-Amz-Date") != "20150830T123600Z" {
+			t.Errorf("%s: X-Amz-Date = %s", c.name, req.Header.Get("X-Amz-Date"))
+		}
+	}
+}
+
+func TestSignV4SignsTheSessionToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "https://api.ecr.sa-east-1.amazonaws.com/", nil)
+	credentials := exampleCredentials
+	credentials.SessionToken = "session"
+	signV4(req, []byte(`{}`), credentials, "sa-east-1", "ecr", time.Now())
+
+	if req.Header.Get("X-Amz-Security-Token") != "session" ||
+		!strings.Contains(req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-date;x-amz-security-token,") {
+		t.Errorf("headers = %v", req.Header)
+	}
+}
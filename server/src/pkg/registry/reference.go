@@ -0,0 +1,97 @@
+// pkg/registry/reference.go
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const dockerHub = "docker.io"
+
+// ecrHostRe matches ECR registry hosts and captures the account and region
+var ecrHostRe = regexp.MustCompile(`^(\d{12})\.dkr\.ecr\.([a-z0-9-]+)\.amazonaws\.com$`)
+
+// Reference is a parsed image reference such as
+// 123456789012.dkr.ecr.sa-east-1.amazonaws.com/console-audit-bff:1.62.0
+type Reference struct {
+	Host       string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference follows the docker conventions: the first path component is
+// a host only when it contains a dot or a port or is localhost, images without
+// a host are on Docker Hub, and images without a tag or digest are "latest".
+func ParseReference(image string) (Reference, error) {
+	var ref Reference
+	name := strings.TrimSpace(image)
+	if name == "" {
+		return ref, fmt.Errorf("empty image reference")
+	}
+
+	if at := strings.Index(name, "@"); at >= 0 {
+		name, ref.Digest = name[:at], name[at+1:]
+		if !strings.Contains(ref.Digest, ":") {
+			return ref, fmt.Errorf("invalid digest in image reference %q", image)
+		}
+	}
+	if colon := strings.LastIndex(name, ":"); colon > strings.LastIndex(name, "/") {
+		name, ref.Tag = name[:colon], name[colon+1:]
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag = "latest"
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		ref.Host, ref.Repository = parts[0], parts[1]
+	} else {
+		ref.Host, ref.Repository = dockerHub, name
+	}
+	if ref.Host == dockerHub && !strings.Contains(ref.Repository, "/") {
+		ref.Repository = "library/" + ref.Repository
+	}
+	if ref.Repository == "" {
+		return ref, fmt.Errorf("invalid image reference %q", image)
+	}
+	return ref, nil
+}
+
+func (r Reference) String() string {
+	s := r.Host + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Name is the repository and tag without the host, which is what stays the
+// same when an image is replicated to the registries of other regions
+func (r Reference) Name() string {
+	if r.Tag == "" {
+		return r.Repository
+	}
+	return r.Repository + ":" + r.Tag
+}
+
+// manifestRef is what to ask the registry for: the digest when pinned
+func (r Reference) manifestRef() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+// ECR returns the account and region of an ECR registry host
+func (r Reference) ECR() (account, region string, ok bool) {
+	match := ecrHostRe.FindStringSubmatch(r.Host)
+	if match == nil {
+		return "", "", false
+	}
+	return match[1], match[2], true
+}
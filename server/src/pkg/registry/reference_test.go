@@ -0,0 +1,60 @@
+package registry
+
+import "testing"
+
+func TestParseReference(t *testing.T) {
+	digest := "sha256:4d6a7bc3a8b4d0f4e0c1a33e2d7d1f0e4c2a8a5c9d4f3b6e7a8c9d0e1f2a3b4c"
+	cases := []struct {
+		image string
+		want  Reference
+	}{
+		{"nginx", Reference{Host: "docker.io", Repository: "library/nginx", Tag: "latest"}},
+		{"nginx:1.25", Reference{Host: "docker.io", Repository: "library/nginx", Tag: "1.25"}},
+		{"istio/proxyv2:1.20.3", Reference{Host: "docker.io", Repository: "istio/proxyv2", Tag: "1.20.3"}},
+		{"localhost/backoffice-core-bff", Reference{Host: "localhost", Repository: "backoffice-core-bff", Tag: "latest"}},
+		{"localhost:5000/psm/backoffice-core-bff:1.24.0", Reference{Host: "localhost:5000", Repository: "psm/backoffice-core-bff", Tag: "1.24.0"}},
+		{"registry.local:5000/backoffice-core-bff", Reference{Host: "registry.local:5000", Repository: "backoffice-core-bff", Tag: "latest"}},
+		{"123456789012.dkr.ecr.sa-east-1.amazonaws.com/console-audit-bff:1.62.0",
+			Reference{Host: "123456789012.dkr.ecr.sa-east-1.amazonaws.com", Repository: "console-audit-bff", Tag: "1.62.0"}},
+		// Pinned by digest, with and without the tag it was pushed as
+		{"backoffice-core-bff@" + digest, Reference{Host: "docker.io", Repository: "library/backoffice-core-bff", Digest: digest}},
+		{"registry.local:5000/backoffice-core-bff:1.24.0@" + digest, Reference{Host: "registry.local:5000", Repository: "backoffice-core-bff", Tag: "1.24.0", Digest: digest}},
+	}
+	for _, c := range cases {
+		got, err := ParseReference(c.image)
+		if err != nil {
+			t.Errorf("ParseReference(%q): %v", c.image, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("ParseReference(%q) = %+v, want %+v", c.image, got, c.want)
+		}
+	}
+
+	for _, image := range []string{"", "  ", "nginx@latest", "registry.local:5000/:1.0"} {
+		if ref, err := ParseReference(image); err == nil {
+			t.Errorf("ParseReference(%q) = %+v, want an error", image, ref)
+		}
+	}
+}
+
+func TestReferenceParts(t *testing.T) {
+	ref, err := ParseReference("123456789012.dkr.ecr.sa-east-1.amazonaws.com/console-audit-bff:1.62.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ref.Name() != "console-audit-bff:1.62.0" || ref.manifestRef() != "1.62.0" {
+		t.Errorf("name = %s, manifest ref = %s", ref.Name(), ref.manifestRef())
+	}
+	if account, region, ok := ref.ECR(); !ok || account != "123456789012" || region != "sa-east-1" {
+		t.Errorf("ECR() = %s, %s, %v", account, region, ok)
+	}
+
+	pinned := Reference{Host: "registry.local:5000", Repository: "backoffice-core-bff", Tag: "1.24.0", Digest: "sha256:abc"}
+	if pinned.manifestRef() != "sha256:abc" || pinned.String() != "registry.local:5000/backoffice-core-bff:1.24.0@sha256:abc" {
+		t.Errorf("manifest ref = %s, string = %s", pinned.manifestRef(), pinned.String())
+	}
+	if _, _, ok := pinned.ECR(); ok {
+		t.Error("registry.local:5000 taken for ECR")
+	}
+}
@@ -0,0 +1,115 @@
+// pkg/squads/ownership.go
+package squads
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var (
+	terraformSquadRe = regexp.MustCompile(`Squad\s*=\s*"([^"]*)"`)
+	clismoSquadRe    = regexp.MustCompile(`(?m)^squad_name:\s*["']?([^"'\s#]+)`)
+	helmSquadRe      = regexp.MustCompile(`(?m)^\s*squad:\s*["']?([^"'\s#]+)`)
+)
+
+// Reconcile works out who owns a repo from the squads file, the pismo.json
+// catalog team, the terraform Squad tags, clismo.yaml squad_name and the helm
+// values.yaml squad, and reports every source that disagrees with the owner.
+// repoDir is the checkout of the repo (projects/projects/<repo>/github).
+func (r *Registry) Reconcile(repo, catalogTeam, repoDir string) Ownership {
+	ownership := Ownership{
+		Repo:      repo,
+		Declared:  r.DeclaredOwner(repo),
+		Catalog:   catalogTeam,
+		Terraform: collectMatches(filepath.Join(repoDir, "scripts/terraform"), "providers.tf", terraformSquadRe),
+		Clismo:    firstMatch(filepath.Join(repoDir, "clismo.yaml"), clismoSquadRe),
+		Helm:      collectMatches(filepath.Join(repoDir, "scripts/helm"), "values.yaml", helmSquadRe),
+	}
+
+	// The squads file is authoritative, followed by the catalog and then the
+	// repo's own configuration
+	candidates := []struct {
+		source string
+		values []string
+	}{
+		{"squads", nonEmpty(ownership.Declared)},
+		{"catalog", nonEmpty(ownership.Catalog)},
+		{"clismo", nonEmpty(ownership.Clismo)},
+		{"terraform", ownership.Terraform},
+		{"helm", ownership.Helm},
+	}
+	for _, candidate := range candidates {
+		if len(candidate.values) > 0 {
+			ownership.Owner = candidate.values[0]
+			ownership.Source = candidate.source
+			break
+		}
+	}
+
+	for _, candidate := range candidates {
+		for _, value := range candidate.values {
+			if value != ownership.Owner {
+				ownership.Mismatches = append(ownership.Mismatches,
+					fmt.Sprintf("%s says %s but owner is %s (from %s)", candidate.source, value, ownership.Owner, ownership.Source))
+			}
+		}
+	}
+	ownership.Consistent = len(ownership.Mismatches) == 0
+
+	return ownership
+}
+
+// collectMatches walks rootDir for files called fileName and returns the
+// distinct values captured by re, sorted
+func collectMatches(rootDir, fileName string, re *regexp.Regexp) []string {
+	seen := make(map[string]bool)
+
+	filepath.Walk(rootDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() || info.Name() != fileName {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		for _, match := range re.FindAllStringSubmatch(string(content), -1) {
+			if value := strings.TrimSpace(match[1]); value != "" {
+				seen[value] = true
+			}
+		}
+		return nil
+	})
+
+	values := make([]string, 0, len(seen))
+	for value := range seen {
+		values = append(values, value)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func firstMatch(path string, re *regexp.Regexp) string {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	matches := re.FindStringSubmatch(string(content))
+	if len(matches) > 1 {
+		return matches[1]
+	}
+	return ""
+}
+
+func nonEmpty(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return []string{value}
+}
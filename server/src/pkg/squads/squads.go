@@ -0,0 +1,78 @@
+// pkg/squads/squads.go
+package squads
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// Registry holds the squads loaded from the local squads file
+type Registry struct {
+	squads map[string]*Squad
+	// declared maps a repo to the squad that lists it in the squads file
+	declared map[string]string
+}
+
+// LoadSquads reads the squads file. A missing file yields an empty registry
+// so ownership can still be reconciled from the other sources.
+func LoadSquads(path string) (*Registry, error) {
+	registry := &Registry{
+		squads:   make(map[string]*Squad),
+		declared: make(map[string]string),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return registry, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading squads file: %v", err)
+	}
+
+	var file SquadsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing squads file: %v", err)
+	}
+
+	for i := range file.Squads {
+		squad := &file.Squads[i]
+		if squad.Name == "" {
+			return nil, fmt.Errorf("squad at index %d has no name", i)
+		}
+		if _, exists := registry.squads[squad.Name]; exists {
+			return nil, fmt.Errorf("squad %s is declared more than once", squad.Name)
+		}
+		registry.squads[squad.Name] = squad
+
+		for _, repo := range squad.Repos {
+			if owner, exists := registry.declared[repo]; exists {
+				return nil, fmt.Errorf("repo %s is declared by both %s and %s", repo, owner, squad.Name)
+			}
+			registry.declared[repo] = squad.Name
+		}
+	}
+
+	return registry, nil
+}
+
+// Get returns the named squad, or nil if the squads file does not declare it
+func (r *Registry) Get(name string) *Squad {
+	return r.squads[name]
+}
+
+// Names returns the declared squad names in sorted order
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.squads))
+	for name := range r.squads {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DeclaredOwner returns the squad that lists the repo in the squads file
+func (r *Registry) DeclaredOwner(repo string) string {
+	return r.declared[repo]
+}
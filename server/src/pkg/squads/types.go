@@ -0,0 +1,44 @@
+// pkg/squads/types.go
+package squads
+
+type Member struct {
+	Name   string `json:"name"`
+	Email  string `json:"email,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Github string `json:"github,omitempty"`
+}
+
+type OnCall struct {
+	Primary   string `json:"primary,omitempty"`
+	Secondary string `json:"secondary,omitempty"`
+	Rotation  string `json:"rotation,omitempty"`
+	Schedule  string `json:"schedule,omitempty"`
+}
+
+type Squad struct {
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"displayName,omitempty"`
+	Description  string   `json:"description,omitempty"`
+	SlackChannel string   `json:"slackChannel,omitempty"`
+	Repos        []string `json:"repos,omitempty"`
+	Members      []Member `json:"members,omitempty"`
+	OnCall       OnCall   `json:"onCall"`
+}
+
+type SquadsFile struct {
+	Squads []Squad `json:"squads"`
+}
+
+// Ownership records which squad each source claims for a repo
+type Ownership struct {
+	Repo       string   `json:"repo"`
+	Owner      string   `json:"owner"`
+	Source     string   `json:"source"`
+	Declared   string   `json:"declared,omitempty"`
+	Catalog    string   `json:"catalog,omitempty"`
+	Terraform  []string `json:"terraform,omitempty"`
+	Clismo     string   `json:"clismo,omitempty"`
+	Helm       []string `json:"helm,omitempty"`
+	Consistent bool     `json:"consistent"`
+	Mismatches []string `json:"mismatches,omitempty"`
+}
@@ -0,0 +1,163 @@
+// pkg/summary/read.go
+package summary
+
+import (
+	"argocd/pkg/regions"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+// Read loads a summary file, upgrading it to SchemaVersion when it was written
+// by an older version. The bool reports whether it needed upgrading, so the
+// caller can write the upgraded summary back.
+func Read(filename string) (*Repo, bool, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading summary: %w", err)
+	}
+	return Decode(data)
+}
+
+// Decode parses a summary, upgrading it to SchemaVersion when needed
+func Decode(data []byte) (*Repo, bool, error) {
+	var header struct {
+		SchemaVersion int `json:"schemaVersion"`
+	}
+	if err := json.Unmarshal(data, &header); err != nil {
+		return nil, false, fmt.Errorf("error parsing summary: %w", err)
+	}
+	if header.SchemaVersion < 0 {
+		return nil, false, fmt.Errorf("invalid summary schema version %d", header.SchemaVersion)
+	}
+	if header.SchemaVersion > SchemaVersion {
+		return nil, false, fmt.Errorf("summary schema version %d is newer than the supported version %d", header.SchemaVersion, SchemaVersion)
+	}
+
+	migrated := header.SchemaVersion < SchemaVersion
+	if migrated {
+		var err error
+		if data, err = migrate(data, header.SchemaVersion); err != nil {
+			return nil, false, err
+		}
+	}
+
+	var repo Repo
+	if err := json.Unmarshal(data, &repo); err != nil {
+		return nil, false, fmt.Errorf("error parsing summary: %w", err)
+	}
+	return &repo, migrated, nil
+}
+
+// migrations[v] upgrades a summary from version v to v+1
+var migrations = []func(map[string]interface{}){
+	migrateUnversioned,
+	migrateLocations,
+}
+
+func migrate(data []byte, from int) ([]byte, error) {
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("error parsing summary: %w", err)
+	}
+	for version := from; version < SchemaVersion; version++ {
+		migrations[version](raw)
+	}
+	raw["schemaVersion"] = SchemaVersion
+
+	upgraded, err := json.Marshal(raw)
+	if err != nil {
+		return nil, fmt.Errorf("error upgrading summary from version %d: %w", from, err)
+	}
+	return upgraded, nil
+}
+
+// migrateUnversioned upgrades the summaries written before schemaVersion
+// existed. Their shape is the same, but the untyped writer let a few fields
+// through as null, as a single string, or with the rollout analyzer's plain
+// text result in place of the status object.
+func migrateUnversioned(raw map[string]interface{}) {
+	delete(raw, "refreshing")
+	if _, ok := raw["argocd"].(map[string]interface{}); !ok {
+		delete(raw, "argocd")
+	}
+	if _, ok := raw["github"].(map[string]interface{}); !ok {
+		delete(raw, "github")
+	}
+
+	apps, _ := raw["apps"].([]interface{})
+	kept := []interface{}{}
+	for _, app := range apps {
+		appMap, ok := app.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		for _, key := range []string{"error", "warning", "images"} {
+			appMap[key] = stringList(appMap[key])
+			if appMap[key] == nil {
+				delete(appMap, key)
+			}
+		}
+		for _, key := range []string{"deployment", "argocd", "grafana", "codefresh"} {
+			if _, ok := appMap[key].(map[string]interface{}); !ok {
+				delete(appMap, key)
+			}
+		}
+		if argocd, ok := appMap["argocd"].(map[string]interface{}); ok {
+			status, ok := argocd["status"].(map[string]interface{})
+			if !ok {
+				status = map[string]interface{}{}
+			}
+			if steps := stringList(status["step"]); steps != nil {
+				status["step"] = steps
+			} else {
+				delete(status, "step")
+			}
+			argocd["status"] = status
+			if _, ok := argocd["health"].(string); !ok {
+				argocd["health"] = ""
+			}
+		}
+		kept = append(kept, appMap)
+	}
+	raw["apps"] = kept
+}
+
+// migrateLocations adds the account, region and env of each app, which
+// version 1 only had inside the app name
+func migrateLocations(raw map[string]interface{}) {
+	repoName, _ := raw["repoName"].(string)
+	apps, _ := raw["apps"].([]interface{})
+	for _, app := range apps {
+		appMap, ok := app.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		appName, _ := appMap["appName"].(string)
+		location, _ := regions.ParseAppName(repoName, appName)
+		appMap["account"] = location.Account
+		appMap["region"] = location.Region
+		appMap["env"] = location.Env
+	}
+}
+
+// stringList turns a string or a list of strings into a list, dropping
+// anything else
+func stringList(value interface{}) []interface{} {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+		return []interface{}{v}
+	case []interface{}:
+		var list []interface{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
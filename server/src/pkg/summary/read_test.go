@@ -0,0 +1,145 @@
+package summary
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// unversioned is a summary written before schemaVersion existed, with the
+// nulls, single strings and plain text the untyped writer let through
+const unversioned = `{
+	"repoName": "crm-core",
+	"repoBitUrl": "https://github.com/pismo/crm-core",
+	"repoNamespace": "crm",
+	"argocd": null,
+	"github": "unavailable",
+	"refreshing": true,
+	"apps": [
+		{
+			"appName": "crm-core-prod-sa-east-1",
+			"type": "primary",
+			"error": null,
+			"warning": "canary paused",
+			"images": ["crm-core:1.24.0", 42],
+			"deployment": {"totalPods": 3, "deployments": [{"version": "1.24.0", "type": "stable", "podCount": 3}]},
+			"argocd": {"url": "https://argocd/applications/crm-core-prod-sa-east-1", "status": "Rollout is progressing", "health": null},
+			"grafana": "none",
+			"codefresh": null
+		},
+		{
+			"appName": "crm-core-dev-ext-sa-east-1",
+			"type": "primary",
+			"error": ["timeout reading rollout"],
+			"deployment": "Error: could not analyze deployment",
+			"argocd": {"status": {"step": "3", "weight": "50"}, "health": "Error"}
+		},
+		"not an app"
+	]
+}`
+
+func TestDecodeUpgradesUnversionedSummaries(t *testing.T) {
+	repo, migrated, err := Decode([]byte(unversioned))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !migrated || repo.SchemaVersion != SchemaVersion {
+		t.Errorf("migrated = %v, schema version = %d", migrated, repo.SchemaVersion)
+	}
+	if repo.RepoName != "crm-core" || repo.RepoNamespace != "crm" || repo.Refreshing || repo.ArgoCD != (Link{}) || repo.GitHub != (GitHub{}) {
+		t.Errorf("repo = %+v", repo)
+	}
+	if len(repo.Apps) != 2 {
+		t.Fatalf("apps = %+v, want the 2 objects", repo.Apps)
+	}
+
+	prod, ext := repo.Apps[0], repo.Apps[1]
+	if prod.Account != "prod" || prod.Region != "sa-east-1" || prod.Env != "prod" {
+		t.Errorf("prod location = %s/%s/%s", prod.Account, prod.Region, prod.Env)
+	}
+	if prod.Error != nil || !reflect.DeepEqual(prod.Warning, []string{"canary paused"}) || !reflect.DeepEqual(prod.Images, []string{"crm-core:1.24.0"}) {
+		t.Errorf("prod lists: error = %v, warning = %v, images = %v", prod.Error, prod.Warning, prod.Images)
+	}
+	if prod.Version() != "1.24.0" || prod.Grafana != nil || prod.Codefresh != nil {
+		t.Errorf("prod = %+v", prod)
+	}
+	// Plain text in place of the status is dropped, the rest of argocd kept
+	if prod.ArgoCD == nil || prod.ArgoCD.URL != "https://argocd/applications/crm-core-prod-sa-east-1" ||
+		!reflect.DeepEqual(prod.ArgoCD.Status, RolloutStatus{}) || prod.ArgoCD.Health != "" {
+		t.Errorf("prod argocd = %+v", prod.ArgoCD)
+	}
+
+	if ext.Account != "dev-ext" || ext.Region != "sa-east-1" || ext.Env != "ext" {
+		t.Errorf("ext location = %s/%s/%s", ext.Account, ext.Region, ext.Env)
+	}
+	if ext.Deployment != nil || !ext.HasErrors() {
+		t.Errorf("ext = %+v", ext)
+	}
+	if ext.ArgoCD == nil || !reflect.DeepEqual(ext.ArgoCD.Status, RolloutStatus{Step: []string{"3"}, Weight: "50"}) || ext.ArgoCD.Health != "Error" {
+		t.Errorf("ext argocd = %+v", ext.ArgoCD)
+	}
+}
+
+func TestDecodeUpgradesVersion1Summaries(t *testing.T) {
+	repo, migrated, err := Decode([]byte(`{
+		"schemaVersion": 1,
+		"repoName": "console-audit-bff",
+		"apps": [
+			{"appName": "console-audit-bff-prod-us-gov-west-1", "type": "failover", "images": ["console-audit-bff:1.62.0"]},
+			{"appName": "console-audit-bff-sandbox", "type": "primary"}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("Decode: %v", err)
+	}
+	if !migrated || repo.SchemaVersion != SchemaVersion {
+		t.Errorf("migrated = %v, schema version = %d", migrated, repo.SchemaVersion)
+	}
+
+	want := []App{
+		{AppName: "console-audit-bff-prod-us-gov-west-1", Type: TypeFailover, Account: "prod", Region: "us-gov-west-1", Env: "prod", Images: []string{"console-audit-bff:1.62.0"}},
+		// A name without a region is all account
+		{AppName: "console-audit-bff-sandbox", Type: TypePrimary, Account: "sandbox", Env: "sandbox"},
+	}
+	if !reflect.DeepEqual(repo.Apps, want) {
+		t.Errorf("apps = %+v, want %+v", repo.Apps, want)
+	}
+	if location := repo.Apps[0].Location(); location.Key() != "prod-us-gov-west-1" || location.Primary {
+		t.Errorf("location = %+v", location)
+	}
+}
+
+func TestDecodeKeepsCurrentSummaries(t *testing.T) {
+	repo, migrated, err := Decode([]byte(`{"schemaVersion": 2, "repoName": "crm-core", "refreshing": true,
+		"apps": [{"appName": "crm-core-prod-sa-east-1", "type": "primary", "account": "prod", "region": "sa-east-1", "env": "prod"}]}`))
+	if err != nil || migrated {
+		t.Fatalf("migrated = %v, err = %v", migrated, err)
+	}
+	// Nothing is rewritten: refreshing is only dropped from older summaries
+	if !repo.Refreshing || repo.Apps[0].Account != "prod" {
+		t.Errorf("repo = %+v", repo)
+	}
+}
+
+func TestDecodeRejectsUnknownVersions(t *testing.T) {
+	for _, data := range []string{`{"schemaVersion": -1}`, `{"schemaVersion": 99}`, `{"schemaVersion": "2"}`, `not json`} {
+		if _, _, err := Decode([]byte(data)); err == nil {
+			t.Errorf("Decode(%s) succeeded", data)
+		}
+	}
+}
+
+func TestReadReportsMissingFiles(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crm-core.json")
+	if _, _, err := Read(path); err == nil || !strings.Contains(err.Error(), "error reading summary") {
+		t.Errorf("err = %v", err)
+	}
+	if err := os.WriteFile(path, []byte(unversioned), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, migrated, err := Read(path); err != nil || !migrated {
+		t.Errorf("migrated = %v, err = %v", migrated, err)
+	}
+}
@@ -0,0 +1,109 @@
+// pkg/summary/schema.go
+package summary
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// SchemaID is the $id of the published schema, relative to the server
+const SchemaID = "/schema/summary.json"
+
+// Schema returns the JSON Schema (draft 2020-12) of Repo. It is generated from
+// the Go types, so what is published cannot drift from what is written.
+func Schema() map[string]interface{} {
+	g := &schemaGenerator{defs: map[string]interface{}{}}
+	root := g.object(reflect.TypeOf(Repo{}))
+	root["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	root["$id"] = SchemaID
+	root["title"] = fmt.Sprintf("Lighthouse repo summary, schema version %d", SchemaVersion)
+	root["$defs"] = g.defs
+	return root
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+// schema returns the schema of t, registering structs under $defs
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		name := t.Name()
+		if _, ok := g.defs[name]; !ok {
+			g.defs[name] = nil // guards against recursive types
+			g.defs[name] = g.object(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + name}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	}
+	return map[string]interface{}{}
+}
+
+// object returns the schema of a struct's exported, JSON encoded fields.
+// Fields without omitempty are required.
+func (g *schemaGenerator) object(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = field.Name
+		}
+		omitEmpty := strings.Contains(","+options+",", ",omitempty,")
+
+		property := g.schema(field.Type)
+		if enum := field.Tag.Get("enum"); enum != "" {
+			property["enum"] = strings.Split(enum, ",")
+		}
+		// A nil slice is written as null unless the field is omitted when empty
+		if !omitEmpty && field.Type.Kind() == reflect.Slice {
+			property["type"] = []string{"array", "null"}
+		}
+		properties[name] = property
+
+		if !omitEmpty {
+			required = append(required, name)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+}
@@ -0,0 +1,184 @@
+// pkg/summary/summary.go
+package summary
+
+import (
+	"argocd/pkg/analyzer"
+	"argocd/pkg/ci"
+	"argocd/pkg/cluster"
+	"argocd/pkg/inventory"
+	"argocd/pkg/regions"
+	"argocd/pkg/registry"
+	"strings"
+	"time"
+)
+
+// SchemaVersion is written to every summary file. Bump it whenever a field
+// changes meaning or shape, and teach migrate how to upgrade the old files.
+const SchemaVersion = 2
+
+// App types
+const (
+	TypePrimary  = "primary"
+	TypeFailover = "failover"
+)
+
+// Repo is the summary written to projects/projects-summary/<repo>.json and
+// served by /?repo=<repo>
+type Repo struct {
+	SchemaVersion int        `json:"schemaVersion"`
+	RepoName      string     `json:"repoName"`
+	RepoBitURL    string     `json:"repoBitUrl"`
+	RepoCodefresh string     `json:"repoCodefresh"`
+	Apps          []App      `json:"apps"`
+	ArgoCD        Link       `json:"argocd"`
+	RepoDesc      string     `json:"repoDesc"`
+	RepoSquad     string     `json:"repoSquad"`
+	RepoNamespace string     `json:"repoNamespace,omitempty"`
+	GitHub        GitHub     `json:"github"`
+	Builds        []ci.Build `json:"builds,omitempty"`
+	// Partial is set when some apps or the git details timed out; TimedOut
+	// lists them, with "git" standing for the git details
+	Partial  bool     `json:"partial,omitempty"`
+	TimedOut []string `json:"timedOut,omitempty"`
+	// DigestMismatches lists the tags that resolve to different digests in
+	// the registries of different regions
+	DigestMismatches []DigestMismatch `json:"digestMismatches,omitempty"`
+	// Refreshing is only set on responses served while a newer summary is being built
+	Refreshing bool `json:"refreshing,omitempty"`
+}
+
+// App is one ArgoCD application of the repo, i.e. one account and region
+type App struct {
+	AppName string `json:"appName"`
+	Type    string `json:"type" enum:"primary,failover"`
+	Account string `json:"account"`
+	Region  string `json:"region"`
+	Env     string `json:"env"`
+	// Provider is where the app's workload was read from, e.g. argocd/default
+	// or kubernetes/<kubeconfig context>
+	Provider string   `json:"provider,omitempty"`
+	Error    []string `json:"error,omitempty"`
+	Warning  []string `json:"warning,omitempty"`
+	// Images are the main images of the rollout; Containers lists every
+	// main, sidecar and init container
+	Images     []string              `json:"images,omitempty"`
+	Containers []inventory.Container `json:"containers,omitempty"`
+	// ImageDetails is what the registry says about each of Images
+	ImageDetails []registry.ImageInfo         `json:"imageDetails,omitempty"`
+	Deployment   *analyzer.DeploymentAnalysis `json:"deployment,omitempty"`
+	ArgoCD       *ArgoCD                      `json:"argocd,omitempty"`
+	Grafana      *Link                        `json:"grafana,omitempty"`
+	Codefresh    *Codefresh                   `json:"codefresh,omitempty"`
+	TimedOut     bool                         `json:"timedOut,omitempty"`
+	// HPAs are the autoscalers of the rollout, and Events its most recent
+	// Kubernetes events
+	HPAs   []cluster.HPA   `json:"hpas,omitempty"`
+	Events []cluster.Event `json:"events,omitempty"`
+	// Violations are the policy rules the app breaks
+	Violations []Violation `json:"violations,omitempty"`
+}
+
+// Violation is a policy rule an app breaks
+type Violation struct {
+	Rule     string `json:"rule"`
+	Severity string `json:"severity" enum:"info,warning,error"`
+	Message  string `json:"message"`
+}
+
+// DigestMismatch is a tag that is not the same image everywhere it runs.
+// Digests maps each digest to the apps running it.
+type DigestMismatch struct {
+	Image   string              `json:"image"`
+	Digests map[string][]string `json:"digests"`
+}
+
+// ArgoCD is the app's rollout state as seen by ArgoCD, or read from
+// Kubernetes for clusters ArgoCD does not manage, which leaves URL empty
+type ArgoCD struct {
+	URL string `json:"url"`
+	// Instance names the ArgoCD instance the app was read from
+	Instance string        `json:"instance,omitempty"`
+	Status   RolloutStatus `json:"status"`
+	// Health is "Error" when ArgoCD reports the app unhealthy, empty otherwise
+	Health  string `json:"health"`
+	Aborted bool   `json:"aborted,omitempty"`
+	// PausedSince is when the canary stopped at its current pause step
+	PausedSince *time.Time `json:"pausedSince,omitempty"`
+}
+
+// RolloutStatus is the canary step the rollout is at and the weight set by the
+// step before it; both are empty when no canary is in progress
+type RolloutStatus struct {
+	Step   []string `json:"step,omitempty"`
+	Weight string   `json:"weight,omitempty"`
+}
+
+type Link struct {
+	URL string `json:"url"`
+}
+
+// Codefresh links the app to the build of its deployed image, or to a search
+// of the repo's pipelines when that build is unknown
+type Codefresh struct {
+	URL   string    `json:"url"`
+	Build *ci.Build `json:"build,omitempty"`
+}
+
+// GitHub is the head of the repo's default branch when it was last refreshed
+type GitHub struct {
+	Commit    string `json:"commit"`
+	Branch    string `json:"branch"`
+	Author    string `json:"author"`
+	Timestamp string `json:"timestamp"`
+	Tag       string `json:"tag"`
+}
+
+// NewApp starts the summary of the app deployed to location
+func NewApp(appName string, location regions.Location) App {
+	app := App{
+		AppName: appName,
+		Type:    TypePrimary,
+		Account: location.Account,
+		Region:  location.Region,
+		Env:     location.Env,
+	}
+	if !location.Primary {
+		app.Type = TypeFailover
+	}
+	return app
+}
+
+// Location returns the account and region the app is deployed to
+func (a App) Location() regions.Location {
+	location := regions.NewLocation(a.Account, a.Region)
+	location.Env = a.Env
+	location.Primary = a.Type != TypeFailover
+	return location
+}
+
+// HasErrors reports whether the app has errors, which means its deployment
+// details are missing or not to be trusted
+func (a App) HasErrors() bool {
+	return len(a.Error) > 0
+}
+
+// Version returns the version the app is running, or "" when unknown
+func (a App) Version() string {
+	if a.Deployment == nil {
+		return ""
+	}
+	return a.Deployment.PrimaryVersion()
+}
+
+// Paused reports whether the app's canary is waiting on a pause step
+func (a App) Paused() bool {
+	if a.ArgoCD == nil {
+		return false
+	}
+	for _, step := range a.ArgoCD.Status.Step {
+		if strings.Contains(step, `"pause"`) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"flag"
 	"fmt"
@@ -136,10 +137,26 @@ func outputJSON(repositories []Repository) {
 }
 
 func outputCSV(repositories []Repository) {
-	fmt.Println("repository_name,team,description")
-	fmt.Println("------------------------------------")
+	// encoding/csv quotes fields containing commas, quotes or newlines (RFC 4180)
+	writer := csv.NewWriter(os.Stdout)
+	writer.UseCRLF = true
+
+	if err := writer.Write([]string{"repository_name", "team", "description"}); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+		os.Exit(1)
+	}
 	for _, repo := range repositories {
-		fmt.Printf("%s,%s,%s\n", repo.Name, repo.Team, repo.Description)
+		if err := writer.Write([]string{repo.Name, repo.Team, repo.Description}); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Write only buffers; errors writing to stdout surface on Flush
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+		os.Exit(1)
 	}
 }
 
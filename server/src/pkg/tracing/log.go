@@ -0,0 +1,35 @@
+// pkg/tracing/log.go
+package tracing
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogHandler adds the request ID and trace context from ctx to every record,
+// so log lines can be matched to a request and its spans
+type LogHandler struct {
+	slog.Handler
+}
+
+func NewLogHandler(inner slog.Handler) *LogHandler {
+	return &LogHandler{Handler: inner}
+}
+
+func (h *LogHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := RequestID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if span := SpanFromContext(ctx); span != nil {
+		record.AddAttrs(slog.String("trace_id", span.TraceID), slog.String("span_id", span.SpanID))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *LogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *LogHandler) WithGroup(name string) slog.Handler {
+	return &LogHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,180 @@
+// pkg/tracing/otlp.go
+package tracing
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// encodeOTLP builds an OTLP/JSON ExportTraceServiceRequest
+func encodeOTLP(serviceName string, spans []*Span) ([]byte, error) {
+	type anyValue map[string]interface{}
+	type keyValue struct {
+		Key   string   `json:"key"`
+		Value anyValue `json:"value"`
+	}
+	toAttributes := func(values map[string]interface{}) []keyValue {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		attributes := make([]keyValue, 0, len(keys))
+		for _, key := range keys {
+			var value anyValue
+			switch v := values[key].(type) {
+			case bool:
+				value = anyValue{"boolValue": v}
+			case int:
+				value = anyValue{"intValue": strconv.Itoa(v)}
+			case int64:
+				value = anyValue{"intValue": strconv.FormatInt(v, 10)}
+			case float64:
+				value = anyValue{"doubleValue": v}
+			default:
+				value = anyValue{"stringValue": fmt.Sprint(v)}
+			}
+			attributes = append(attributes, keyValue{Key: key, Value: value})
+		}
+		return attributes
+	}
+
+	encoded := make([]map[string]interface{}, 0, len(spans))
+	for _, span := range spans {
+		attributes, errMessage := span.snapshot()
+		status := map[string]interface{}{"code": 1}
+		if errMessage != "" {
+			status = map[string]interface{}{"code": 2, "message": errMessage}
+		}
+		item := map[string]interface{}{
+			"traceId":           span.TraceID,
+			"spanId":            span.SpanID,
+			"name":              span.Name,
+			"kind":              1,
+			"startTimeUnixNano": strconv.FormatInt(span.Start.UnixNano(), 10),
+			"endTimeUnixNano":   strconv.FormatInt(span.End.UnixNano(), 10),
+			"attributes":        toAttributes(attributes),
+			"status":            status,
+		}
+		if span.ParentSpanID != "" {
+			item["parentSpanId"] = span.ParentSpanID
+		}
+		encoded = append(encoded, item)
+	}
+
+	request := map[string]interface{}{
+		"resourceSpans": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{
+					"attributes": toAttributes(map[string]interface{}{"service.name": serviceName}),
+				},
+				"scopeSpans": []interface{}{
+					map[string]interface{}{
+						"scope": map[string]string{"name": "lighthouse"},
+						"spans": encoded,
+					},
+				},
+			},
+		},
+	}
+	return json.Marshal(request)
+}
+
+// OTLPExporter posts spans to an OTLP/HTTP collector using the JSON encoding
+type OTLPExporter struct {
+	Endpoint    string
+	Headers     map[string]string
+	ServiceName string
+	client      *http.Client
+}
+
+// NewOTLPExporter takes the collector base URL, e.g. http://localhost:4318
+func NewOTLPExporter(endpoint, serviceName string, headers map[string]string) *OTLPExporter {
+	endpoint = strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(endpoint, "/v1/traces") {
+		endpoint += "/v1/traces"
+	}
+	return &OTLPExporter{
+		Endpoint:    endpoint,
+		Headers:     headers,
+		ServiceName: serviceName,
+		// Its own transport keeps span exports out of the upstream metrics
+		client: &http.Client{Timeout: 10 * time.Second, Transport: &http.Transport{}},
+	}
+}
+
+func (e *OTLPExporter) Export(spans []*Span) error {
+	body, err := encodeOTLP(e.ServiceName, spans)
+	if err != nil {
+		return fmt.Errorf("error encoding spans: %v", err)
+	}
+
+	req, err := http.NewRequest("POST", e.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("error: received status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (e *OTLPExporter) Shutdown() error {
+	return nil
+}
+
+// FileExporter appends one OTLP/JSON request per line, the format read by the
+// OpenTelemetry Collector's otlpjsonfile receiver
+type FileExporter struct {
+	ServiceName string
+	mu          sync.Mutex
+	file        *os.File
+}
+
+func NewFileExporter(path, serviceName string) (*FileExporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening trace file: %v", err)
+	}
+	return &FileExporter{ServiceName: serviceName, file: file}, nil
+}
+
+func (e *FileExporter) Export(spans []*Span) error {
+	line, err := encodeOTLP(e.ServiceName, spans)
+	if err != nil {
+		return fmt.Errorf("error encoding spans: %v", err)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	_, err = e.file.Write(append(line, '\n'))
+	return err
+}
+
+func (e *FileExporter) Shutdown() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.file.Close()
+}
@@ -0,0 +1,267 @@
+// pkg/tracing/tracing.go
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+type Span struct {
+	TraceID      string
+	SpanID       string
+	ParentSpanID string
+	Name         string
+	Start        time.Time
+	End          time.Time
+
+	mu         sync.Mutex
+	attributes map[string]interface{}
+	err        string
+	ended      bool
+	tracer     *Tracer
+}
+
+// SetAttribute records a string, bool, int or float64 attribute
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes[key] = value
+}
+
+// RecordError marks the span as failed
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.err = err.Error()
+}
+
+// Finish ends the span and hands it to the exporter. Calling it twice is a no-op.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.mu.Unlock()
+
+	s.tracer.export(s)
+}
+
+// snapshot copies the attributes and error for exporting
+func (s *Span) snapshot() (map[string]interface{}, string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attributes := make(map[string]interface{}, len(s.attributes))
+	for key, value := range s.attributes {
+		attributes[key] = value
+	}
+	return attributes, s.err
+}
+
+// Traceparent formats the span as a W3C traceparent header for upstream calls
+func (s *Span) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", s.TraceID, s.SpanID)
+}
+
+// Exporter ships finished spans somewhere
+type Exporter interface {
+	Export(spans []*Span) error
+	Shutdown() error
+}
+
+// Tracer batches finished spans to an exporter. A tracer with no exporter
+// still hands out IDs, so request IDs work with tracing switched off.
+type Tracer struct {
+	ServiceName string
+	exporter    Exporter
+
+	mu       sync.Mutex
+	batch    []*Span
+	flush    chan struct{}
+	done     chan struct{}
+	stopped  chan struct{}
+	shutdown sync.Once
+}
+
+const (
+	batchSize     = 256
+	flushInterval = 5 * time.Second
+)
+
+func NewTracer(serviceName string, exporter Exporter) *Tracer {
+	t := &Tracer{ServiceName: serviceName, exporter: exporter}
+	if exporter != nil {
+		t.flush = make(chan struct{}, 1)
+		t.done = make(chan struct{})
+		t.stopped = make(chan struct{})
+		go t.run()
+	}
+	return t
+}
+
+var defaultTracer = NewTracer("lighthouse", nil)
+
+// SetDefault replaces the tracer used by Start
+func SetDefault(tracer *Tracer) {
+	defaultTracer = tracer
+}
+
+// Shutdown flushes outstanding spans of the default tracer
+func Shutdown() error {
+	return defaultTracer.Shutdown()
+}
+
+type spanKey struct{}
+type requestIDKey struct{}
+
+// Start begins a span as a child of the span in ctx, if any
+func Start(ctx context.Context, name string) (context.Context, *Span) {
+	return defaultTracer.Start(ctx, name)
+}
+
+func (t *Tracer) Start(ctx context.Context, name string) (context.Context, *Span) {
+	span := &Span{
+		SpanID:     randomHex(8),
+		Name:       name,
+		Start:      time.Now(),
+		attributes: make(map[string]interface{}),
+		tracer:     t,
+	}
+	if parent := SpanFromContext(ctx); parent != nil {
+		span.TraceID = parent.TraceID
+		span.ParentSpanID = parent.SpanID
+	} else {
+		span.TraceID = randomHex(16)
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// StartRemote begins a root span continuing a W3C traceparent from a caller
+func StartRemote(ctx context.Context, name, traceparent string) (context.Context, *Span) {
+	ctx, span := Start(ctx, name)
+	if traceID, parentID, ok := parseTraceparent(traceparent); ok {
+		span.TraceID = traceID
+		span.ParentSpanID = parentID
+	}
+	return ctx, span
+}
+
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the ID of the request that started the work in ctx
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// NewRequestID returns a short random ID for requests that did not bring one
+func NewRequestID() string {
+	return randomHex(8)
+}
+
+func (t *Tracer) export(span *Span) {
+	if t.exporter == nil {
+		return
+	}
+	t.mu.Lock()
+	t.batch = append(t.batch, span)
+	full := len(t.batch) >= batchSize
+	t.mu.Unlock()
+
+	if full {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.stopped)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.flush:
+		case <-t.done:
+			return
+		}
+		t.exportBatch()
+	}
+}
+
+func (t *Tracer) exportBatch() {
+	t.mu.Lock()
+	batch := t.batch
+	t.batch = nil
+	t.mu.Unlock()
+
+	if len(batch) == 0 {
+		return
+	}
+	if err := t.exporter.Export(batch); err != nil {
+		// Tracing must never break a refresh; drop the batch
+		slog.Warn("Error exporting spans", "spans", len(batch), "error", err)
+	}
+}
+
+// Shutdown stops the background exporter, flushes outstanding spans and
+// closes the exporter. Only the first call does anything.
+func (t *Tracer) Shutdown() error {
+	if t.exporter == nil {
+		return nil
+	}
+	var err error
+	t.shutdown.Do(func() {
+		close(t.done)
+		// Wait for run so the final flush never overlaps an export in flight
+		<-t.stopped
+		t.exportBatch()
+		err = t.exporter.Shutdown()
+	})
+	return err
+}
+
+func parseTraceparent(header string) (traceID, spanID string, ok bool) {
+	var version, flags string
+	if n, _ := fmt.Sscanf(header, "%2s-%32s-%16s-%2s", &version, &traceID, &spanID, &flags); n != 4 {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(traceID); err != nil || len(traceID) != 32 {
+		return "", "", false
+	}
+	if _, err := hex.DecodeString(spanID); err != nil || len(spanID) != 16 {
+		return "", "", false
+	}
+	return traceID, spanID, true
+}
+
+func randomHex(bytes int) string {
+	buf := make([]byte, bytes)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
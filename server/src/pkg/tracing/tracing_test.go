@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowExporter takes a while per batch and notes exports that overlap
+type slowExporter struct {
+	mu          sync.Mutex
+	exporting   bool
+	overlapped  bool
+	exported    int
+	shutdownRan int
+}
+
+func (e *slowExporter) Export(spans []*Span) error {
+	e.mu.Lock()
+	if e.exporting {
+		e.overlapped = true
+	}
+	e.exporting = true
+	e.mu.Unlock()
+
+	time.Sleep(20 * time.Millisecond)
+
+	e.mu.Lock()
+	e.exporting = false
+	e.exported += len(spans)
+	e.mu.Unlock()
+	return nil
+}
+
+func (e *slowExporter) Shutdown() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.shutdownRan++
+	return nil
+}
+
+func TestShutdownFlushesEverySpanWithoutOverlappingExports(t *testing.T) {
+	exporter := &slowExporter{}
+	tracer := NewTracer("lighthouse", exporter)
+
+	// A full batch wakes the background exporter, the rest waits for Shutdown
+	spans := batchSize + 10
+	for i := 0; i < spans; i++ {
+		_, span := tracer.Start(context.Background(), "refresh")
+		span.Finish()
+	}
+	time.Sleep(5 * time.Millisecond)
+
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := tracer.Shutdown(); err != nil {
+		t.Fatalf("second Shutdown: %v", err)
+	}
+
+	exporter.mu.Lock()
+	defer exporter.mu.Unlock()
+	if exporter.overlapped {
+		t.Error("the final flush overlapped an export in flight")
+	}
+	if exporter.exported != spans {
+		t.Errorf("exported %d spans, want %d", exporter.exported, spans)
+	}
+	if exporter.shutdownRan != 1 {
+		t.Errorf("exporter shut down %d times, want 1", exporter.shutdownRan)
+	}
+}
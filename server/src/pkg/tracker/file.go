@@ -0,0 +1,63 @@
+// pkg/tracker/file.go
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type trackerFile struct {
+	Members map[string][]Person `json:"members"`
+	Tickets []Ticket            `json:"tickets"`
+}
+
+// FileProvider serves members and tickets from a local JSON file, for
+// running the dashboard offline or without Jira credentials
+type FileProvider struct {
+	Path string
+}
+
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{Path: path}
+}
+
+func (p *FileProvider) load() (*trackerFile, error) {
+	data, err := os.ReadFile(p.Path)
+	if os.IsNotExist(err) {
+		return &trackerFile{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading tracker file: %v", err)
+	}
+
+	var file trackerFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("error parsing tracker file: %v", err)
+	}
+	return &file, nil
+}
+
+func (p *FileProvider) SquadMembers(ctx context.Context, squad string) ([]Person, error) {
+	file, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+	return file.Members[squad], nil
+}
+
+func (p *FileProvider) RepoTickets(ctx context.Context, repo string) ([]Ticket, error) {
+	file, err := p.load()
+	if err != nil {
+		return nil, err
+	}
+
+	tickets := []Ticket{}
+	for _, ticket := range file.Tickets {
+		if linkedToRepo(ticket, repo) {
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
@@ -0,0 +1,224 @@
+// pkg/tracker/jira.go
+package tracker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const jiraSearchFields = "summary,status,priority,assignee,duedate,updated,labels,components"
+
+// JiraClient talks to the Jira REST API v2. Tickets are linked to a repo by
+// a label or a component with the repo's name, and squad members come from
+// the Jira group named after the squad.
+type JiraClient struct {
+	BaseURL string
+	// User and Token are sent as basic auth (Jira Cloud API tokens); with no
+	// User the token is sent as a bearer personal access token (Jira Server)
+	User       string
+	Token      string
+	Project    string
+	MaxResults int
+	HTTPClient *http.Client
+}
+
+func NewJiraClient(baseURL, user, token, project string) *JiraClient {
+	return &JiraClient{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		User:       user,
+		Token:      token,
+		Project:    project,
+		MaxResults: 50,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type jiraUser struct {
+	AccountID    string            `json:"accountId"`
+	Name         string            `json:"name"`
+	DisplayName  string            `json:"displayName"`
+	EmailAddress string            `json:"emailAddress"`
+	AvatarURLs   map[string]string `json:"avatarUrls"`
+}
+
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary string `json:"summary"`
+		Status  struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Assignee   *jiraUser `json:"assignee"`
+		DueDate    string    `json:"duedate"`
+		Updated    string    `json:"updated"`
+		Labels     []string  `json:"labels"`
+		Components []struct {
+			Name string `json:"name"`
+		} `json:"components"`
+	} `json:"fields"`
+}
+
+// jiraStatusError is returned for non-200 responses so callers can tell a
+// rejected query apart from a transport failure
+type jiraStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *jiraStatusError) Error() string {
+	return fmt.Sprintf("jira returned status %d: %s", e.StatusCode, e.Body)
+}
+
+func (c *JiraClient) get(ctx context.Context, path string, query url.Values, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.BaseURL+path+"?"+query.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.User != "" {
+		req.SetBasicAuth(c.User, c.Token)
+	} else if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error making request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &jiraStatusError{StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error parsing jira response: %v", err)
+	}
+	return nil
+}
+
+func (c *JiraClient) search(ctx context.Context, jql string) ([]Ticket, error) {
+	query := url.Values{}
+	query.Set("jql", jql)
+	query.Set("fields", jiraSearchFields)
+	query.Set("maxResults", fmt.Sprint(c.MaxResults))
+
+	var result struct {
+		Issues []jiraIssue `json:"issues"`
+	}
+	if err := c.get(ctx, "/rest/api/2/search", query, &result); err != nil {
+		return nil, err
+	}
+
+	tickets := make([]Ticket, 0, len(result.Issues))
+	for _, issue := range result.Issues {
+		tickets = append(tickets, c.toTicket(issue))
+	}
+	return tickets, nil
+}
+
+func (c *JiraClient) toTicket(issue jiraIssue) Ticket {
+	ticket := Ticket{
+		ID:      issue.Key,
+		Title:   issue.Fields.Summary,
+		Status:  issue.Fields.Status.Name,
+		DueDate: issue.Fields.DueDate,
+		Updated: issue.Fields.Updated,
+		URL:     c.BaseURL + "/browse/" + issue.Key,
+		Labels:  issue.Fields.Labels,
+	}
+	if issue.Fields.Priority != nil {
+		ticket.Priority = issue.Fields.Priority.Name
+	}
+	if issue.Fields.Assignee != nil {
+		ticket.Assignee = Assignee{
+			Name:   issue.Fields.Assignee.DisplayName,
+			Avatar: issue.Fields.Assignee.AvatarURLs["48x48"],
+		}
+	}
+	for _, component := range issue.Fields.Components {
+		ticket.Components = append(ticket.Components, component.Name)
+	}
+	return ticket
+}
+
+// RepoTickets searches by label and by component separately, because Jira
+// rejects the whole query when the component does not exist in the project
+func (c *JiraClient) RepoTickets(ctx context.Context, repo string) ([]Ticket, error) {
+	scope := ""
+	if c.Project != "" {
+		scope = fmt.Sprintf("project = %s AND ", jqlQuote(c.Project))
+	}
+
+	byLabel, err := c.search(ctx, fmt.Sprintf("%slabels = %s ORDER BY updated DESC", scope, jqlQuote(repo)))
+	if err != nil {
+		return nil, err
+	}
+
+	byComponent, err := c.search(ctx, fmt.Sprintf("%scomponent = %s ORDER BY updated DESC", scope, jqlQuote(repo)))
+	if statusErr, ok := err.(*jiraStatusError); ok && statusErr.StatusCode == http.StatusBadRequest {
+		byComponent = nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	tickets := []Ticket{}
+	for _, ticket := range append(byLabel, byComponent...) {
+		if !seen[ticket.ID] {
+			seen[ticket.ID] = true
+			tickets = append(tickets, ticket)
+		}
+	}
+	return tickets, nil
+}
+
+// SquadMembers lists the members of the Jira group named after the squad
+func (c *JiraClient) SquadMembers(ctx context.Context, squad string) ([]Person, error) {
+	query := url.Values{}
+	query.Set("groupname", squad)
+	query.Set("maxResults", fmt.Sprint(c.MaxResults))
+
+	var result struct {
+		Values []jiraUser `json:"values"`
+	}
+	if err := c.get(ctx, "/rest/api/2/group/member", query, &result); err != nil {
+		if statusErr, ok := err.(*jiraStatusError); ok && statusErr.StatusCode == http.StatusNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	people := make([]Person, 0, len(result.Values))
+	for _, user := range result.Values {
+		id := user.AccountID
+		if id == "" {
+			id = user.Name
+		}
+		people = append(people, Person{
+			ID:     id,
+			Name:   user.DisplayName,
+			Email:  user.EmailAddress,
+			Avatar: user.AvatarURLs["48x48"],
+		})
+	}
+	return people, nil
+}
+
+func jqlQuote(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
@@ -0,0 +1,59 @@
+// pkg/tracker/tracker.go
+package tracker
+
+import (
+	"context"
+	"strings"
+)
+
+type Assignee struct {
+	Name   string `json:"name"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+type Ticket struct {
+	ID         string   `json:"id"`
+	Title      string   `json:"title"`
+	Status     string   `json:"status"`
+	Priority   string   `json:"priority,omitempty"`
+	Assignee   Assignee `json:"assignee"`
+	DueDate    string   `json:"dueDate,omitempty"`
+	Updated    string   `json:"updated,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	Labels     []string `json:"labels,omitempty"`
+	Components []string `json:"components,omitempty"`
+}
+
+type Person struct {
+	ID     string `json:"id,omitempty"`
+	Name   string `json:"name"`
+	Email  string `json:"email,omitempty"`
+	Role   string `json:"role,omitempty"`
+	Avatar string `json:"avatar,omitempty"`
+}
+
+// PeopleProvider lists the members of a squad
+type PeopleProvider interface {
+	SquadMembers(ctx context.Context, squad string) ([]Person, error)
+}
+
+// IssueTracker lists the tickets linked to a repo, either through a label
+// or a component named after the repo
+type IssueTracker interface {
+	RepoTickets(ctx context.Context, repo string) ([]Ticket, error)
+}
+
+// linkedToRepo reports whether a ticket carries the repo as a label or component
+func linkedToRepo(ticket Ticket, repo string) bool {
+	for _, label := range ticket.Labels {
+		if strings.EqualFold(label, repo) {
+			return true
+		}
+	}
+	for _, component := range ticket.Components {
+		if strings.EqualFold(component, repo) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+// pkg/version/version.go
+package version
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// versionRe matches release versions such as "1.62.0", "v2.3" or "1.4.0-rc.1"
+var versionRe = regexp.MustCompile(`^v?(\d+)(?:\.(\d+))?(?:\.(\d+))?(?:-([0-9A-Za-z.-]+))?$`)
+
+// Version is a semantic version; Pre is the pre-release part, if any
+type Version struct {
+	Major, Minor, Patch int
+	Pre                 string
+}
+
+// Parse reads a release version. Image tags such as "PR-123" or commit
+// hashes are not versions and return false.
+func Parse(value string) (Version, bool) {
+	match := versionRe.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return Version{}, false
+	}
+	var v Version
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	v.Patch, _ = strconv.Atoi(match[3])
+	v.Pre = match[4]
+	return v, true
+}
+
+// Compare returns -1, 0 or 1 as a is older than, the same as or newer than b.
+// A pre-release is older than its release.
+func Compare(a, b Version) int {
+	for _, diff := range []int{a.Major - b.Major, a.Minor - b.Minor, a.Patch - b.Patch} {
+		if diff != 0 {
+			return sign(diff)
+		}
+	}
+	switch {
+	case a.Pre == b.Pre:
+		return 0
+	case a.Pre == "":
+		return 1
+	case b.Pre == "":
+		return -1
+	}
+	return sign(strings.Compare(a.Pre, b.Pre))
+}
+
+// Gap is how far one version is behind another, counted at the most
+// significant part that differs: 1.60.3 is 2 minor versions behind 1.62.0.
+type Gap struct {
+	Major int `json:"major"`
+	Minor int `json:"minor"`
+	Patch int `json:"patch"`
+}
+
+// Behind returns how far older is behind newer; the zero Gap when it is not
+func Behind(older, newer Version) Gap {
+	switch {
+	case Compare(older, newer) >= 0:
+		return Gap{}
+	case older.Major != newer.Major:
+		return Gap{Major: newer.Major - older.Major}
+	case older.Minor != newer.Minor:
+		return Gap{Minor: newer.Minor - older.Minor}
+	}
+	return Gap{Patch: newer.Patch - older.Patch}
+}
+
+func (g Gap) IsZero() bool {
+	return g == Gap{}
+}
+
+func (g Gap) String() string {
+	switch {
+	case g.Major > 0:
+		return plural(g.Major, "major version")
+	case g.Minor > 0:
+		return plural(g.Minor, "minor version")
+	case g.Patch > 0:
+		return plural(g.Patch, "patch")
+	}
+	return "up to date"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("1 %s", noun)
+	}
+	if strings.HasSuffix(noun, "ch") {
+		return fmt.Sprintf("%d %ses", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
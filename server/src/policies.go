@@ -0,0 +1,133 @@
+package main
+
+import (
+	"argocd/pkg/policy"
+	"argocd/pkg/summary"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// policiesFile holds the deployment rules apps are checked against; see
+// projects/policies.example.json
+var policiesFile = "projects/policies.json"
+
+// policyEngine is nil when policiesFile does not exist
+var policyEngine *policy.Engine
+
+// setupPolicies compiles the policy rules, if any are configured
+func setupPolicies() error {
+	if _, err := os.Stat(policiesFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	config, err := policy.LoadConfig(policiesFile)
+	if err != nil {
+		return err
+	}
+	policyEngine = policy.NewEngine(*config, repoOwner)
+	slog.Info("Loaded policy rules", "file", policiesFile, "rules", len(config.Rules))
+	return nil
+}
+
+// applyPolicies attaches the rules each app breaks to the app
+func applyPolicies(ctx context.Context, repoData *summary.Repo) {
+	if policyEngine == nil {
+		return
+	}
+	repoDir := filepath.Join("projects/projects", repoData.RepoName, "github")
+	for _, err := range policyEngine.Evaluate(repoData, repoDir) {
+		slog.WarnContext(ctx, "Error evaluating policy", "repo", repoData.RepoName, "error", err)
+	}
+}
+
+// ViolationEntry is one rule broken by one app, as listed on /violations
+type ViolationEntry struct {
+	Repo  string `json:"repo"`
+	Squad string `json:"squad"`
+	App   string `json:"app"`
+	Env   string `json:"env"`
+	summary.Violation
+}
+
+// severityRank orders severities for the ?severity= minimum
+var severityRank = map[string]int{
+	policy.SeverityInfo:    0,
+	policy.SeverityWarning: 1,
+	policy.SeverityError:   2,
+}
+
+// violationsHandler serves /violations: the violations of every app from the
+// last refresh of each repo, most severe first. ?squad=, ?rule= and
+// ?severity= (a minimum) narrow the list.
+func violationsHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	query := r.URL.Query()
+	minSeverity := 0
+	if severity := query.Get("severity"); severity != "" {
+		rank, ok := severityRank[severity]
+		if !ok {
+			http.Error(w, fmt.Sprintf("Unknown severity %q; use info, warning or error", severity), http.StatusBadRequest)
+			return
+		}
+		minSeverity = rank
+	}
+
+	files, err := filepath.Glob(filepath.Join("projects/projects-summary", "*.json"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error listing summaries: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	entries := []ViolationEntry{}
+	for _, file := range files {
+		repoData, _, err := summary.Read(file)
+		if err != nil {
+			slog.WarnContext(r.Context(), "Skipping unreadable summary", "file", file, "error", err)
+			continue
+		}
+		owner := repoOwner(repoData.RepoName)
+		if squad := query.Get("squad"); squad != "" && owner != squad {
+			continue
+		}
+		for _, app := range repoData.Apps {
+			for _, violation := range app.Violations {
+				if rule := query.Get("rule"); rule != "" && violation.Rule != rule {
+					continue
+				}
+				if severityRank[violation.Severity] < minSeverity {
+					continue
+				}
+				entries = append(entries, ViolationEntry{
+					Repo:      repoData.RepoName,
+					Squad:     owner,
+					App:       app.AppName,
+					Env:       app.Env,
+					Violation: violation,
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		if severityRank[entries[i].Severity] != severityRank[entries[j].Severity] {
+			return severityRank[entries[i].Severity] > severityRank[entries[j].Severity]
+		}
+		if entries[i].Repo != entries[j].Repo {
+			return entries[i].Repo < entries[j].Repo
+		}
+		return entries[i].App < entries[j].App
+	})
+
+	writeJSON(w, entries)
+}
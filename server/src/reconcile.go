@@ -0,0 +1,101 @@
+package main
+
+import (
+	"argocd/pkg/reconcile"
+	"argocd/pkg/regions"
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+// argocdRepoLabel is the label ArgoCD applications carry with their repo
+// name. When empty, a repo's applications are those named <repo>-.
+var argocdRepoLabel = ""
+
+// repoReconcileHandler serves /repos/<name>/reconcile: the declared locations
+// compared with the ArgoCD applications that actually exist for the repo
+func repoReconcileHandler(w http.ResponseWriter, r *http.Request, repoName string) {
+	repoBitUrl, namespace, locations := getRepoFileDetails(repoName)
+	if repoBitUrl == "" {
+		http.Error(w, "Unknown baseRepoName", http.StatusBadRequest)
+		return
+	}
+
+	creds, err := argocdCredentials(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	query := url.Values{"appNamespace": {"argocd"}}
+	selector := "prefix"
+	if argocdRepoLabel != "" {
+		selector = argocdRepoLabel + "=" + repoName
+		query.Set("selector", selector)
+	}
+
+	// Every instance is listed, so that apps on an instance their location is
+	// not routed to show up too
+	var apps []reconcile.Application
+	var unreachable []string
+	for _, instance := range argocdInstanceRegistry.Instances() {
+		ctx, cancel := context.WithTimeout(r.Context(), appTimeout)
+		body, err := argocdCall(ctx, instance, creds, http.MethodGet, instance.URL+"/api/v1/applications?"+query.Encode())
+		cancel()
+		if err == nil {
+			var listed []reconcile.Application
+			if listed, err = reconcile.ParseApplications(instance.Name, body); err == nil {
+				apps = append(apps, listed...)
+				continue
+			}
+		}
+		slog.WarnContext(r.Context(), "Error listing ArgoCD applications", "instance", instance.Name, "error", err)
+		unreachable = append(unreachable, instance.Name)
+	}
+	if len(unreachable) == len(argocdInstanceRegistry.Instances()) {
+		http.Error(w, fmt.Sprintf("Error listing ArgoCD applications from %v", unreachable), http.StatusBadGateway)
+		return
+	}
+
+	var repoNames []string
+	if catalog, err := loadPismoData(); err == nil {
+		for _, repo := range catalog.Repositories {
+			repoNames = append(repoNames, repo.RepositoryName)
+		}
+	}
+	apps = reconcile.Select(repoName, apps, argocdRepoLabel, repoNames)
+
+	writeJSON(w, reconcile.Build(repoName, selector, declaredLocations(repoName, namespace, locations), apps, unreachable))
+}
+
+// declaredLocations pairs each location with the ArgoCD instance it is routed
+// to and the namespace its terraform declares, falling back to the repo's
+// namespace for locations such as DR failovers that have no terraform of their
+// own. Locations read from Kubernetes directly have no ArgoCD app to match.
+func declaredLocations(repoName, namespace string, locations []regions.Location) []reconcile.Declared {
+	namespaces := make(map[string]string)
+	if regionDetails, err := regions.ParseRegions(repoName); err == nil {
+		for _, details := range regionDetails {
+			namespaces[regions.NewLocation(details.AccountDefault, details.RegionDefault).Key()] = details.Namespace
+		}
+	}
+
+	declared := make([]reconcile.Declared, 0, len(locations))
+	for _, location := range locations {
+		if kubernetesClusters.For(location) != nil {
+			continue
+		}
+		locationNamespace, ok := namespaces[location.Key()]
+		if !ok || locationNamespace == "" {
+			locationNamespace = namespace
+		}
+		declared = append(declared, reconcile.Declared{
+			Location:  location,
+			Namespace: locationNamespace,
+			Instance:  argocdInstanceRegistry.For(location).Name,
+		})
+	}
+	return declared
+}
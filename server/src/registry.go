@@ -0,0 +1,137 @@
+package main
+
+import (
+	"argocd/pkg/registry"
+	"argocd/pkg/summary"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// registriesFile configures the container registries images are looked up
+// in; see projects/registries.example.json
+var registriesFile = "projects/registries.json"
+
+// registryClient is nil when registry lookups are off
+var registryClient *registry.Client
+
+// setupRegistry enables image lookups when registriesFile exists. ECR
+// registries use the AWS credentials from the environment.
+func setupRegistry() error {
+	if _, err := os.Stat(registriesFile); os.IsNotExist(err) {
+		return nil
+	}
+
+	config, err := registry.LoadConfig(registriesFile)
+	if err != nil {
+		return err
+	}
+	registryClient = registry.NewClient(*config, registry.AWSCredentialsFromEnv())
+	slog.Info("Resolving images against registries", "file", registriesFile, "configured", len(config.Registries))
+	return nil
+}
+
+// inspectImages resolves every app's images to their digest, push date, size
+// and scan results, then flags tags that are different images in different
+// regions
+func inspectImages(ctx context.Context, repoData *summary.Repo) {
+	if registryClient == nil {
+		return
+	}
+
+	images := make(map[string]registry.ImageInfo)
+	for _, app := range repoData.Apps {
+		for _, image := range app.Images {
+			images[image] = registry.ImageInfo{Image: image}
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	sem := make(chan struct{}, 4)
+	for image := range images {
+		wg.Add(1)
+		go func(image string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			info, err := registryClient.Resolve(ctx, image)
+			if err != nil {
+				slog.WarnContext(ctx, "Error resolving image", "image", image, "error", err)
+				info.Error = err.Error()
+			}
+			mu.Lock()
+			images[image] = info
+			mu.Unlock()
+		}(image)
+	}
+	wg.Wait()
+
+	for i := range repoData.Apps {
+		app := &repoData.Apps[i]
+		app.ImageDetails = nil
+		for _, image := range app.Images {
+			app.ImageDetails = append(app.ImageDetails, images[image])
+		}
+	}
+
+	repoData.DigestMismatches = digestMismatches(repoData.Apps)
+	for _, mismatch := range repoData.DigestMismatches {
+		for digest, appNames := range mismatch.Digests {
+			for _, appName := range appNames {
+				for i := range repoData.Apps {
+					if repoData.Apps[i].AppName == appName {
+						repoData.Apps[i].Warning = append(repoData.Apps[i].Warning,
+							fmt.Sprintf("%s is %s here but a different image in other regions", mismatch.Image, shortDigest(digest)))
+					}
+				}
+			}
+		}
+	}
+}
+
+// digestMismatches finds the tags that resolve to more than one digest.
+// Tags are compared without the registry host, since each region pulls from
+// its own replica.
+func digestMismatches(apps []summary.App) []summary.DigestMismatch {
+	digests := make(map[string]map[string][]string)
+	for _, app := range apps {
+		for _, info := range app.ImageDetails {
+			ref, err := registry.ParseReference(info.Image)
+			if err != nil || info.Digest == "" || ref.Tag == "" {
+				continue
+			}
+			name := ref.Name()
+			if digests[name] == nil {
+				digests[name] = make(map[string][]string)
+			}
+			digests[name][info.Digest] = append(digests[name][info.Digest], app.AppName)
+		}
+	}
+
+	var mismatches []summary.DigestMismatch
+	for name, byDigest := range digests {
+		if len(byDigest) > 1 {
+			for _, appNames := range byDigest {
+				sort.Strings(appNames)
+			}
+			mismatches = append(mismatches, summary.DigestMismatch{Image: name, Digests: byDigest})
+		}
+	}
+	sort.Slice(mismatches, func(i, j int) bool { return mismatches[i].Image < mismatches[j].Image })
+	return mismatches
+}
+
+// shortDigest abbreviates a digest the way docker images does
+func shortDigest(digest string) string {
+	_, hex, _ := strings.Cut(digest, ":")
+	if len(hex) > 12 {
+		hex = hex[:12]
+	}
+	return hex
+}
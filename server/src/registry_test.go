@@ -0,0 +1,38 @@
+package main
+
+import (
+	"argocd/pkg/registry"
+	"argocd/pkg/summary"
+	"reflect"
+	"testing"
+)
+
+func TestDigestMismatchesComparesTagsAcrossRegistries(t *testing.T) {
+	app := func(name string, details ...registry.ImageInfo) summary.App {
+		return summary.App{AppName: name, ImageDetails: details}
+	}
+	apps := []summary.App{
+		app("backoffice-core-bff-prod-sa-east-1",
+			registry.ImageInfo{Image: "111111111111.dkr.ecr.sa-east-1.amazonaws.com/backoffice-core-bff:1.24.0", Digest: "sha256:aaa"},
+			registry.ImageInfo{Image: "istio/proxyv2:1.20.3", Digest: "sha256:ccc"}),
+		app("backoffice-core-bff-prod-us-east-1",
+			registry.ImageInfo{Image: "111111111111.dkr.ecr.us-east-1.amazonaws.com/backoffice-core-bff:1.24.0", Digest: "sha256:bbb"},
+			registry.ImageInfo{Image: "istio/proxyv2:1.20.3", Digest: "sha256:ccc"}),
+		app("backoffice-core-bff-ext-sa-east-1",
+			registry.ImageInfo{Image: "111111111111.dkr.ecr.sa-east-1.amazonaws.com/backoffice-core-bff:1.24.0", Digest: "sha256:aaa"},
+			// Unresolved and digest pinned images have nothing to compare
+			registry.ImageInfo{Image: "istio/proxyv2:1.20.3", Error: "registry returned status 500"},
+			registry.ImageInfo{Image: "busybox@sha256:ddd", Digest: "sha256:ddd"}),
+	}
+
+	want := []summary.DigestMismatch{{
+		Image: "backoffice-core-bff:1.24.0",
+		Digests: map[string][]string{
+			"sha256:aaa": {"backoffice-core-bff-ext-sa-east-1", "backoffice-core-bff-prod-sa-east-1"},
+			"sha256:bbb": {"backoffice-core-bff-prod-us-east-1"},
+		},
+	}}
+	if got := digestMismatches(apps); !reflect.DeepEqual(got, want) {
+		t.Errorf("mismatches = %+v, want %+v", got, want)
+	}
+}
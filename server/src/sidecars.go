@@ -0,0 +1,60 @@
+package main
+
+import (
+	"argocd/pkg/inventory"
+	"argocd/pkg/summary"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+)
+
+// fleetImages groups the containers of a role across every repo summary
+func fleetImages(role string) ([]inventory.ImageVersions, error) {
+	files, err := filepath.Glob(filepath.Join("projects/projects-summary", "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("error listing summaries: %v", err)
+	}
+
+	fleet := inventory.NewFleet(role)
+	for _, file := range files {
+		repoData, _, err := summary.Read(file)
+		if err != nil {
+			slog.Warn("Skipping unreadable summary", "file", file, "error", err)
+			continue
+		}
+		for _, app := range repoData.Apps {
+			fleet.Add(repoData.RepoName, app.AppName, app.Containers)
+		}
+	}
+	return fleet.Images(), nil
+}
+
+// sidecarsHandler serves /sidecars: the sidecar images running in the fleet
+// by image repository and version, with the services running outdated ones.
+// ?role=init lists init containers instead.
+func sidecarsHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	role := r.URL.Query().Get("role")
+	switch role {
+	case "":
+		role = inventory.RoleSidecar
+	case inventory.RoleSidecar, inventory.RoleInit:
+	default:
+		http.Error(w, fmt.Sprintf("Unknown role %q; use sidecar or init", role), http.StatusBadRequest)
+		return
+	}
+
+	images, err := fleetImages(role)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, images)
+}
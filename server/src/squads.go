@@ -0,0 +1,291 @@
+package main
+
+import (
+	"argocd/pkg/squads"
+	"argocd/pkg/summary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// squadsFile declares squads with their repos, members and on-call details
+var squadsFile = "projects/squads.json"
+
+type RepoHealth struct {
+	Repo        string            `json:"repo"`
+	Description string            `json:"description,omitempty"`
+	Ownership   squads.Ownership  `json:"ownership"`
+	Processed   bool              `json:"processed"`
+	Status      string            `json:"status"`
+	Apps        int               `json:"apps"`
+	Healthy     int               `json:"healthy"`
+	Erroring    []string          `json:"erroring,omitempty"`
+	Versions    map[string]string `json:"versions,omitempty"`
+}
+
+type SquadPortfolio struct {
+	squads.Squad
+	Declared   bool         `json:"declared"`
+	Repos      []RepoHealth `json:"repos"`
+	Status     string       `json:"status"`
+	Mismatches int          `json:"mismatches"`
+}
+
+type SquadListEntry struct {
+	Name     string `json:"name"`
+	Declared bool   `json:"declared"`
+	Repos    int    `json:"repos"`
+}
+
+// reconcileOwnership resolves the owner of every catalog repo
+func reconcileOwnership() (*squads.Registry, []squads.Ownership, error) {
+	registry, err := squads.LoadSquads(squadsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	catalog, err := loadPismoData()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool)
+	var ownerships []squads.Ownership
+	for _, repo := range catalog.Repositories {
+		seen[repo.RepositoryName] = true
+		repoDir := filepath.Join("projects/projects", repo.RepositoryName, "github")
+		ownerships = append(ownerships, registry.Reconcile(repo.RepositoryName, repo.Team, repoDir))
+	}
+
+	// Repos declared in the squads file but missing from the catalog
+	for _, name := range registry.Names() {
+		for _, repo := range registry.Get(name).Repos {
+			if !seen[repo] {
+				seen[repo] = true
+				repoDir := filepath.Join("projects/projects", repo, "github")
+				ownerships = append(ownerships, registry.Reconcile(repo, "", repoDir))
+			}
+		}
+	}
+
+	return registry, ownerships, nil
+}
+
+// getRepoHealth summarises the repo's last refresh from its summary file
+func getRepoHealth(ownership squads.Ownership, description string) RepoHealth {
+	health := RepoHealth{
+		Repo:        ownership.Repo,
+		Description: description,
+		Ownership:   ownership,
+		Status:      "unknown",
+	}
+
+	deploymentPath := filepath.Join("projects/projects-summary", ownership.Repo+".json")
+	deploymentInfo, _, err := summary.Read(deploymentPath)
+	if err != nil {
+		return health
+	}
+	health.Processed = true
+
+	for _, app := range deploymentInfo.Apps {
+		health.Apps++
+		if app.HasErrors() || (app.ArgoCD != nil && app.ArgoCD.Health == "Error") {
+			health.Erroring = append(health.Erroring, app.AppName)
+			continue
+		}
+		health.Healthy++
+	}
+	sort.Strings(health.Erroring)
+	health.Versions = getEnvironmentVersions(deploymentInfo)
+	health.Status = healthStatus(health.Apps, health.Healthy)
+
+	return health
+}
+
+func healthStatus(total, healthy int) string {
+	switch {
+	case total == 0:
+		return "unknown"
+	case healthy == total:
+		return "healthy"
+	case healthy == 0:
+		return "down"
+	}
+	return "degraded"
+}
+
+func buildSquadPortfolio(name string) (*SquadPortfolio, error) {
+	registry, ownerships, err := reconcileOwnership()
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio := &SquadPortfolio{Squad: squads.Squad{Name: name}}
+	if squad := registry.Get(name); squad != nil {
+		portfolio.Squad = *squad
+		portfolio.Declared = true
+	}
+
+	descriptions := make(map[string]string)
+	if catalog, err := loadPismoData(); err == nil {
+		for _, repo := range catalog.Repositories {
+			descriptions[repo.RepositoryName] = repo.Description
+		}
+	}
+
+	total, healthy := 0, 0
+	for _, ownership := range ownerships {
+		if ownership.Owner != name {
+			continue
+		}
+		health := getRepoHealth(ownership, descriptions[ownership.Repo])
+		if !ownership.Consistent {
+			portfolio.Mismatches++
+		}
+		if health.Processed {
+			total++
+			if health.Status == "healthy" {
+				healthy++
+			}
+		}
+		portfolio.Repos = append(portfolio.Repos, health)
+	}
+
+	if !portfolio.Declared && len(portfolio.Repos) == 0 {
+		return nil, nil
+	}
+	portfolio.Status = healthStatus(total, healthy)
+
+	return portfolio, nil
+}
+
+func listSquadsHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	registry, ownerships, err := reconcileOwnership()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, name := range registry.Names() {
+		counts[name] = 0
+	}
+	for _, ownership := range ownerships {
+		if ownership.Owner != "" {
+			counts[ownership.Owner]++
+		}
+	}
+
+	entries := make([]SquadListEntry, 0, len(counts))
+	for name, count := range counts {
+		entries = append(entries, SquadListEntry{
+			Name:     name,
+			Declared: registry.Get(name) != nil,
+			Repos:    count,
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	writeJSON(w, entries)
+}
+
+// squadHandler serves /squads/<name> with the squad's portfolio and
+// /squads/<name>/members with its members
+func squadHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/squads/"), "/"), "/")
+	name := parts[0]
+	if name == "" {
+		listSquadsHandler(w, r)
+		return
+	}
+
+	if len(parts) > 1 {
+		if len(parts) == 2 && parts[1] == "members" {
+			squadMembersHandler(w, r, name)
+			return
+		}
+		http.NotFound(w, r)
+		return
+	}
+
+	portfolio, err := buildSquadPortfolio(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if portfolio == nil {
+		http.Error(w, fmt.Sprintf("Unknown squad %s", name), http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, portfolio)
+}
+
+// ownershipHandler reports repos whose ownership sources disagree, or a
+// single repo's ownership with ?repo=. Pass ?all=true to include consistent repos.
+func ownershipHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	_, ownerships, err := reconcileOwnership()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	repoName := r.URL.Query().Get("repo")
+	all := r.URL.Query().Get("all") == "true"
+
+	result := []squads.Ownership{}
+	for _, ownership := range ownerships {
+		if repoName != "" {
+			if ownership.Repo == repoName {
+				writeJSON(w, ownership)
+				return
+			}
+			continue
+		}
+		if all || !ownership.Consistent {
+			result = append(result, ownership)
+		}
+	}
+
+	if repoName != "" {
+		http.Error(w, "Unknown baseRepoName", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, result)
+}
+
+func writeJSON(w http.ResponseWriter, value interface{}) {
+	jsonData, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		http.Error(w, "Error marshalling JSON", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(jsonData)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"argocd/pkg/coalesce"
+	"argocd/pkg/summary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// refreshGroup coalesces concurrent refreshes of the same repo
+var refreshGroup = coalesce.NewGroup()
+
+// writeSummary stores a repo summary in its file
+func writeSummary(filename string, repoData *summary.Repo) error {
+	jsonData, err := json.MarshalIndent(repoData, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling to JSON: %w", err)
+	}
+	return writeFileAtomic(filename, jsonData)
+}
+
+// writeFileAtomic writes to a temporary file in the same directory and
+// renames it into place, so readers never see a half-written file
+func writeFileAtomic(filename string, data []byte) error {
+	temp, err := os.CreateTemp(filepath.Dir(filename), "."+filepath.Base(filename)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	tempName := temp.Name()
+	defer os.Remove(tempName)
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("error writing to file: %w", err)
+	}
+	if err := temp.Sync(); err != nil {
+		temp.Close()
+		return fmt.Errorf("error syncing file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("error closing file: %w", err)
+	}
+	if err := os.Chmod(tempName, 0644); err != nil {
+		return fmt.Errorf("error setting file permissions: %w", err)
+	}
+	if err := os.Rename(tempName, filename); err != nil {
+		return fmt.Errorf("error renaming file: %w", err)
+	}
+	return nil
+}
+
+// writeSummarySchema writes the JSON Schema of the repo summary to filename
+func writeSummarySchema(filename string) error {
+	jsonData, err := json.MarshalIndent(summary.Schema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling schema: %w", err)
+	}
+	return writeFileAtomic(filename, append(jsonData, '\n'))
+}
+
+// summarySchemaHandler serves the JSON Schema of the repo summary
+func summarySchemaHandler(w http.ResponseWriter, r *http.Request) {
+	setCORSHeaders(w, r)
+
+	// Handle preflight requests
+	if r.Method == http.MethodOptions {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	json.NewEncoder(w).Encode(summary.Schema())
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"argocd/pkg/regions"
+	"argocd/pkg/summary"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Per-stage deadlines for a refresh, set from flags in main()
+var (
+	refreshTimeout = 3 * time.Minute
+	appTimeout     = 30 * time.Second
+	gitTimeout     = time.Minute
+	cloneTimeout   = 5 * time.Minute
+	buildsTimeout  = 15 * time.Second
+	imagesTimeout  = 30 * time.Second
+)
+
+// timedOutApp stands in for an app that could not be fetched in time, so the
+// rest of the repo's summary can still be written
+func timedOutApp(baseRepoName string, location regions.Location, stage string, err error) summary.App {
+	app := summary.NewApp(location.AppName(baseRepoName), location)
+	app.TimedOut = true
+
+	reason := "timed out"
+	if errors.Is(err, context.Canceled) {
+		reason = "cancelled"
+	}
+	app.Error = []string{fmt.Sprintf("%s while %s", reason, stage)}
+	return app
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"argocd/pkg/squads"
+	"argocd/pkg/tracker"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+var (
+	// trackerFile backs the offline people and ticket provider
+	trackerFile = "projects/tracker.json"
+
+	peopleProvider tracker.PeopleProvider
+	issueTracker   tracker.IssueTracker
+)
+
+// setupTracker uses Jira when a URL is configured, reading the API token from
+// jira-token.txt, and falls back to the local tracker file otherwise
+func setupTracker(jiraURL, jiraUser, jiraProject string) error {
+	if jiraURL == "" {
+		fileProvider := tracker.NewFileProvider(trackerFile)
+		peopleProvider = fileProvider
+		issueTracker = fileProvider
+		return nil
+	}
+
+	tokenBytes, err := ioutil.ReadFile("jira-token.txt")
+	if err != nil {
+		return fmt.Errorf("error reading jira token file: %v", err)
+	}
+
+	jira := tracker.NewJiraClient(jiraURL, jiraUser, strings.TrimSpace(string(tokenBytes)), jiraProject)
+	peopleProvider = jira
+	issueTracker = jira
+	return nil
+}
+
+// squadMembersHandler serves /squads/<name>/members. When the provider knows
+// nobody in the squad, the members listed in the squads file are returned.
+func squadMembersHandler(w http.ResponseWriter, r *http.Request, squad string) {
+	members, err := peopleProvider.SquadMembers(r.Context(), squad)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error fetching squad members: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	if len(members) == 0 {
+		registry, err := squads.LoadSquads(squadsFile)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if declared := registry.Get(squad); declared != nil {
+			for _, member := range declared.Members {
+				members = append(members, tracker.Person{
+					ID:    member.Github,
+					Name:  member.Name,
+					Email: member.Email,
+					Role:  member.Role,
+				})
+			}
+		}
+	}
+
+	if members == nil {
+		members = []tracker.Person{}
+	}
+	writeJSON(w, members)
+}